@@ -2,18 +2,26 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/rs/zerolog"
 	slogzerolog "github.com/samber/slog-zerolog"
+	"github.com/tilseiffert/docker-tex-to-pdf/internal/config"
 	"github.com/tilseiffert/docker-tex-to-pdf/internal/contextkeys"
 	"github.com/tilseiffert/docker-tex-to-pdf/internal/restserver"
+	grpcsrv "github.com/tilseiffert/docker-tex-to-pdf/internal/server"
 	server "github.com/tilseiffert/docker-tex-to-pdf/pkg/server"
-	"gorm.io/gorm"
-
-	"github.com/glebarez/sqlite"
 )
 
 // var loggerKey = &contextKey{"logger"}
@@ -62,38 +70,285 @@ func RegisterEndpoints(muxer *http.ServeMux) {
 	})
 }
 
+// commands are the subcommands of the server, "serve" is run if none is given
+var commands = map[string]func(name string, args []string) int{
+	"serve":  serve,
+	"apikey": apikey,
+}
+
 func main() {
 
+	command, args := "serve", os.Args[1:]
+
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+
+	run, ok := commands[command]
+
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command '%s', expected serve or apikey\n", command)
+		os.Exit(2)
+	}
+
+	os.Exit(run(os.Args[0]+" "+command, args))
+}
+
+// serve runs the REST API and the gRPC TexCompiler service on a shared job store and worker pool until SIGTERM or SIGINT
+// It returns the exit code
+func serve(name string, args []string) int {
+
+	cfg, err := config.Load(name, args)
+
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "invalid configuration:", err)
+		return 2
+	}
+
+	if cfg.PrintConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, "failed to print configuration:", err)
+			return 1
+		}
+
+		return 0
+	}
+
 	zerologLogger := zerolog.New(zerolog.ConsoleWriter{Out: os.Stderr})
-	logger := slog.New(slogzerolog.Option{Level: slog.LevelDebug, Logger: &zerologLogger}.NewZerologHandler())
+	logger := slog.New(slogzerolog.Option{Level: cfg.SlogLevel(), Logger: &zerologLogger}.NewZerologHandler())
 	logger = logger.With("func", "main").With("logger", "std-slog")
-	logger.Debug("Hello World 👋")
+	logger.Debug("Hello World 👋", "config", cfg.File)
 
-	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	db, err := restserver.OpenDB(cfg.DBDriver, cfg.DB, nil)
 
 	if err != nil {
 		logger.Error("failed to connect database", "error", err)
+		return 1
 	}
 
-	apiserver, err := restserver.NewServer(db, &restserver.ServerOptions{
-		BUILDDIR_PREFIX: "build-tex-to-pdfa",
-	})
+	apiserver, err := restserver.NewServer(db, cfg.ServerOptions())
 
 	if err != nil {
 		logger.Error("failed to create api-server", "error", err)
+		return 1
+	}
+
+	// SIGTERM (e.g. on container stops) and SIGINT stop the server gracefully, see below
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	// the workers compile the queued jobs in the background, detached from any request
+	ctx = context.WithValue(ctx, "logger", logger)
+
+	if err := apiserver.Recover(ctx); err != nil {
+		logger.Error("failed to recover jobs", "error", err)
+		return 1
+	}
+
+	apiserver.StartWorkers(ctx)
+
+	// API keys are checked against the db of the api-server, see the apikey command
+	var auth server.Authenticator
+
+	if cfg.Auth {
+		auth = apiserver
+		logger.Info("API keys required")
+	}
+
+	// gRPC calls are compiled as jobs of the api-server, so they are visible via the REST API
+	grpcserver := grpcsrv.NewServer(apiserver, auth)
+
+	if cfg.GRPCAddress != "" && !cfg.GRPCMultiplex {
+		address, err := grpcsrv.Serve(grpcserver, cfg.GRPCAddress)
+
+		if err != nil {
+			logger.Error("failed to start gRPC server", "error", err)
+			return 1
+		}
+
+		logger.Info("starting gRPC server on " + address.String())
+	}
+
+	if cfg.GRPCMultiplex {
+		logger.Info("serving gRPC on the address of the REST server", "address", cfg.Address)
 	}
 
 	srv := server.NewRestServer(logger)
+	serverErr := make(chan error, 1)
+
+	go func() {
+		serverErr <- srv.Start(cfg.RestServerOptions(apiserver.RegisterEndpoints, grpcserver, auth))
+	}()
 
-	opts := server.RestServerOptions{
-		Address:                  ":6204",
-		OptLogReqeust:            true,
-		CallbackEndpointRegister: apiserver.RegisterEndpoints,
+	select {
+	case err := <-serverErr:
+		logger.Error("failed to start server", "error", err)
+		return 1
+	case <-ctx.Done():
 	}
 
-	err = srv.Start(&opts)
+	// === Graceful shutdown ===
+
+	// a second signal terminates immediately
+	stop()
+
+	logger.Info("Shutting down, waiting for running requests and jobs", "timeout", cfg.ShutdownTimeoutDuration())
+
+	shutdownCtx, cancel := context.WithTimeout(context.WithValue(context.Background(), "logger", logger), cfg.ShutdownTimeoutDuration())
+	defer cancel()
+
+	// gRPC calls wait for their jobs, so all share the deadline
+	var wg sync.WaitGroup
+	wg.Add(3)
+
+	restDone := make(chan struct{})
+
+	go func() {
+		defer wg.Done()
+		defer close(restDone)
+
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			logger.Warn("failed to drain requests", "error", err)
+		}
+	}()
+
+	go func() {
+		defer wg.Done()
+
+		// the REST server waits for the multiplexed gRPC calls, the remaining ones are cancelled afterwards
+		if cfg.GRPCMultiplex {
+			<-restDone
+			grpcserver.Stop()
+			return
+		}
+
+		if err := grpcsrv.Shutdown(shutdownCtx, grpcserver); err != nil {
+			logger.Warn("failed to drain gRPC calls", "error", err)
+		}
+	}()
+
+	go func() {
+		defer wg.Done()
+
+		if err := apiserver.Shutdown(shutdownCtx); err != nil {
+			logger.Warn("failed to drain jobs", "error", err)
+		}
+	}()
+
+	wg.Wait()
+
+	logger.Info("Bye 👋")
+
+	return 0
+}
+
+// apikey manages the API keys required with the option auth, it returns the exit code
+//   - apikey create -name NAME [-role admin]: creates a key and prints it, it cannot be shown again
+//   - apikey list: lists the keys
+//   - apikey revoke ID: revokes a key
+//
+// The database is configured like for serve (e.g. -db or TEXTOPDF_DB)
+func apikey(name string, args []string) int {
+
+	usage := "usage: " + name + " create -name NAME [-role " + server.ROLE_ADMIN + "] | list | revoke ID"
+
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+
+	action := args[0]
+
+	var keyName, role string
+
+	cfg, rest, err := config.LoadCommand(name+" "+action, args[1:], func(flagset *flag.FlagSet) {
+		if action == "create" {
+			flagset.StringVar(&keyName, "name", "", "name of the key, e.g. the client using it")
+			flagset.StringVar(&role, "role", server.ROLE_CLIENT, "role of the key: "+server.ROLE_CLIENT+" sees its own jobs, "+server.ROLE_ADMIN+" sees everything")
+		}
+	})
+
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
 
 	if err != nil {
-		logger.Error("failed to start server", "error", err)
+		fmt.Fprintln(os.Stderr, "invalid configuration:", err)
+		return 2
 	}
+
+	if (action == "revoke") != (len(rest) == 1) || len(rest) > 1 {
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+
+	db, err := restserver.OpenDB(cfg.DBDriver, cfg.DB, nil)
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to connect database:", err)
+		return 1
+	}
+
+	apiserver, err := restserver.NewServer(db, cfg.ServerOptions())
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to open api-server:", err)
+		return 1
+	}
+
+	switch action {
+	case "create":
+		key, created, err := apiserver.CreateAPIKey(keyName, role)
+
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "failed to create API key:", err)
+			return 1
+		}
+
+		fmt.Fprintf(os.Stderr, "Created API key %d (%s, role %s), it cannot be shown again:\n", created.ID, created.Name, created.Role)
+		fmt.Println(key)
+
+	case "list":
+		apikeys, err := apiserver.ListAPIKeys()
+
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "failed to list API keys:", err)
+			return 1
+		}
+
+		for _, k := range apikeys {
+			lastUsed := "never used"
+
+			if k.LastUsedAt != nil {
+				lastUsed = "last used " + k.LastUsedAt.Format(time.RFC3339)
+			}
+
+			fmt.Printf("%d\t%s…\t%s\t%s\tcreated %s, %s\n", k.ID, k.Hint, k.Role, k.Name, k.CreatedAt.Format(time.RFC3339), lastUsed)
+		}
+
+	case "revoke":
+		id, err := strconv.ParseUint(rest[0], 10, 64)
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid key id '%s'\n", rest[0])
+			return 2
+		}
+
+		if err := apiserver.RevokeAPIKey(uint(id)); err != nil {
+			fmt.Fprintln(os.Stderr, "failed to revoke API key:", err)
+			return 1
+		}
+
+		fmt.Fprintf(os.Stderr, "Revoked API key %d\n", id)
+
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+
+	return 0
 }
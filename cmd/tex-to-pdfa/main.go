@@ -2,7 +2,10 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/rs/zerolog"
@@ -38,8 +41,121 @@ func Log(ctx context.Context) *zerolog.Logger {
 	panic("unable to retrieve logger from context")
 }
 
+// parseFlags parses the command line flags into compile options
+func parseFlags() *textopdfa.CompileOptions {
+	defaults := textopdfa.DefaultCompileOptions()
+	opts := &textopdfa.CompileOptions{}
+	policy := 0
+
+	flag.StringVar(&opts.Engine, "engine", defaults.Engine, "TeX engine ("+strings.Join(textopdfa.EngineNames(), ", ")+")")
+	flag.IntVar(&opts.PDFAPart, "pdfa-part", defaults.PDFAPart, "PDF/A part of the result (1, 2 or 3)")
+	flag.StringVar(&opts.ColorConversionStrategy, "color-strategy", defaults.ColorConversionStrategy, "gs color conversion strategy (e.g. UseDeviceIndependentColor, RGB, CMYK)")
+	flag.IntVar(&policy, "compatibility-policy", *defaults.CompatibilityPolicy, "gs PDF/A compatibility policy (0: include and warn, 1: ignore and warn, 2: abort)")
+	flag.IntVar(&opts.TimeoutSeconds, "timeout", defaults.TimeoutSeconds, "timeout of the whole compilation in seconds")
+	flag.IntVar(&opts.StageTimeoutSeconds, "stage-timeout", defaults.StageTimeoutSeconds, "timeout of each command (e.g. a LaTeX pass) in seconds")
+	flag.StringVar(&opts.Validator, "validator", "", "validate the result with "+strings.Join(textopdfa.ValidatorNames(), ", ")+" (empty skips the validation)")
+	flag.BoolVar(&opts.SkipPDFA1, "skip-pdfa1", defaults.SkipPDFA1, "skip the intermediate PDF/A-1 conversion")
+	flag.StringVar(&opts.FacturXProfile, "facturx-profile", "", "Factur-X profile ("+strings.Join(textopdfa.FacturXProfileNames(), ", ")+"), requires -attach factur-x.xml")
+	flag.Func("attach", "embed a file into the PDF/A-3 file, format: file[,relationship[,mime-type]] (repeatable)", func(value string) error {
+		fields := strings.SplitN(value, ",", 3)
+		attachment := textopdfa.Attachment{File: fields[0]}
+
+		if len(fields) > 1 {
+			attachment.Relationship = fields[1]
+		}
+
+		if len(fields) > 2 {
+			attachment.MIMEType = fields[2]
+		}
+
+		opts.Attachments = append(opts.Attachments, attachment)
+
+		return nil
+	})
+	intent := &textopdfa.OutputIntent{}
+
+	flag.StringVar(&intent.Profile, "icc-profile", "", "name of an ICC profile in -icc-dir used as output intent (e.g. ISOcoated_v2_300_eci)")
+	flag.StringVar(&intent.File, "icc-file", "", "ICC profile file used as output intent, instead of -icc-profile")
+	flag.StringVar(&intent.Identifier, "output-condition", "", "output condition identifier of the output intent (e.g. FOGRA39), defaults to the profile name")
+	flag.StringVar(&opts.ICCProfileDir, "icc-dir", defaults.ICCProfileDir, "directory of the ICC profiles selectable with -icc-profile")
+	metadata := &textopdfa.Metadata{}
+	namespaces := map[string]string{}
+
+	flag.StringVar(&metadata.Title, "title", "", "title of the document")
+	flag.StringVar(&metadata.Author, "author", "", "author of the document")
+	flag.StringVar(&metadata.Subject, "subject", "", "subject of the document")
+	flag.StringVar(&metadata.Keywords, "keywords", "", "keywords of the document")
+	flag.StringVar(&metadata.Creator, "creator", "", "application that created the document")
+	flag.StringVar(&metadata.Language, "lang", "", "language of the document (e.g. de-DE)")
+	flag.Func("xmp-namespace", "namespace of custom XMP properties, format: prefix=uri (repeatable)", func(value string) error {
+		prefix, uri, ok := strings.Cut(value, "=")
+
+		if !ok {
+			return fmt.Errorf("expected prefix=uri")
+		}
+
+		namespaces[prefix] = uri
+
+		return nil
+	})
+	flag.Func("xmp", "custom XMP property, format: prefix:name=value, the prefix must be declared with -xmp-namespace (repeatable)", func(value string) error {
+		property, value, ok := strings.Cut(value, "=")
+		prefix, name, ok2 := strings.Cut(property, ":")
+
+		if !ok || !ok2 {
+			return fmt.Errorf("expected prefix:name=value")
+		}
+
+		metadata.Custom = append(metadata.Custom, textopdfa.XMPProperty{Prefix: prefix, Name: name, Value: value})
+
+		return nil
+	})
+	flag.Parse()
+
+	opts.CompatibilityPolicy = &policy
+
+	for i, property := range metadata.Custom {
+		uri, ok := namespaces[property.Prefix]
+
+		if !ok {
+			fmt.Fprintf(flag.CommandLine.Output(), "XMP prefix '%s' is not declared, use -xmp-namespace %s=uri\n", property.Prefix, property.Prefix)
+			os.Exit(2)
+		}
+
+		metadata.Custom[i].Namespace = uri
+	}
+
+	if intent.Profile != "" || intent.File != "" {
+		opts.OutputIntent = intent
+	}
+
+	// only set metadata if any was given, otherwise LaTeX' metadata is kept
+	if metadata.Title+metadata.Author+metadata.Subject+metadata.Keywords+metadata.Creator+metadata.Language != "" || len(metadata.Custom) > 0 {
+		opts.Metadata = metadata
+	}
+
+	return opts
+}
+
+// logDiagnostics logs the errors and warnings reported by TeX
+func logDiagnostics(ctx context.Context, diagnostics []textopdfa.Diagnostic) {
+
+	for _, diagnostic := range diagnostics {
+		event := Log(ctx).Warn()
+
+		if diagnostic.Severity == textopdfa.SEVERITY_ERROR {
+			event = Log(ctx).Error()
+		}
+
+		event.Str("kind", diagnostic.Kind).Str("file", diagnostic.File).Int("line", diagnostic.Line).
+			Str("package", diagnostic.Package).Msg(diagnostic.Message)
+	}
+}
+
 func main() {
 
+	opts := parseFlags()
+
 	// === Initialize context ===
 
 	starttime := time.Now()
@@ -50,13 +166,26 @@ func main() {
 	Log(ctx).Debug().Msg("Hello world 👋")
 
 	// === Compile TeX to PDF/A ===
-	result, err := textopdfa.CompileTexToPDFA(ctx, TEXFILE, BUILDDIR_TEMPLATE)
+	result, err := textopdfa.CompileTexToPDFA(ctx, TEXFILE, BUILDDIR_TEMPLATE, opts)
+
+	if result != nil {
+		logDiagnostics(ctx, result.Diagnostics)
+	}
 
 	if err != nil {
 		Log(ctx).Fatal().Err(err).Msg("Error compiling TeX to PDF/A")
 	}
 
-	Log(ctx).Info().Str("path", result).Msg("Successfully compiled TeX to PDF/A")
+	Log(ctx).Info().Str("path", result.Path).Msg("Successfully compiled TeX to PDF/A")
+
+	if result.Validation != nil {
+		for _, rule := range result.Validation.FailedRules {
+			Log(ctx).Warn().Str("specification", rule.Specification).Str("clause", rule.Clause).Msg(rule.Description)
+		}
+
+		Log(ctx).Info().Str("validator", result.Validation.Validator).Str("profile", result.Validation.Profile).
+			Bool("compliant", result.Validation.Compliant).Msg("Validated PDF/A")
+	}
 
 	// === Tidy up ===
 
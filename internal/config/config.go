@@ -0,0 +1,463 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tilseiffert/docker-tex-to-pdf/internal/restserver"
+	"github.com/tilseiffert/docker-tex-to-pdf/internal/textopdfa"
+	"github.com/tilseiffert/docker-tex-to-pdf/pkg/server"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// ENV_PREFIX is the prefix of the environment variables, e.g. TEXTOPDF_ADDRESS for the key address
+	ENV_PREFIX = "TEXTOPDF_"
+
+	// ENV_CONFIG is the environment variable naming the config file, if -config is not given
+	ENV_CONFIG = ENV_PREFIX + "CONFIG"
+
+	DEFAULT_ADDRESS          = ":6204"
+	DEFAULT_GRPC_ADDRESS     = ":50051"
+	DEFAULT_LOG_LEVEL        = "debug"
+	DEFAULT_BUILDDIR_PREFIX  = "build-tex-to-pdfa"
+	DEFAULT_SHUTDOWN_TIMEOUT = 30 // seconds, container runtimes must wait longer before killing the server (e.g. docker stop --time)
+)
+
+var patternPassword = regexp.MustCompile(`(password=)\S+`)
+
+// Config is the configuration of the server
+// Each value is read from (highest precedence first) a flag, an environment variable and the YAML config file, otherwise its default is used
+// The key in the config file is given by the yaml tag, the flag is the key with dashes (e.g. -log-level)
+// and the environment variable the key in upper case with prefix ENV_PREFIX (e.g. TEXTOPDF_LOG_LEVEL)
+type Config struct {
+	Address            string `yaml:"address" usage:"address the REST server listens on"`
+	GRPCAddress        string `yaml:"grpc_address" usage:"address the gRPC server listens on, empty disables it"`
+	Auth               bool   `yaml:"auth" usage:"require an API key for all requests and gRPC calls (create keys with the apikey command), callers see only their own jobs"`
+	GRPCMultiplex      bool   `yaml:"grpc_multiplex" usage:"serve gRPC on the address of the REST server (HTTP/2 without TLS) instead of grpc_address"`
+	LogLevel           string `yaml:"log_level" usage:"log level (debug, info, warn or error)"`
+	LogRequests        bool   `yaml:"log_requests" usage:"log every request"`
+	BuildDirPrefix     string `yaml:"builddir_prefix" usage:"prefix of the temporary build directories"`
+	JobDir             string `yaml:"jobdir" usage:"directory holding the files of the jobs"`
+	Workers            int    `yaml:"workers" usage:"number of jobs compiled in parallel"`
+	DBDriver           string `yaml:"db_driver" usage:"database driver (sqlite or postgres)"`
+	DB                 string `yaml:"db" usage:"SQLite file path or Postgres DSN, the default in-memory SQLite database loses all jobs on restart"`
+	RequeueInterrupted bool   `yaml:"requeue_interrupted" usage:"queue jobs interrupted by a restart again instead of marking them as failed"`
+	ICCProfileDir      string `yaml:"icc_profile_dir" usage:"directory of the ICC profiles selectable as output intent"`
+	RetentionFinished  int    `yaml:"retention_finished_hours" usage:"hours to keep finished jobs (e.g. 24), 0 keeps them forever"`
+	RetentionFailed    int    `yaml:"retention_failed_hours" usage:"hours to keep failed, cancelled and interrupted jobs (e.g. 168 to investigate them for a week), 0 keeps them forever"`
+	JobDirMaxSize      int    `yaml:"jobdir_max_size_mb" usage:"maximum size of the job dir in MiB, the oldest jobs are removed early if it is exceeded, 0 means unlimited"`
+	JanitorInterval    int    `yaml:"janitor_interval_minutes" usage:"minutes between the cleanups of old jobs"`
+	CacheDir           string `yaml:"cache_dir" usage:"directory of the compile cache"`
+	CacheMaxSize       int    `yaml:"cache_max_size_mb" usage:"maximum size of the compile cache in MiB, the least recently used results are evicted first, 0 disables the cache"`
+	PublicURL          string `yaml:"public_url" usage:"base URL of the server used in webhook callbacks (e.g. https://tex.example.com), empty sends relative URLs"`
+	WebhookAllowlist   string `yaml:"webhook_allowlist" usage:"comma-separated host names, addresses and CIDR ranges (e.g. hooks.internal,10.0.0.0/8) webhook callbacks may reach although they are loopback, private or link-local"`
+	ShutdownTimeout    int    `yaml:"shutdown_timeout_seconds" usage:"seconds to wait for running requests and jobs on SIGTERM/SIGINT before interrupting them"`
+
+	File        string `yaml:"-"` // path of the config file that was read, empty if none
+	PrintConfig bool   `yaml:"-"` // print the configuration and exit (-print-config)
+}
+
+// Default returns the default configuration
+func Default() *Config {
+	return &Config{
+		Address:         DEFAULT_ADDRESS,
+		GRPCAddress:     DEFAULT_GRPC_ADDRESS,
+		LogLevel:        DEFAULT_LOG_LEVEL,
+		LogRequests:     true,
+		BuildDirPrefix:  DEFAULT_BUILDDIR_PREFIX,
+		JobDir:          restserver.DEFAULT_JOBDIR,
+		Workers:         restserver.DEFAULT_WORKERS,
+		DBDriver:        restserver.DEFAULT_DB_DRIVER,
+		DB:              restserver.DEFAULT_DB_DSN,
+		ICCProfileDir:   textopdfa.DEFAULT_ICC_PROFILE_DIR,
+		JanitorInterval: int(restserver.DEFAULT_JANITOR_INTERVAL / time.Minute),
+		CacheDir:        restserver.DEFAULT_CACHEDIR,
+		CacheMaxSize:    restserver.DEFAULT_CACHE_MAX_SIZE >> 20,
+		ShutdownTimeout: DEFAULT_SHUTDOWN_TIMEOUT,
+	}
+}
+
+// field is a configurable value of Config
+type field struct {
+	key   string
+	usage string
+	value reflect.Value
+}
+
+// fields returns the configurable values of cfg, in order of declaration
+func (cfg *Config) fields() []field {
+	var fields []field
+
+	v := reflect.ValueOf(cfg).Elem()
+
+	for i := 0; i < v.NumField(); i++ {
+		key := v.Type().Field(i).Tag.Get("yaml")
+
+		if key == "" || key == "-" {
+			continue
+		}
+
+		fields = append(fields, field{key: key, usage: v.Type().Field(i).Tag.Get("usage"), value: v.Field(i)})
+	}
+
+	return fields
+}
+
+// flagName returns the flag of a key, e.g. "log-level" for "log_level"
+func flagName(key string) string {
+	return strings.ReplaceAll(key, "_", "-")
+}
+
+// envName returns the environment variable of a key, e.g. "TEXTOPDF_LOG_LEVEL" for "log_level"
+func envName(key string) string {
+	return ENV_PREFIX + strings.ToUpper(key)
+}
+
+// set parses s into the value of a field
+func (f field) set(s string) error {
+
+	switch f.value.Kind() {
+	case reflect.String:
+		f.value.SetString(s)
+
+	case reflect.Int:
+		n, err := strconv.Atoi(s)
+
+		if err != nil {
+			return fmt.Errorf("invalid number '%s'", s)
+		}
+
+		f.value.SetInt(int64(n))
+
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+
+		if err != nil {
+			return fmt.Errorf("invalid boolean '%s'", s)
+		}
+
+		f.value.SetBool(b)
+
+	default:
+		return fmt.Errorf("unsupported type %s", f.value.Kind())
+	}
+
+	return nil
+}
+
+// Load reads the configuration from the command line arguments (without the program name), the environment and the config file
+// The config file is given by -config or the environment variable ENV_CONFIG, without either none is read
+// It returns flag.ErrHelp if -h was given, the usage has been printed then
+func Load(name string, args []string) (*Config, error) {
+	cfg, rest, err := LoadCommand(name, args, nil)
+
+	if err == nil && len(rest) > 0 {
+		return nil, fmt.Errorf("unexpected argument '%s'", rest[0])
+	}
+
+	return cfg, err
+}
+
+// LoadCommand is Load for commands with flags and arguments of their own: define (optional) adds the flags of the command to flagset,
+// the arguments following the flags are returned
+func LoadCommand(name string, args []string, define func(flagset *flag.FlagSet)) (*Config, []string, error) {
+	cfg := Default()
+
+	// flags are parsed into a separate config, only those given override the other sources
+	flags := Default()
+	flagset := flag.NewFlagSet(name, flag.ContinueOnError)
+
+	flagset.StringVar(&flags.File, "config", "", "YAML config file (env "+ENV_CONFIG+")")
+	flagset.BoolVar(&flags.PrintConfig, "print-config", false, "print the configuration and exit")
+
+	for _, f := range flags.fields() {
+		usage := f.usage + " (env " + envName(f.key) + ")"
+
+		switch ptr := f.value.Addr().Interface().(type) {
+		case *string:
+			flagset.StringVar(ptr, flagName(f.key), *ptr, usage)
+		case *int:
+			flagset.IntVar(ptr, flagName(f.key), *ptr, usage)
+		case *bool:
+			flagset.BoolVar(ptr, flagName(f.key), *ptr, usage)
+		}
+	}
+
+	if define != nil {
+		define(flagset)
+	}
+
+	if err := flagset.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	// === Config file ===
+
+	cfg.File = flags.File
+
+	if cfg.File == "" {
+		cfg.File = os.Getenv(ENV_CONFIG)
+	}
+
+	if cfg.File != "" {
+		if err := cfg.readFile(cfg.File); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// === Environment ===
+
+	for _, f := range cfg.fields() {
+		if value, ok := os.LookupEnv(envName(f.key)); ok {
+			if err := f.set(value); err != nil {
+				return nil, nil, fmt.Errorf("%s: %w", envName(f.key), err)
+			}
+		}
+	}
+
+	// === Flags ===
+
+	given := map[string]bool{}
+	flagset.Visit(func(f *flag.Flag) { given[f.Name] = true })
+
+	flagFields := flags.fields()
+
+	for i, f := range cfg.fields() {
+		if given[flagName(f.key)] {
+			f.value.Set(flagFields[i].value)
+		}
+	}
+
+	cfg.PrintConfig = flags.PrintConfig
+
+	return cfg, flagset.Args(), cfg.Validate()
+}
+
+// readFile reads the YAML config file at path into cfg, unknown keys are rejected
+func (cfg *Config) readFile(path string) error {
+	content, err := os.ReadFile(path)
+
+	if err != nil {
+		return fmt.Errorf("could not read config file: %w", err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("invalid config file '%s': %w", path, err)
+	}
+
+	return nil
+}
+
+// Validate checks the configuration and returns an error describing the first invalid value
+func (cfg *Config) Validate() error {
+
+	if _, _, err := net.SplitHostPort(cfg.Address); err != nil {
+		return fmt.Errorf("invalid address '%s', expected host:port (e.g. ':6204'): %w", cfg.Address, err)
+	}
+
+	if cfg.GRPCAddress != "" && !cfg.GRPCMultiplex {
+		if _, _, err := net.SplitHostPort(cfg.GRPCAddress); err != nil {
+			return fmt.Errorf("invalid gRPC address '%s', expected host:port (e.g. ':50051'): %w", cfg.GRPCAddress, err)
+		}
+
+		if cfg.GRPCAddress == cfg.Address {
+			return fmt.Errorf("gRPC address '%s' is the address of the REST server, use grpc_multiplex to serve both on it", cfg.GRPCAddress)
+		}
+	}
+
+	var level slog.Level
+
+	if err := level.UnmarshalText([]byte(cfg.LogLevel)); err != nil {
+		return fmt.Errorf("invalid log level '%s', expected debug, info, warn or error", cfg.LogLevel)
+	}
+
+	if cfg.BuildDirPrefix == "" || strings.ContainsAny(cfg.BuildDirPrefix, "/\\*") {
+		return fmt.Errorf("invalid builddir prefix '%s', expected a file name", cfg.BuildDirPrefix)
+	}
+
+	if cfg.JobDir == "" {
+		return fmt.Errorf("jobdir must not be empty")
+	}
+
+	if cfg.Workers < 1 {
+		return fmt.Errorf("invalid number of workers %d, expected at least 1", cfg.Workers)
+	}
+
+	if cfg.DBDriver != restserver.DB_DRIVER_SQLITE && cfg.DBDriver != restserver.DB_DRIVER_POSTGRES {
+		return fmt.Errorf("unknown database driver '%s', expected %s or %s", cfg.DBDriver, restserver.DB_DRIVER_SQLITE, restserver.DB_DRIVER_POSTGRES)
+	}
+
+	if cfg.DB == "" {
+		return fmt.Errorf("db must not be empty")
+	}
+
+	if cfg.RetentionFinished < 0 || cfg.RetentionFailed < 0 {
+		return fmt.Errorf("invalid retention, expected a positive number of hours or 0 to keep jobs forever")
+	}
+
+	if cfg.JobDirMaxSize < 0 {
+		return fmt.Errorf("invalid job dir size %d, expected a positive number of MiB or 0 for unlimited", cfg.JobDirMaxSize)
+	}
+
+	if cfg.JanitorInterval < 1 {
+		return fmt.Errorf("invalid janitor interval %d, expected at least 1 minute", cfg.JanitorInterval)
+	}
+
+	if cfg.CacheMaxSize < 0 {
+		return fmt.Errorf("invalid cache size %d, expected a positive number of MiB or 0 to disable the cache", cfg.CacheMaxSize)
+	}
+
+	if cfg.CacheMaxSize > 0 && cfg.CacheDir == "" {
+		return fmt.Errorf("cache_dir must not be empty")
+	}
+
+	if cfg.PublicURL != "" {
+		if u, err := url.Parse(cfg.PublicURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid public URL '%s', expected an absolute http or https URL", cfg.PublicURL)
+		}
+	}
+
+	for _, entry := range cfg.WebhookAllowlistEntries() {
+		if _, err := netip.ParsePrefix(entry); strings.Contains(entry, "/") && err != nil {
+			return fmt.Errorf("invalid webhook allowlist entry '%s', expected a host name, address or CIDR range", entry)
+		}
+	}
+
+	if cfg.ShutdownTimeout < 0 {
+		return fmt.Errorf("invalid shutdown timeout %d, expected a positive number of seconds", cfg.ShutdownTimeout)
+	}
+
+	return nil
+}
+
+// SlogLevel returns the log level (valid after Validate)
+func (cfg *Config) SlogLevel() slog.Level {
+	var level slog.Level
+	_ = level.UnmarshalText([]byte(cfg.LogLevel))
+	return level
+}
+
+// ShutdownTimeoutDuration returns the shutdown timeout
+func (cfg *Config) ShutdownTimeoutDuration() time.Duration {
+	return time.Duration(cfg.ShutdownTimeout) * time.Second
+}
+
+// Retention returns the retention of the jobs by status, statuses kept forever are left out
+func (cfg *Config) Retention() map[string]time.Duration {
+	retention := make(map[string]time.Duration)
+
+	if cfg.RetentionFinished > 0 {
+		retention[restserver.JOBSTATUS_FINISHED] = time.Duration(cfg.RetentionFinished) * time.Hour
+	}
+
+	if cfg.RetentionFailed > 0 {
+		for _, status := range []string{restserver.JOBSTATUS_ERROR, restserver.JOBSTATUS_TIMEOUT, restserver.JOBSTATUS_CANCELLED, restserver.JOBSTATUS_INTERRUPTED} {
+			retention[status] = time.Duration(cfg.RetentionFailed) * time.Hour
+		}
+	}
+
+	return retention
+}
+
+// WebhookAllowlistEntries returns the entries of WebhookAllowlist
+func (cfg *Config) WebhookAllowlistEntries() []string {
+	var entries []string
+
+	for _, entry := range strings.Split(cfg.WebhookAllowlist, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries
+}
+
+// ServerOptions returns the options of the job server
+func (cfg *Config) ServerOptions() *restserver.ServerOptions {
+	return &restserver.ServerOptions{
+		BUILDDIR_PREFIX:    cfg.BuildDirPrefix,
+		JobDir:             cfg.JobDir,
+		Workers:            cfg.Workers,
+		ICCProfileDir:      cfg.ICCProfileDir,
+		Retention:          cfg.Retention(),
+		JobDirMaxSize:      int64(cfg.JobDirMaxSize) << 20,
+		JanitorInterval:    time.Duration(cfg.JanitorInterval) * time.Minute,
+		CacheDir:           cfg.CacheDir,
+		CacheMaxSize:       int64(cfg.CacheMaxSize) << 20,
+		PublicURL:          cfg.PublicURL,
+		WebhookAllowlist:   cfg.WebhookAllowlistEntries(),
+		RequeueInterrupted: cfg.RequeueInterrupted,
+	}
+}
+
+// RestServerOptions returns the options of the HTTP server
+// grpcHandler serves gRPC calls on the same address if GRPCMultiplex is set, auth checks the API keys if Auth is set
+func (cfg *Config) RestServerOptions(callbackEndpointRegister func(muxer *http.ServeMux), grpcHandler http.Handler, auth server.Authenticator) *server.RestServerOptions {
+	opts := &server.RestServerOptions{
+		Address:                  cfg.Address,
+		OptLogReqeust:            cfg.LogRequests,
+		CallbackEndpointRegister: callbackEndpointRegister,
+	}
+
+	if cfg.GRPCMultiplex {
+		opts.GRPCHandler = grpcHandler
+	}
+
+	if cfg.Auth {
+		opts.Authenticator = auth
+		opts.PublicPaths = restserver.PublicPaths
+	}
+
+	return opts
+}
+
+// redactedDB returns the database DSN with its password replaced
+func (cfg *Config) redactedDB() string {
+
+	if u, err := url.Parse(cfg.DB); err == nil && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), "xxxxx")
+			return u.String()
+		}
+	}
+
+	return patternPassword.ReplaceAllString(cfg.DB, "${1}xxxxx")
+}
+
+// Print writes the configuration as YAML to w, passwords are redacted
+func (cfg *Config) Print(w io.Writer) error {
+	redacted := *cfg
+	redacted.DB = cfg.redactedDB()
+
+	content, err := yaml.Marshal(&redacted)
+
+	if err != nil {
+		return err
+	}
+
+	if cfg.File != "" {
+		fmt.Fprintf(w, "# config file: %s\n", cfg.File)
+	}
+
+	_, err = w.Write(content)
+
+	return err
+}
@@ -20,32 +20,996 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type Phase int32
+
+const (
+	Phase_PHASE_UNSPECIFIED      Phase = 0
+	Phase_PHASE_QUEUED           Phase = 1 // the job waits for a free worker (only if the server runs with job store)
+	Phase_PHASE_COMPILING        Phase = 2 // a pass of the TeX engine runs
+	Phase_PHASE_CONVERTING_PDFA1 Phase = 3 // the intermediate conversion to PDF/A-1 runs
+	Phase_PHASE_CONVERTING_PDFA  Phase = 4 // the conversion to the requested PDF/A part (e.g. PDF/A-3) runs
+	Phase_PHASE_VALIDATING       Phase = 5 // the PDF/A file is validated
+	Phase_PHASE_DONE             Phase = 6 // the PDF/A file is ready, the result and the PDF chunks follow
+)
+
+// Enum value maps for Phase.
+var (
+	Phase_name = map[int32]string{
+		0: "PHASE_UNSPECIFIED",
+		1: "PHASE_QUEUED",
+		2: "PHASE_COMPILING",
+		3: "PHASE_CONVERTING_PDFA1",
+		4: "PHASE_CONVERTING_PDFA",
+		5: "PHASE_VALIDATING",
+		6: "PHASE_DONE",
+	}
+	Phase_value = map[string]int32{
+		"PHASE_UNSPECIFIED":      0,
+		"PHASE_QUEUED":           1,
+		"PHASE_COMPILING":        2,
+		"PHASE_CONVERTING_PDFA1": 3,
+		"PHASE_CONVERTING_PDFA":  4,
+		"PHASE_VALIDATING":       5,
+		"PHASE_DONE":             6,
+	}
+)
+
+func (x Phase) Enum() *Phase {
+	p := new(Phase)
+	*p = x
+	return p
+}
+
+func (x Phase) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Phase) Descriptor() protoreflect.EnumDescriptor {
+	return file_tex_to_pdf_proto_enumTypes[0].Descriptor()
+}
+
+func (Phase) Type() protoreflect.EnumType {
+	return &file_tex_to_pdf_proto_enumTypes[0]
+}
+
+func (x Phase) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Phase.Descriptor instead.
+func (Phase) EnumDescriptor() ([]byte, []int) {
+	return file_tex_to_pdf_proto_rawDescGZIP(), []int{0}
+}
+
 type File struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Name    string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`       // the name of the file (e.g. "main.tex")
-	Content []byte `protobuf:"bytes,2,opt,name=content,proto3" json:"content,omitempty"` // the content of the file (e.g. "\documentclass{article}...")
+	Name    string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`       // the name of the file (e.g. "main.tex")
+	Content []byte `protobuf:"bytes,2,opt,name=content,proto3" json:"content,omitempty"` // the content of the file (e.g. "\documentclass{article}...")
+}
+
+func (x *File) Reset() {
+	*x = File{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_tex_to_pdf_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *File) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*File) ProtoMessage() {}
+
+func (x *File) ProtoReflect() protoreflect.Message {
+	mi := &file_tex_to_pdf_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use File.ProtoReflect.Descriptor instead.
+func (*File) Descriptor() ([]byte, []int) {
+	return file_tex_to_pdf_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *File) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *File) GetContent() []byte {
+	if x != nil {
+		return x.Content
+	}
+	return nil
+}
+
+type Attachment struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	File         string `protobuf:"bytes,1,opt,name=file,proto3" json:"file,omitempty"`                         // the path of one of the request's files to embed
+	Name         string `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`                         // the name of the embedded file, defaults to the base name of file
+	MimeType     string `protobuf:"bytes,3,opt,name=mime_type,json=mimeType,proto3" json:"mime_type,omitempty"` // the MIME type (e.g. "text/xml"), defaults to the type of the file extension
+	Relationship string `protobuf:"bytes,4,opt,name=relationship,proto3" json:"relationship,omitempty"`         // the AFRelationship (Source, Data, Alternative, Supplement or Unspecified)
+	Description  string `protobuf:"bytes,5,opt,name=description,proto3" json:"description,omitempty"`           // an optional description of the file
+}
+
+func (x *Attachment) Reset() {
+	*x = Attachment{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_tex_to_pdf_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Attachment) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Attachment) ProtoMessage() {}
+
+func (x *Attachment) ProtoReflect() protoreflect.Message {
+	mi := &file_tex_to_pdf_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Attachment.ProtoReflect.Descriptor instead.
+func (*Attachment) Descriptor() ([]byte, []int) {
+	return file_tex_to_pdf_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Attachment) GetFile() string {
+	if x != nil {
+		return x.File
+	}
+	return ""
+}
+
+func (x *Attachment) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Attachment) GetMimeType() string {
+	if x != nil {
+		return x.MimeType
+	}
+	return ""
+}
+
+func (x *Attachment) GetRelationship() string {
+	if x != nil {
+		return x.Relationship
+	}
+	return ""
+}
+
+func (x *Attachment) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+type OutputIntent struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Profile      string `protobuf:"bytes,1,opt,name=profile,proto3" json:"profile,omitempty"`                               // the name of an ICC profile of the server's registry (e.g. "ISOcoated_v2_300_eci")
+	File         string `protobuf:"bytes,2,opt,name=file,proto3" json:"file,omitempty"`                                     // the path of one of the request's files holding an ICC profile, instead of profile
+	Identifier   string `protobuf:"bytes,3,opt,name=identifier,proto3" json:"identifier,omitempty"`                         // the OutputConditionIdentifier (e.g. "FOGRA39"), defaults to the name of the profile
+	Condition    string `protobuf:"bytes,4,opt,name=condition,proto3" json:"condition,omitempty"`                           // an optional description of the output condition
+	RegistryName string `protobuf:"bytes,5,opt,name=registry_name,json=registryName,proto3" json:"registry_name,omitempty"` // an optional URL of the registry the identifier is defined in
+}
+
+func (x *OutputIntent) Reset() {
+	*x = OutputIntent{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_tex_to_pdf_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *OutputIntent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OutputIntent) ProtoMessage() {}
+
+func (x *OutputIntent) ProtoReflect() protoreflect.Message {
+	mi := &file_tex_to_pdf_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OutputIntent.ProtoReflect.Descriptor instead.
+func (*OutputIntent) Descriptor() ([]byte, []int) {
+	return file_tex_to_pdf_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *OutputIntent) GetProfile() string {
+	if x != nil {
+		return x.Profile
+	}
+	return ""
+}
+
+func (x *OutputIntent) GetFile() string {
+	if x != nil {
+		return x.File
+	}
+	return ""
+}
+
+func (x *OutputIntent) GetIdentifier() string {
+	if x != nil {
+		return x.Identifier
+	}
+	return ""
+}
+
+func (x *OutputIntent) GetCondition() string {
+	if x != nil {
+		return x.Condition
+	}
+	return ""
+}
+
+func (x *OutputIntent) GetRegistryName() string {
+	if x != nil {
+		return x.RegistryName
+	}
+	return ""
+}
+
+type CompileOptions struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	PdfaPart                int32         `protobuf:"varint,1,opt,name=pdfa_part,json=pdfaPart,proto3" json:"pdfa_part,omitempty"`                                               // PDF/A part of the result (1, 2 or 3), 0 means default (3), the result is always conformance level b
+	ColorConversionStrategy string        `protobuf:"bytes,3,opt,name=color_conversion_strategy,json=colorConversionStrategy,proto3" json:"color_conversion_strategy,omitempty"` // gs ColorConversionStrategy (e.g. "UseDeviceIndependentColor"), empty means default
+	CompatibilityPolicy     *int32        `protobuf:"varint,4,opt,name=compatibility_policy,json=compatibilityPolicy,proto3,oneof" json:"compatibility_policy,omitempty"`        // gs PDFACompatibilityPolicy (0, 1 or 2), unset means default (2)
+	SkipPdfa1               bool          `protobuf:"varint,5,opt,name=skip_pdfa1,json=skipPdfa1,proto3" json:"skip_pdfa1,omitempty"`                                            // skip the intermediate PDF/A-1 conversion
+	Engine                  string        `protobuf:"bytes,6,opt,name=engine,proto3" json:"engine,omitempty"`                                                                    // TeX engine (rubber, latexmk, pdflatex, xelatex or lualatex), empty means default (rubber)
+	TimeoutSeconds          int32         `protobuf:"varint,7,opt,name=timeout_seconds,json=timeoutSeconds,proto3" json:"timeout_seconds,omitempty"`                             // timeout of the whole pipeline, 0 means default
+	StageTimeoutSeconds     int32         `protobuf:"varint,8,opt,name=stage_timeout_seconds,json=stageTimeoutSeconds,proto3" json:"stage_timeout_seconds,omitempty"`            // timeout of each command, 0 means default
+	Validator               string        `protobuf:"bytes,9,opt,name=validator,proto3" json:"validator,omitempty"`                                                              // PDF/A validator (auto, verapdf or internal), empty skips the validation
+	Attachments             []*Attachment `protobuf:"bytes,10,rep,name=attachments,proto3" json:"attachments,omitempty"`                                                         // files embedded into the PDF/A-3 file
+	FacturxProfile          string        `protobuf:"bytes,11,opt,name=facturx_profile,json=facturxProfile,proto3" json:"facturx_profile,omitempty"`                             // Factur-X profile (MINIMUM, BASIC WL, BASIC, EN16931 or EXTENDED), requires the invoice attached as "factur-x.xml"
+	OutputIntent            *OutputIntent `protobuf:"bytes,12,opt,name=output_intent,json=outputIntent,proto3" json:"output_intent,omitempty"`                                   // ICC profile of the output intent, unset means gs' default
+}
+
+func (x *CompileOptions) Reset() {
+	*x = CompileOptions{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_tex_to_pdf_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CompileOptions) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CompileOptions) ProtoMessage() {}
+
+func (x *CompileOptions) ProtoReflect() protoreflect.Message {
+	mi := &file_tex_to_pdf_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CompileOptions.ProtoReflect.Descriptor instead.
+func (*CompileOptions) Descriptor() ([]byte, []int) {
+	return file_tex_to_pdf_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *CompileOptions) GetPdfaPart() int32 {
+	if x != nil {
+		return x.PdfaPart
+	}
+	return 0
+}
+
+func (x *CompileOptions) GetColorConversionStrategy() string {
+	if x != nil {
+		return x.ColorConversionStrategy
+	}
+	return ""
+}
+
+func (x *CompileOptions) GetCompatibilityPolicy() int32 {
+	if x != nil && x.CompatibilityPolicy != nil {
+		return *x.CompatibilityPolicy
+	}
+	return 0
+}
+
+func (x *CompileOptions) GetSkipPdfa1() bool {
+	if x != nil {
+		return x.SkipPdfa1
+	}
+	return false
+}
+
+func (x *CompileOptions) GetEngine() string {
+	if x != nil {
+		return x.Engine
+	}
+	return ""
+}
+
+func (x *CompileOptions) GetTimeoutSeconds() int32 {
+	if x != nil {
+		return x.TimeoutSeconds
+	}
+	return 0
+}
+
+func (x *CompileOptions) GetStageTimeoutSeconds() int32 {
+	if x != nil {
+		return x.StageTimeoutSeconds
+	}
+	return 0
+}
+
+func (x *CompileOptions) GetValidator() string {
+	if x != nil {
+		return x.Validator
+	}
+	return ""
+}
+
+func (x *CompileOptions) GetAttachments() []*Attachment {
+	if x != nil {
+		return x.Attachments
+	}
+	return nil
+}
+
+func (x *CompileOptions) GetFacturxProfile() string {
+	if x != nil {
+		return x.FacturxProfile
+	}
+	return ""
+}
+
+func (x *CompileOptions) GetOutputIntent() *OutputIntent {
+	if x != nil {
+		return x.OutputIntent
+	}
+	return nil
+}
+
+type XMPProperty struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Namespace string `protobuf:"bytes,1,opt,name=namespace,proto3" json:"namespace,omitempty"` // the namespace URI, e.g. "https://example.com/ns/invoice/1.0/"
+	Prefix    string `protobuf:"bytes,2,opt,name=prefix,proto3" json:"prefix,omitempty"`       // the namespace prefix, e.g. "inv"
+	Name      string `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`           // the property name, e.g. "CustomerNumber"
+	Value     string `protobuf:"bytes,4,opt,name=value,proto3" json:"value,omitempty"`
+}
+
+func (x *XMPProperty) Reset() {
+	*x = XMPProperty{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_tex_to_pdf_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *XMPProperty) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*XMPProperty) ProtoMessage() {}
+
+func (x *XMPProperty) ProtoReflect() protoreflect.Message {
+	mi := &file_tex_to_pdf_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use XMPProperty.ProtoReflect.Descriptor instead.
+func (*XMPProperty) Descriptor() ([]byte, []int) {
+	return file_tex_to_pdf_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *XMPProperty) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *XMPProperty) GetPrefix() string {
+	if x != nil {
+		return x.Prefix
+	}
+	return ""
+}
+
+func (x *XMPProperty) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *XMPProperty) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+type Metadata struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Title    string         `protobuf:"bytes,1,opt,name=title,proto3" json:"title,omitempty"`
+	Author   string         `protobuf:"bytes,2,opt,name=author,proto3" json:"author,omitempty"`
+	Subject  string         `protobuf:"bytes,3,opt,name=subject,proto3" json:"subject,omitempty"`
+	Keywords string         `protobuf:"bytes,4,opt,name=keywords,proto3" json:"keywords,omitempty"`
+	Creator  string         `protobuf:"bytes,5,opt,name=creator,proto3" json:"creator,omitempty"`   // the application that created the document
+	Language string         `protobuf:"bytes,6,opt,name=language,proto3" json:"language,omitempty"` // the language tag of the document, e.g. "de-DE"
+	Custom   []*XMPProperty `protobuf:"bytes,7,rep,name=custom,proto3" json:"custom,omitempty"`     // additional XMP properties
+}
+
+func (x *Metadata) Reset() {
+	*x = Metadata{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_tex_to_pdf_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Metadata) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Metadata) ProtoMessage() {}
+
+func (x *Metadata) ProtoReflect() protoreflect.Message {
+	mi := &file_tex_to_pdf_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Metadata.ProtoReflect.Descriptor instead.
+func (*Metadata) Descriptor() ([]byte, []int) {
+	return file_tex_to_pdf_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Metadata) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *Metadata) GetAuthor() string {
+	if x != nil {
+		return x.Author
+	}
+	return ""
+}
+
+func (x *Metadata) GetSubject() string {
+	if x != nil {
+		return x.Subject
+	}
+	return ""
+}
+
+func (x *Metadata) GetKeywords() string {
+	if x != nil {
+		return x.Keywords
+	}
+	return ""
+}
+
+func (x *Metadata) GetCreator() string {
+	if x != nil {
+		return x.Creator
+	}
+	return ""
+}
+
+func (x *Metadata) GetLanguage() string {
+	if x != nil {
+		return x.Language
+	}
+	return ""
+}
+
+func (x *Metadata) GetCustom() []*XMPProperty {
+	if x != nil {
+		return x.Custom
+	}
+	return nil
+}
+
+type CompileRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Files    []*File         `protobuf:"bytes,1,rep,name=files,proto3" json:"files,omitempty"`                       // A list of files. This allows sending TeX files and their corresponding images or other dependencies.
+	Options  *CompileOptions `protobuf:"bytes,2,opt,name=options,proto3" json:"options,omitempty"`                   // Optional compile options, defaults are used if not set
+	MainFile string          `protobuf:"bytes,3,opt,name=main_file,json=mainFile,proto3" json:"main_file,omitempty"` // Optional path of the main TeX file, defaults to "main.tex" or the only .tex file
+	Metadata *Metadata       `protobuf:"bytes,4,opt,name=metadata,proto3" json:"metadata,omitempty"`                 // Optional metadata (title, author, ...) written to the Info dictionary and XMP metadata
+}
+
+func (x *CompileRequest) Reset() {
+	*x = CompileRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_tex_to_pdf_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CompileRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CompileRequest) ProtoMessage() {}
+
+func (x *CompileRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_tex_to_pdf_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CompileRequest.ProtoReflect.Descriptor instead.
+func (*CompileRequest) Descriptor() ([]byte, []int) {
+	return file_tex_to_pdf_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *CompileRequest) GetFiles() []*File {
+	if x != nil {
+		return x.Files
+	}
+	return nil
+}
+
+func (x *CompileRequest) GetOptions() *CompileOptions {
+	if x != nil {
+		return x.Options
+	}
+	return nil
+}
+
+func (x *CompileRequest) GetMainFile() string {
+	if x != nil {
+		return x.MainFile
+	}
+	return ""
+}
+
+func (x *CompileRequest) GetMetadata() *Metadata {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+type Diagnostic struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Severity string `protobuf:"bytes,1,opt,name=severity,proto3" json:"severity,omitempty"` // "error" or "warning"
+	Kind     string `protobuf:"bytes,2,opt,name=kind,proto3" json:"kind,omitempty"`         // e.g. "fatal", "missing_package", "undefined_reference", "overfull_box"
+	Message  string `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`   // the message as written by TeX
+	File     string `protobuf:"bytes,4,opt,name=file,proto3" json:"file,omitempty"`         // the file the diagnostic refers to, relative to the main TeX file's directory if inside it
+	Line     int32  `protobuf:"varint,5,opt,name=line,proto3" json:"line,omitempty"`        // the line in file, 0 if unknown
+	Package  string `protobuf:"bytes,6,opt,name=package,proto3" json:"package,omitempty"`   // the package or class that raised the diagnostic, empty for TeX and LaTeX itself
+}
+
+func (x *Diagnostic) Reset() {
+	*x = Diagnostic{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_tex_to_pdf_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Diagnostic) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Diagnostic) ProtoMessage() {}
+
+func (x *Diagnostic) ProtoReflect() protoreflect.Message {
+	mi := &file_tex_to_pdf_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Diagnostic.ProtoReflect.Descriptor instead.
+func (*Diagnostic) Descriptor() ([]byte, []int) {
+	return file_tex_to_pdf_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Diagnostic) GetSeverity() string {
+	if x != nil {
+		return x.Severity
+	}
+	return ""
+}
+
+func (x *Diagnostic) GetKind() string {
+	if x != nil {
+		return x.Kind
+	}
+	return ""
+}
+
+func (x *Diagnostic) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *Diagnostic) GetFile() string {
+	if x != nil {
+		return x.File
+	}
+	return ""
+}
+
+func (x *Diagnostic) GetLine() int32 {
+	if x != nil {
+		return x.Line
+	}
+	return 0
+}
+
+func (x *Diagnostic) GetPackage() string {
+	if x != nil {
+		return x.Package
+	}
+	return ""
+}
+
+type ValidationRule struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Specification string `protobuf:"bytes,1,opt,name=specification,proto3" json:"specification,omitempty"`                    // e.g. "ISO 19005-3:2012"
+	Clause        string `protobuf:"bytes,2,opt,name=clause,proto3" json:"clause,omitempty"`                                  // the clause of the specification, e.g. "6.6.2.1"
+	TestNumber    int32  `protobuf:"varint,3,opt,name=test_number,json=testNumber,proto3" json:"test_number,omitempty"`       // the number of the test within the clause (veraPDF only)
+	Description   string `protobuf:"bytes,4,opt,name=description,proto3" json:"description,omitempty"`                        // what the rule requires
+	FailedChecks  int32  `protobuf:"varint,5,opt,name=failed_checks,json=failedChecks,proto3" json:"failed_checks,omitempty"` // the number of objects violating the rule (veraPDF only)
+}
+
+func (x *ValidationRule) Reset() {
+	*x = ValidationRule{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_tex_to_pdf_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ValidationRule) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ValidationRule) ProtoMessage() {}
+
+func (x *ValidationRule) ProtoReflect() protoreflect.Message {
+	mi := &file_tex_to_pdf_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ValidationRule.ProtoReflect.Descriptor instead.
+func (*ValidationRule) Descriptor() ([]byte, []int) {
+	return file_tex_to_pdf_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ValidationRule) GetSpecification() string {
+	if x != nil {
+		return x.Specification
+	}
+	return ""
+}
+
+func (x *ValidationRule) GetClause() string {
+	if x != nil {
+		return x.Clause
+	}
+	return ""
+}
+
+func (x *ValidationRule) GetTestNumber() int32 {
+	if x != nil {
+		return x.TestNumber
+	}
+	return 0
+}
+
+func (x *ValidationRule) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *ValidationRule) GetFailedChecks() int32 {
+	if x != nil {
+		return x.FailedChecks
+	}
+	return 0
+}
+
+type ValidationReport struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Validator   string            `protobuf:"bytes,1,opt,name=validator,proto3" json:"validator,omitempty"`                        // the validator that checked the file (verapdf or internal)
+	Profile     string            `protobuf:"bytes,2,opt,name=profile,proto3" json:"profile,omitempty"`                            // the profile the file was checked against, e.g. "PDF/A-3B"
+	Compliant   bool              `protobuf:"varint,3,opt,name=compliant,proto3" json:"compliant,omitempty"`                       // whether the file passed all rules
+	FailedRules []*ValidationRule `protobuf:"bytes,4,rep,name=failed_rules,json=failedRules,proto3" json:"failed_rules,omitempty"` // the rules the file violates
+}
+
+func (x *ValidationReport) Reset() {
+	*x = ValidationReport{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_tex_to_pdf_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ValidationReport) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ValidationReport) ProtoMessage() {}
+
+func (x *ValidationReport) ProtoReflect() protoreflect.Message {
+	mi := &file_tex_to_pdf_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ValidationReport.ProtoReflect.Descriptor instead.
+func (*ValidationReport) Descriptor() ([]byte, []int) {
+	return file_tex_to_pdf_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ValidationReport) GetValidator() string {
+	if x != nil {
+		return x.Validator
+	}
+	return ""
+}
+
+func (x *ValidationReport) GetProfile() string {
+	if x != nil {
+		return x.Profile
+	}
+	return ""
+}
+
+func (x *ValidationReport) GetCompliant() bool {
+	if x != nil {
+		return x.Compliant
+	}
+	return false
+}
+
+func (x *ValidationReport) GetFailedRules() []*ValidationRule {
+	if x != nil {
+		return x.FailedRules
+	}
+	return nil
+}
+
+type CompileReply struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	PdfContent  []byte            `protobuf:"bytes,1,opt,name=pdf_content,json=pdfContent,proto3" json:"pdf_content,omitempty"` // The content of the resulting PDF file
+	Log         string            `protobuf:"bytes,2,opt,name=log,proto3" json:"log,omitempty"`                                 // The log of the compilation process
+	Diagnostics []*Diagnostic     `protobuf:"bytes,3,rep,name=diagnostics,proto3" json:"diagnostics,omitempty"`                 // Errors and warnings reported by TeX
+	Validation  *ValidationReport `protobuf:"bytes,4,opt,name=validation,proto3" json:"validation,omitempty"`                   // The result of the PDF/A validation, unset if it was not requested
+	JobId       string            `protobuf:"bytes,5,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`                // The id of the job, its status and logs are available via the REST API (empty if the server runs without job store)
+	PdfSize     int64             `protobuf:"varint,6,opt,name=pdf_size,json=pdfSize,proto3" json:"pdf_size,omitempty"`         // The size of the resulting PDF file in bytes
+}
+
+func (x *CompileReply) Reset() {
+	*x = CompileReply{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_tex_to_pdf_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CompileReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CompileReply) ProtoMessage() {}
+
+func (x *CompileReply) ProtoReflect() protoreflect.Message {
+	mi := &file_tex_to_pdf_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CompileReply.ProtoReflect.Descriptor instead.
+func (*CompileReply) Descriptor() ([]byte, []int) {
+	return file_tex_to_pdf_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *CompileReply) GetPdfContent() []byte {
+	if x != nil {
+		return x.PdfContent
+	}
+	return nil
+}
+
+func (x *CompileReply) GetLog() string {
+	if x != nil {
+		return x.Log
+	}
+	return ""
+}
+
+func (x *CompileReply) GetDiagnostics() []*Diagnostic {
+	if x != nil {
+		return x.Diagnostics
+	}
+	return nil
+}
+
+func (x *CompileReply) GetValidation() *ValidationReport {
+	if x != nil {
+		return x.Validation
+	}
+	return nil
+}
+
+func (x *CompileReply) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+func (x *CompileReply) GetPdfSize() int64 {
+	if x != nil {
+		return x.PdfSize
+	}
+	return 0
+}
+
+type Progress struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Phase   Phase  `protobuf:"varint,1,opt,name=phase,proto3,enum=tex_to_pdf.Phase" json:"phase,omitempty"`
+	Pass    int32  `protobuf:"varint,2,opt,name=pass,proto3" json:"pass,omitempty"`               // the number of the pass within the phase, starting at 1 (e.g. the LaTeX pass)
+	Command string `protobuf:"bytes,3,opt,name=command,proto3" json:"command,omitempty"`          // the command line of the command run, empty if the phase runs no command
+	JobId   string `protobuf:"bytes,4,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"` // the id of the job (empty if the server runs without job store)
 }
 
-func (x *File) Reset() {
-	*x = File{}
+func (x *Progress) Reset() {
+	*x = Progress{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_tex_to_pdf_proto_msgTypes[0]
+		mi := &file_tex_to_pdf_proto_msgTypes[11]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *File) String() string {
+func (x *Progress) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*File) ProtoMessage() {}
+func (*Progress) ProtoMessage() {}
 
-func (x *File) ProtoReflect() protoreflect.Message {
-	mi := &file_tex_to_pdf_proto_msgTypes[0]
+func (x *Progress) ProtoReflect() protoreflect.Message {
+	mi := &file_tex_to_pdf_proto_msgTypes[11]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -56,50 +1020,66 @@ func (x *File) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use File.ProtoReflect.Descriptor instead.
-func (*File) Descriptor() ([]byte, []int) {
-	return file_tex_to_pdf_proto_rawDescGZIP(), []int{0}
+// Deprecated: Use Progress.ProtoReflect.Descriptor instead.
+func (*Progress) Descriptor() ([]byte, []int) {
+	return file_tex_to_pdf_proto_rawDescGZIP(), []int{11}
 }
 
-func (x *File) GetName() string {
+func (x *Progress) GetPhase() Phase {
 	if x != nil {
-		return x.Name
+		return x.Phase
+	}
+	return Phase_PHASE_UNSPECIFIED
+}
+
+func (x *Progress) GetPass() int32 {
+	if x != nil {
+		return x.Pass
+	}
+	return 0
+}
+
+func (x *Progress) GetCommand() string {
+	if x != nil {
+		return x.Command
 	}
 	return ""
 }
 
-func (x *File) GetContent() []byte {
+func (x *Progress) GetJobId() string {
 	if x != nil {
-		return x.Content
+		return x.JobId
 	}
-	return nil
+	return ""
 }
 
-type CompileRequest struct {
+type LogLine struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Files []*File `protobuf:"bytes,1,rep,name=files,proto3" json:"files,omitempty"` // A list of files. This allows sending TeX files and their corresponding images or other dependencies.
+	Phase  Phase  `protobuf:"varint,1,opt,name=phase,proto3,enum=tex_to_pdf.Phase" json:"phase,omitempty"` // the phase of the command writing the line
+	Line   string `protobuf:"bytes,2,opt,name=line,proto3" json:"line,omitempty"`                          // the line without line break
+	Stderr bool   `protobuf:"varint,3,opt,name=stderr,proto3" json:"stderr,omitempty"`                     // the line was written to stderr
 }
 
-func (x *CompileRequest) Reset() {
-	*x = CompileRequest{}
+func (x *LogLine) Reset() {
+	*x = LogLine{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_tex_to_pdf_proto_msgTypes[1]
+		mi := &file_tex_to_pdf_proto_msgTypes[12]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *CompileRequest) String() string {
+func (x *LogLine) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*CompileRequest) ProtoMessage() {}
+func (*LogLine) ProtoMessage() {}
 
-func (x *CompileRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_tex_to_pdf_proto_msgTypes[1]
+func (x *LogLine) ProtoReflect() protoreflect.Message {
+	mi := &file_tex_to_pdf_proto_msgTypes[12]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -110,44 +1090,62 @@ func (x *CompileRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use CompileRequest.ProtoReflect.Descriptor instead.
-func (*CompileRequest) Descriptor() ([]byte, []int) {
-	return file_tex_to_pdf_proto_rawDescGZIP(), []int{1}
+// Deprecated: Use LogLine.ProtoReflect.Descriptor instead.
+func (*LogLine) Descriptor() ([]byte, []int) {
+	return file_tex_to_pdf_proto_rawDescGZIP(), []int{12}
 }
 
-func (x *CompileRequest) GetFiles() []*File {
+func (x *LogLine) GetPhase() Phase {
 	if x != nil {
-		return x.Files
+		return x.Phase
 	}
-	return nil
+	return Phase_PHASE_UNSPECIFIED
 }
 
-type CompileReply struct {
+func (x *LogLine) GetLine() string {
+	if x != nil {
+		return x.Line
+	}
+	return ""
+}
+
+func (x *LogLine) GetStderr() bool {
+	if x != nil {
+		return x.Stderr
+	}
+	return false
+}
+
+type CompileEvent struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	PdfContent []byte `protobuf:"bytes,1,opt,name=pdf_content,json=pdfContent,proto3" json:"pdf_content,omitempty"` // The content of the resulting PDF file
-	Log        string `protobuf:"bytes,2,opt,name=log,proto3" json:"log,omitempty"`                                 // The log of the compilation process
+	// Types that are assignable to Event:
+	//	*CompileEvent_Progress
+	//	*CompileEvent_LogLine
+	//	*CompileEvent_Result
+	//	*CompileEvent_PdfChunk
+	Event isCompileEvent_Event `protobuf_oneof:"event"`
 }
 
-func (x *CompileReply) Reset() {
-	*x = CompileReply{}
+func (x *CompileEvent) Reset() {
+	*x = CompileEvent{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_tex_to_pdf_proto_msgTypes[2]
+		mi := &file_tex_to_pdf_proto_msgTypes[13]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *CompileReply) String() string {
+func (x *CompileEvent) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*CompileReply) ProtoMessage() {}
+func (*CompileEvent) ProtoMessage() {}
 
-func (x *CompileReply) ProtoReflect() protoreflect.Message {
-	mi := &file_tex_to_pdf_proto_msgTypes[2]
+func (x *CompileEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_tex_to_pdf_proto_msgTypes[13]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -158,25 +1156,74 @@ func (x *CompileReply) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use CompileReply.ProtoReflect.Descriptor instead.
-func (*CompileReply) Descriptor() ([]byte, []int) {
-	return file_tex_to_pdf_proto_rawDescGZIP(), []int{2}
+// Deprecated: Use CompileEvent.ProtoReflect.Descriptor instead.
+func (*CompileEvent) Descriptor() ([]byte, []int) {
+	return file_tex_to_pdf_proto_rawDescGZIP(), []int{13}
 }
 
-func (x *CompileReply) GetPdfContent() []byte {
-	if x != nil {
-		return x.PdfContent
+func (m *CompileEvent) GetEvent() isCompileEvent_Event {
+	if m != nil {
+		return m.Event
 	}
 	return nil
 }
 
-func (x *CompileReply) GetLog() string {
-	if x != nil {
-		return x.Log
+func (x *CompileEvent) GetProgress() *Progress {
+	if x, ok := x.GetEvent().(*CompileEvent_Progress); ok {
+		return x.Progress
 	}
-	return ""
+	return nil
+}
+
+func (x *CompileEvent) GetLogLine() *LogLine {
+	if x, ok := x.GetEvent().(*CompileEvent_LogLine); ok {
+		return x.LogLine
+	}
+	return nil
+}
+
+func (x *CompileEvent) GetResult() *CompileReply {
+	if x, ok := x.GetEvent().(*CompileEvent_Result); ok {
+		return x.Result
+	}
+	return nil
+}
+
+func (x *CompileEvent) GetPdfChunk() []byte {
+	if x, ok := x.GetEvent().(*CompileEvent_PdfChunk); ok {
+		return x.PdfChunk
+	}
+	return nil
+}
+
+type isCompileEvent_Event interface {
+	isCompileEvent_Event()
+}
+
+type CompileEvent_Progress struct {
+	Progress *Progress `protobuf:"bytes,1,opt,name=progress,proto3,oneof"` // a new phase or pass started
+}
+
+type CompileEvent_LogLine struct {
+	LogLine *LogLine `protobuf:"bytes,2,opt,name=log_line,json=logLine,proto3,oneof"` // a line of output of the running command, lines may be dropped if the client reads too slowly
+}
+
+type CompileEvent_Result struct {
+	Result *CompileReply `protobuf:"bytes,3,opt,name=result,proto3,oneof"` // the outcome of the compilation without pdf_content, sent once after PHASE_DONE
+}
+
+type CompileEvent_PdfChunk struct {
+	PdfChunk []byte `protobuf:"bytes,4,opt,name=pdf_chunk,json=pdfChunk,proto3,oneof"` // a part of the PDF file following the result, the chunks in order make up the file (pdf_size bytes)
 }
 
+func (*CompileEvent_Progress) isCompileEvent_Event() {}
+
+func (*CompileEvent_LogLine) isCompileEvent_Event() {}
+
+func (*CompileEvent_Result) isCompileEvent_Event() {}
+
+func (*CompileEvent_PdfChunk) isCompileEvent_Event() {}
+
 var File_tex_to_pdf_proto protoreflect.FileDescriptor
 
 var file_tex_to_pdf_proto_rawDesc = []byte{
@@ -185,24 +1232,192 @@ var file_tex_to_pdf_proto_rawDesc = []byte{
 	0x0a, 0x04, 0x46, 0x69, 0x6c, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01,
 	0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x63, 0x6f,
 	0x6e, 0x74, 0x65, 0x6e, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x07, 0x63, 0x6f, 0x6e,
-	0x74, 0x65, 0x6e, 0x74, 0x22, 0x38, 0x0a, 0x0e, 0x43, 0x6f, 0x6d, 0x70, 0x69, 0x6c, 0x65, 0x52,
-	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x26, 0x0a, 0x05, 0x66, 0x69, 0x6c, 0x65, 0x73, 0x18,
-	0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x74, 0x65, 0x78, 0x5f, 0x74, 0x6f, 0x5f, 0x70,
-	0x64, 0x66, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x52, 0x05, 0x66, 0x69, 0x6c, 0x65, 0x73, 0x22, 0x41,
-	0x0a, 0x0c, 0x43, 0x6f, 0x6d, 0x70, 0x69, 0x6c, 0x65, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x1f,
-	0x0a, 0x0b, 0x70, 0x64, 0x66, 0x5f, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x18, 0x01, 0x20,
-	0x01, 0x28, 0x0c, 0x52, 0x0a, 0x70, 0x64, 0x66, 0x43, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x12,
-	0x10, 0x0a, 0x03, 0x6c, 0x6f, 0x67, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6c, 0x6f,
-	0x67, 0x32, 0x53, 0x0a, 0x0b, 0x54, 0x65, 0x78, 0x43, 0x6f, 0x6d, 0x70, 0x69, 0x6c, 0x65, 0x72,
-	0x12, 0x44, 0x0a, 0x0c, 0x43, 0x6f, 0x6d, 0x70, 0x69, 0x6c, 0x65, 0x54, 0x6f, 0x50, 0x44, 0x46,
-	0x12, 0x1a, 0x2e, 0x74, 0x65, 0x78, 0x5f, 0x74, 0x6f, 0x5f, 0x70, 0x64, 0x66, 0x2e, 0x43, 0x6f,
-	0x6d, 0x70, 0x69, 0x6c, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x18, 0x2e, 0x74,
+	0x74, 0x65, 0x6e, 0x74, 0x22, 0x97, 0x01, 0x0a, 0x0a, 0x41, 0x74, 0x74, 0x61, 0x63, 0x68, 0x6d,
+	0x65, 0x6e, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x66, 0x69, 0x6c, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x04, 0x66, 0x69, 0x6c, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x1b, 0x0a, 0x09, 0x6d,
+	0x69, 0x6d, 0x65, 0x5f, 0x74, 0x79, 0x70, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08,
+	0x6d, 0x69, 0x6d, 0x65, 0x54, 0x79, 0x70, 0x65, 0x12, 0x22, 0x0a, 0x0c, 0x72, 0x65, 0x6c, 0x61,
+	0x74, 0x69, 0x6f, 0x6e, 0x73, 0x68, 0x69, 0x70, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c,
+	0x72, 0x65, 0x6c, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x68, 0x69, 0x70, 0x12, 0x20, 0x0a, 0x0b,
+	0x64, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x05, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x0b, 0x64, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x22, 0x9f,
+	0x01, 0x0a, 0x0c, 0x4f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x49, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x12,
+	0x18, 0x0a, 0x07, 0x70, 0x72, 0x6f, 0x66, 0x69, 0x6c, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x07, 0x70, 0x72, 0x6f, 0x66, 0x69, 0x6c, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x66, 0x69, 0x6c,
+	0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x66, 0x69, 0x6c, 0x65, 0x12, 0x1e, 0x0a,
+	0x0a, 0x69, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x66, 0x69, 0x65, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x0a, 0x69, 0x64, 0x65, 0x6e, 0x74, 0x69, 0x66, 0x69, 0x65, 0x72, 0x12, 0x1c, 0x0a,
+	0x09, 0x63, 0x6f, 0x6e, 0x64, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x09, 0x63, 0x6f, 0x6e, 0x64, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x23, 0x0a, 0x0d, 0x72,
+	0x65, 0x67, 0x69, 0x73, 0x74, 0x72, 0x79, 0x5f, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x05, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x0c, 0x72, 0x65, 0x67, 0x69, 0x73, 0x74, 0x72, 0x79, 0x4e, 0x61, 0x6d, 0x65,
+	0x22, 0xa6, 0x04, 0x0a, 0x0e, 0x43, 0x6f, 0x6d, 0x70, 0x69, 0x6c, 0x65, 0x4f, 0x70, 0x74, 0x69,
+	0x6f, 0x6e, 0x73, 0x12, 0x1b, 0x0a, 0x09, 0x70, 0x64, 0x66, 0x61, 0x5f, 0x70, 0x61, 0x72, 0x74,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x08, 0x70, 0x64, 0x66, 0x61, 0x50, 0x61, 0x72, 0x74,
+	0x12, 0x3a, 0x0a, 0x19, 0x63, 0x6f, 0x6c, 0x6f, 0x72, 0x5f, 0x63, 0x6f, 0x6e, 0x76, 0x65, 0x72,
+	0x73, 0x69, 0x6f, 0x6e, 0x5f, 0x73, 0x74, 0x72, 0x61, 0x74, 0x65, 0x67, 0x79, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x17, 0x63, 0x6f, 0x6c, 0x6f, 0x72, 0x43, 0x6f, 0x6e, 0x76, 0x65, 0x72,
+	0x73, 0x69, 0x6f, 0x6e, 0x53, 0x74, 0x72, 0x61, 0x74, 0x65, 0x67, 0x79, 0x12, 0x36, 0x0a, 0x14,
+	0x63, 0x6f, 0x6d, 0x70, 0x61, 0x74, 0x69, 0x62, 0x69, 0x6c, 0x69, 0x74, 0x79, 0x5f, 0x70, 0x6f,
+	0x6c, 0x69, 0x63, 0x79, 0x18, 0x04, 0x20, 0x01, 0x28, 0x05, 0x48, 0x00, 0x52, 0x13, 0x63, 0x6f,
+	0x6d, 0x70, 0x61, 0x74, 0x69, 0x62, 0x69, 0x6c, 0x69, 0x74, 0x79, 0x50, 0x6f, 0x6c, 0x69, 0x63,
+	0x79, 0x88, 0x01, 0x01, 0x12, 0x1d, 0x0a, 0x0a, 0x73, 0x6b, 0x69, 0x70, 0x5f, 0x70, 0x64, 0x66,
+	0x61, 0x31, 0x18, 0x05, 0x20, 0x01, 0x28, 0x08, 0x52, 0x09, 0x73, 0x6b, 0x69, 0x70, 0x50, 0x64,
+	0x66, 0x61, 0x31, 0x12, 0x16, 0x0a, 0x06, 0x65, 0x6e, 0x67, 0x69, 0x6e, 0x65, 0x18, 0x06, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x06, 0x65, 0x6e, 0x67, 0x69, 0x6e, 0x65, 0x12, 0x27, 0x0a, 0x0f, 0x74,
+	0x69, 0x6d, 0x65, 0x6f, 0x75, 0x74, 0x5f, 0x73, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x73, 0x18, 0x07,
+	0x20, 0x01, 0x28, 0x05, 0x52, 0x0e, 0x74, 0x69, 0x6d, 0x65, 0x6f, 0x75, 0x74, 0x53, 0x65, 0x63,
+	0x6f, 0x6e, 0x64, 0x73, 0x12, 0x32, 0x0a, 0x15, 0x73, 0x74, 0x61, 0x67, 0x65, 0x5f, 0x74, 0x69,
+	0x6d, 0x65, 0x6f, 0x75, 0x74, 0x5f, 0x73, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x73, 0x18, 0x08, 0x20,
+	0x01, 0x28, 0x05, 0x52, 0x13, 0x73, 0x74, 0x61, 0x67, 0x65, 0x54, 0x69, 0x6d, 0x65, 0x6f, 0x75,
+	0x74, 0x53, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x73, 0x12, 0x1c, 0x0a, 0x09, 0x76, 0x61, 0x6c, 0x69,
+	0x64, 0x61, 0x74, 0x6f, 0x72, 0x18, 0x09, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x76, 0x61, 0x6c,
+	0x69, 0x64, 0x61, 0x74, 0x6f, 0x72, 0x12, 0x38, 0x0a, 0x0b, 0x61, 0x74, 0x74, 0x61, 0x63, 0x68,
+	0x6d, 0x65, 0x6e, 0x74, 0x73, 0x18, 0x0a, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x74, 0x65,
+	0x78, 0x5f, 0x74, 0x6f, 0x5f, 0x70, 0x64, 0x66, 0x2e, 0x41, 0x74, 0x74, 0x61, 0x63, 0x68, 0x6d,
+	0x65, 0x6e, 0x74, 0x52, 0x0b, 0x61, 0x74, 0x74, 0x61, 0x63, 0x68, 0x6d, 0x65, 0x6e, 0x74, 0x73,
+	0x12, 0x27, 0x0a, 0x0f, 0x66, 0x61, 0x63, 0x74, 0x75, 0x72, 0x78, 0x5f, 0x70, 0x72, 0x6f, 0x66,
+	0x69, 0x6c, 0x65, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0e, 0x66, 0x61, 0x63, 0x74, 0x75,
+	0x72, 0x78, 0x50, 0x72, 0x6f, 0x66, 0x69, 0x6c, 0x65, 0x12, 0x3d, 0x0a, 0x0d, 0x6f, 0x75, 0x74,
+	0x70, 0x75, 0x74, 0x5f, 0x69, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x18, 0x0c, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x18, 0x2e, 0x74, 0x65, 0x78, 0x5f, 0x74, 0x6f, 0x5f, 0x70, 0x64, 0x66, 0x2e, 0x4f, 0x75,
+	0x74, 0x70, 0x75, 0x74, 0x49, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x52, 0x0c, 0x6f, 0x75, 0x74, 0x70,
+	0x75, 0x74, 0x49, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x42, 0x17, 0x0a, 0x15, 0x5f, 0x63, 0x6f, 0x6d,
+	0x70, 0x61, 0x74, 0x69, 0x62, 0x69, 0x6c, 0x69, 0x74, 0x79, 0x5f, 0x70, 0x6f, 0x6c, 0x69, 0x63,
+	0x79, 0x4a, 0x04, 0x08, 0x02, 0x10, 0x03, 0x52, 0x10, 0x70, 0x64, 0x66, 0x61, 0x5f, 0x63, 0x6f,
+	0x6e, 0x66, 0x6f, 0x72, 0x6d, 0x61, 0x6e, 0x63, 0x65, 0x22, 0x6d, 0x0a, 0x0b, 0x58, 0x4d, 0x50,
+	0x50, 0x72, 0x6f, 0x70, 0x65, 0x72, 0x74, 0x79, 0x12, 0x1c, 0x0a, 0x09, 0x6e, 0x61, 0x6d, 0x65,
+	0x73, 0x70, 0x61, 0x63, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x6e, 0x61, 0x6d,
+	0x65, 0x73, 0x70, 0x61, 0x63, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x70, 0x72, 0x65, 0x66, 0x69, 0x78,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x70, 0x72, 0x65, 0x66, 0x69, 0x78, 0x12, 0x12,
+	0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61,
+	0x6d, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x22, 0xd5, 0x01, 0x0a, 0x08, 0x4d, 0x65, 0x74,
+	0x61, 0x64, 0x61, 0x74, 0x61, 0x12, 0x14, 0x0a, 0x05, 0x74, 0x69, 0x74, 0x6c, 0x65, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x74, 0x69, 0x74, 0x6c, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x61,
+	0x75, 0x74, 0x68, 0x6f, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x61, 0x75, 0x74,
+	0x68, 0x6f, 0x72, 0x12, 0x18, 0x0a, 0x07, 0x73, 0x75, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x73, 0x75, 0x62, 0x6a, 0x65, 0x63, 0x74, 0x12, 0x1a, 0x0a,
+	0x08, 0x6b, 0x65, 0x79, 0x77, 0x6f, 0x72, 0x64, 0x73, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x08, 0x6b, 0x65, 0x79, 0x77, 0x6f, 0x72, 0x64, 0x73, 0x12, 0x18, 0x0a, 0x07, 0x63, 0x72, 0x65,
+	0x61, 0x74, 0x6f, 0x72, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x63, 0x72, 0x65, 0x61,
+	0x74, 0x6f, 0x72, 0x12, 0x1a, 0x0a, 0x08, 0x6c, 0x61, 0x6e, 0x67, 0x75, 0x61, 0x67, 0x65, 0x18,
+	0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x6c, 0x61, 0x6e, 0x67, 0x75, 0x61, 0x67, 0x65, 0x12,
+	0x2f, 0x0a, 0x06, 0x63, 0x75, 0x73, 0x74, 0x6f, 0x6d, 0x18, 0x07, 0x20, 0x03, 0x28, 0x0b, 0x32,
+	0x17, 0x2e, 0x74, 0x65, 0x78, 0x5f, 0x74, 0x6f, 0x5f, 0x70, 0x64, 0x66, 0x2e, 0x58, 0x4d, 0x50,
+	0x50, 0x72, 0x6f, 0x70, 0x65, 0x72, 0x74, 0x79, 0x52, 0x06, 0x63, 0x75, 0x73, 0x74, 0x6f, 0x6d,
+	0x22, 0xbd, 0x01, 0x0a, 0x0e, 0x43, 0x6f, 0x6d, 0x70, 0x69, 0x6c, 0x65, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x12, 0x26, 0x0a, 0x05, 0x66, 0x69, 0x6c, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03,
+	0x28, 0x0b, 0x32, 0x10, 0x2e, 0x74, 0x65, 0x78, 0x5f, 0x74, 0x6f, 0x5f, 0x70, 0x64, 0x66, 0x2e,
+	0x46, 0x69, 0x6c, 0x65, 0x52, 0x05, 0x66, 0x69, 0x6c, 0x65, 0x73, 0x12, 0x34, 0x0a, 0x07, 0x6f,
+	0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x74,
 	0x65, 0x78, 0x5f, 0x74, 0x6f, 0x5f, 0x70, 0x64, 0x66, 0x2e, 0x43, 0x6f, 0x6d, 0x70, 0x69, 0x6c,
-	0x65, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x42, 0x3c, 0x5a, 0x3a, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62,
-	0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x74, 0x69, 0x6c, 0x73, 0x65, 0x69, 0x66, 0x66, 0x65, 0x72, 0x74,
-	0x2f, 0x64, 0x6f, 0x63, 0x6b, 0x65, 0x72, 0x2d, 0x74, 0x65, 0x78, 0x2d, 0x74, 0x6f, 0x2d, 0x70,
-	0x64, 0x66, 0x2f, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c, 0x2f, 0x70, 0x72, 0x6f, 0x74,
-	0x6f, 0x62, 0x75, 0x66, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x65, 0x4f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x52, 0x07, 0x6f, 0x70, 0x74, 0x69, 0x6f, 0x6e,
+	0x73, 0x12, 0x1b, 0x0a, 0x09, 0x6d, 0x61, 0x69, 0x6e, 0x5f, 0x66, 0x69, 0x6c, 0x65, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x6d, 0x61, 0x69, 0x6e, 0x46, 0x69, 0x6c, 0x65, 0x12, 0x30,
+	0x0a, 0x08, 0x6d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x14, 0x2e, 0x74, 0x65, 0x78, 0x5f, 0x74, 0x6f, 0x5f, 0x70, 0x64, 0x66, 0x2e, 0x4d, 0x65,
+	0x74, 0x61, 0x64, 0x61, 0x74, 0x61, 0x52, 0x08, 0x6d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74, 0x61,
+	0x22, 0x98, 0x01, 0x0a, 0x0a, 0x44, 0x69, 0x61, 0x67, 0x6e, 0x6f, 0x73, 0x74, 0x69, 0x63, 0x12,
+	0x1a, 0x0a, 0x08, 0x73, 0x65, 0x76, 0x65, 0x72, 0x69, 0x74, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x08, 0x73, 0x65, 0x76, 0x65, 0x72, 0x69, 0x74, 0x79, 0x12, 0x12, 0x0a, 0x04, 0x6b,
+	0x69, 0x6e, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6b, 0x69, 0x6e, 0x64, 0x12,
+	0x18, 0x0a, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x66, 0x69, 0x6c,
+	0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x66, 0x69, 0x6c, 0x65, 0x12, 0x12, 0x0a,
+	0x04, 0x6c, 0x69, 0x6e, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x6c, 0x69, 0x6e,
+	0x65, 0x12, 0x18, 0x0a, 0x07, 0x70, 0x61, 0x63, 0x6b, 0x61, 0x67, 0x65, 0x18, 0x06, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x07, 0x70, 0x61, 0x63, 0x6b, 0x61, 0x67, 0x65, 0x22, 0xb6, 0x01, 0x0a, 0x0e,
+	0x56, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x75, 0x6c, 0x65, 0x12, 0x24,
+	0x0a, 0x0d, 0x73, 0x70, 0x65, 0x63, 0x69, 0x66, 0x69, 0x63, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0d, 0x73, 0x70, 0x65, 0x63, 0x69, 0x66, 0x69, 0x63, 0x61,
+	0x74, 0x69, 0x6f, 0x6e, 0x12, 0x16, 0x0a, 0x06, 0x63, 0x6c, 0x61, 0x75, 0x73, 0x65, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x63, 0x6c, 0x61, 0x75, 0x73, 0x65, 0x12, 0x1f, 0x0a, 0x0b,
+	0x74, 0x65, 0x73, 0x74, 0x5f, 0x6e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x05, 0x52, 0x0a, 0x74, 0x65, 0x73, 0x74, 0x4e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x12, 0x20, 0x0a,
+	0x0b, 0x64, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x04, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x0b, 0x64, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x12,
+	0x23, 0x0a, 0x0d, 0x66, 0x61, 0x69, 0x6c, 0x65, 0x64, 0x5f, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x73,
+	0x18, 0x05, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0c, 0x66, 0x61, 0x69, 0x6c, 0x65, 0x64, 0x43, 0x68,
+	0x65, 0x63, 0x6b, 0x73, 0x22, 0xa7, 0x01, 0x0a, 0x10, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74,
+	0x69, 0x6f, 0x6e, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x12, 0x1c, 0x0a, 0x09, 0x76, 0x61, 0x6c,
+	0x69, 0x64, 0x61, 0x74, 0x6f, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x76, 0x61,
+	0x6c, 0x69, 0x64, 0x61, 0x74, 0x6f, 0x72, 0x12, 0x18, 0x0a, 0x07, 0x70, 0x72, 0x6f, 0x66, 0x69,
+	0x6c, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x70, 0x72, 0x6f, 0x66, 0x69, 0x6c,
+	0x65, 0x12, 0x1c, 0x0a, 0x09, 0x63, 0x6f, 0x6d, 0x70, 0x6c, 0x69, 0x61, 0x6e, 0x74, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x08, 0x52, 0x09, 0x63, 0x6f, 0x6d, 0x70, 0x6c, 0x69, 0x61, 0x6e, 0x74, 0x12,
+	0x3d, 0x0a, 0x0c, 0x66, 0x61, 0x69, 0x6c, 0x65, 0x64, 0x5f, 0x72, 0x75, 0x6c, 0x65, 0x73, 0x18,
+	0x04, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x74, 0x65, 0x78, 0x5f, 0x74, 0x6f, 0x5f, 0x70,
+	0x64, 0x66, 0x2e, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x75, 0x6c,
+	0x65, 0x52, 0x0b, 0x66, 0x61, 0x69, 0x6c, 0x65, 0x64, 0x52, 0x75, 0x6c, 0x65, 0x73, 0x22, 0xeb,
+	0x01, 0x0a, 0x0c, 0x43, 0x6f, 0x6d, 0x70, 0x69, 0x6c, 0x65, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12,
+	0x1f, 0x0a, 0x0b, 0x70, 0x64, 0x66, 0x5f, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x0c, 0x52, 0x0a, 0x70, 0x64, 0x66, 0x43, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74,
+	0x12, 0x10, 0x0a, 0x03, 0x6c, 0x6f, 0x67, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6c,
+	0x6f, 0x67, 0x12, 0x38, 0x0a, 0x0b, 0x64, 0x69, 0x61, 0x67, 0x6e, 0x6f, 0x73, 0x74, 0x69, 0x63,
+	0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x74, 0x65, 0x78, 0x5f, 0x74, 0x6f,
+	0x5f, 0x70, 0x64, 0x66, 0x2e, 0x44, 0x69, 0x61, 0x67, 0x6e, 0x6f, 0x73, 0x74, 0x69, 0x63, 0x52,
+	0x0b, 0x64, 0x69, 0x61, 0x67, 0x6e, 0x6f, 0x73, 0x74, 0x69, 0x63, 0x73, 0x12, 0x3c, 0x0a, 0x0a,
+	0x76, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x1c, 0x2e, 0x74, 0x65, 0x78, 0x5f, 0x74, 0x6f, 0x5f, 0x70, 0x64, 0x66, 0x2e, 0x56, 0x61,
+	0x6c, 0x69, 0x64, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x52, 0x0a,
+	0x76, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x15, 0x0a, 0x06, 0x6a, 0x6f,
+	0x62, 0x5f, 0x69, 0x64, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x6a, 0x6f, 0x62, 0x49,
+	0x64, 0x12, 0x19, 0x0a, 0x08, 0x70, 0x64, 0x66, 0x5f, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x06, 0x20,
+	0x01, 0x28, 0x03, 0x52, 0x07, 0x70, 0x64, 0x66, 0x53, 0x69, 0x7a, 0x65, 0x22, 0x78, 0x0a, 0x08,
+	0x50, 0x72, 0x6f, 0x67, 0x72, 0x65, 0x73, 0x73, 0x12, 0x27, 0x0a, 0x05, 0x70, 0x68, 0x61, 0x73,
+	0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x11, 0x2e, 0x74, 0x65, 0x78, 0x5f, 0x74, 0x6f,
+	0x5f, 0x70, 0x64, 0x66, 0x2e, 0x50, 0x68, 0x61, 0x73, 0x65, 0x52, 0x05, 0x70, 0x68, 0x61, 0x73,
+	0x65, 0x12, 0x12, 0x0a, 0x04, 0x70, 0x61, 0x73, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52,
+	0x04, 0x70, 0x61, 0x73, 0x73, 0x12, 0x18, 0x0a, 0x07, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x63, 0x6f, 0x6d, 0x6d, 0x61, 0x6e, 0x64, 0x12,
+	0x15, 0x0a, 0x06, 0x6a, 0x6f, 0x62, 0x5f, 0x69, 0x64, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x05, 0x6a, 0x6f, 0x62, 0x49, 0x64, 0x22, 0x5e, 0x0a, 0x07, 0x4c, 0x6f, 0x67, 0x4c, 0x69, 0x6e,
+	0x65, 0x12, 0x27, 0x0a, 0x05, 0x70, 0x68, 0x61, 0x73, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e,
+	0x32, 0x11, 0x2e, 0x74, 0x65, 0x78, 0x5f, 0x74, 0x6f, 0x5f, 0x70, 0x64, 0x66, 0x2e, 0x50, 0x68,
+	0x61, 0x73, 0x65, 0x52, 0x05, 0x70, 0x68, 0x61, 0x73, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x6c, 0x69,
+	0x6e, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6c, 0x69, 0x6e, 0x65, 0x12, 0x16,
+	0x0a, 0x06, 0x73, 0x74, 0x64, 0x65, 0x72, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x06,
+	0x73, 0x74, 0x64, 0x65, 0x72, 0x72, 0x22, 0xd0, 0x01, 0x0a, 0x0c, 0x43, 0x6f, 0x6d, 0x70, 0x69,
+	0x6c, 0x65, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x12, 0x32, 0x0a, 0x08, 0x70, 0x72, 0x6f, 0x67, 0x72,
+	0x65, 0x73, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x14, 0x2e, 0x74, 0x65, 0x78, 0x5f,
+	0x74, 0x6f, 0x5f, 0x70, 0x64, 0x66, 0x2e, 0x50, 0x72, 0x6f, 0x67, 0x72, 0x65, 0x73, 0x73, 0x48,
+	0x00, 0x52, 0x08, 0x70, 0x72, 0x6f, 0x67, 0x72, 0x65, 0x73, 0x73, 0x12, 0x30, 0x0a, 0x08, 0x6c,
+	0x6f, 0x67, 0x5f, 0x6c, 0x69, 0x6e, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x13, 0x2e,
+	0x74, 0x65, 0x78, 0x5f, 0x74, 0x6f, 0x5f, 0x70, 0x64, 0x66, 0x2e, 0x4c, 0x6f, 0x67, 0x4c, 0x69,
+	0x6e, 0x65, 0x48, 0x00, 0x52, 0x07, 0x6c, 0x6f, 0x67, 0x4c, 0x69, 0x6e, 0x65, 0x12, 0x32, 0x0a,
+	0x06, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x18, 0x2e,
+	0x74, 0x65, 0x78, 0x5f, 0x74, 0x6f, 0x5f, 0x70, 0x64, 0x66, 0x2e, 0x43, 0x6f, 0x6d, 0x70, 0x69,
+	0x6c, 0x65, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x48, 0x00, 0x52, 0x06, 0x72, 0x65, 0x73, 0x75, 0x6c,
+	0x74, 0x12, 0x1d, 0x0a, 0x09, 0x70, 0x64, 0x66, 0x5f, 0x63, 0x68, 0x75, 0x6e, 0x6b, 0x18, 0x04,
+	0x20, 0x01, 0x28, 0x0c, 0x48, 0x00, 0x52, 0x08, 0x70, 0x64, 0x66, 0x43, 0x68, 0x75, 0x6e, 0x6b,
+	0x42, 0x07, 0x0a, 0x05, 0x65, 0x76, 0x65, 0x6e, 0x74, 0x2a, 0xa2, 0x01, 0x0a, 0x05, 0x50, 0x68,
+	0x61, 0x73, 0x65, 0x12, 0x15, 0x0a, 0x11, 0x50, 0x48, 0x41, 0x53, 0x45, 0x5f, 0x55, 0x4e, 0x53,
+	0x50, 0x45, 0x43, 0x49, 0x46, 0x49, 0x45, 0x44, 0x10, 0x00, 0x12, 0x10, 0x0a, 0x0c, 0x50, 0x48,
+	0x41, 0x53, 0x45, 0x5f, 0x51, 0x55, 0x45, 0x55, 0x45, 0x44, 0x10, 0x01, 0x12, 0x13, 0x0a, 0x0f,
+	0x50, 0x48, 0x41, 0x53, 0x45, 0x5f, 0x43, 0x4f, 0x4d, 0x50, 0x49, 0x4c, 0x49, 0x4e, 0x47, 0x10,
+	0x02, 0x12, 0x1a, 0x0a, 0x16, 0x50, 0x48, 0x41, 0x53, 0x45, 0x5f, 0x43, 0x4f, 0x4e, 0x56, 0x45,
+	0x52, 0x54, 0x49, 0x4e, 0x47, 0x5f, 0x50, 0x44, 0x46, 0x41, 0x31, 0x10, 0x03, 0x12, 0x19, 0x0a,
+	0x15, 0x50, 0x48, 0x41, 0x53, 0x45, 0x5f, 0x43, 0x4f, 0x4e, 0x56, 0x45, 0x52, 0x54, 0x49, 0x4e,
+	0x47, 0x5f, 0x50, 0x44, 0x46, 0x41, 0x10, 0x04, 0x12, 0x14, 0x0a, 0x10, 0x50, 0x48, 0x41, 0x53,
+	0x45, 0x5f, 0x56, 0x41, 0x4c, 0x49, 0x44, 0x41, 0x54, 0x49, 0x4e, 0x47, 0x10, 0x05, 0x12, 0x0e,
+	0x0a, 0x0a, 0x50, 0x48, 0x41, 0x53, 0x45, 0x5f, 0x44, 0x4f, 0x4e, 0x45, 0x10, 0x06, 0x32, 0x9c,
+	0x01, 0x0a, 0x0b, 0x54, 0x65, 0x78, 0x43, 0x6f, 0x6d, 0x70, 0x69, 0x6c, 0x65, 0x72, 0x12, 0x44,
+	0x0a, 0x0c, 0x43, 0x6f, 0x6d, 0x70, 0x69, 0x6c, 0x65, 0x54, 0x6f, 0x50, 0x44, 0x46, 0x12, 0x1a,
+	0x2e, 0x74, 0x65, 0x78, 0x5f, 0x74, 0x6f, 0x5f, 0x70, 0x64, 0x66, 0x2e, 0x43, 0x6f, 0x6d, 0x70,
+	0x69, 0x6c, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x18, 0x2e, 0x74, 0x65, 0x78,
+	0x5f, 0x74, 0x6f, 0x5f, 0x70, 0x64, 0x66, 0x2e, 0x43, 0x6f, 0x6d, 0x70, 0x69, 0x6c, 0x65, 0x52,
+	0x65, 0x70, 0x6c, 0x79, 0x12, 0x47, 0x0a, 0x0d, 0x43, 0x6f, 0x6d, 0x70, 0x69, 0x6c, 0x65, 0x53,
+	0x74, 0x72, 0x65, 0x61, 0x6d, 0x12, 0x1a, 0x2e, 0x74, 0x65, 0x78, 0x5f, 0x74, 0x6f, 0x5f, 0x70,
+	0x64, 0x66, 0x2e, 0x43, 0x6f, 0x6d, 0x70, 0x69, 0x6c, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x1a, 0x18, 0x2e, 0x74, 0x65, 0x78, 0x5f, 0x74, 0x6f, 0x5f, 0x70, 0x64, 0x66, 0x2e, 0x43,
+	0x6f, 0x6d, 0x70, 0x69, 0x6c, 0x65, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x30, 0x01, 0x42, 0x3c, 0x5a,
+	0x3a, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x74, 0x69, 0x6c, 0x73,
+	0x65, 0x69, 0x66, 0x66, 0x65, 0x72, 0x74, 0x2f, 0x64, 0x6f, 0x63, 0x6b, 0x65, 0x72, 0x2d, 0x74,
+	0x65, 0x78, 0x2d, 0x74, 0x6f, 0x2d, 0x70, 0x64, 0x66, 0x2f, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x6e,
+	0x61, 0x6c, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x62, 0x06, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x33,
 }
 
 var (
@@ -217,21 +1432,49 @@ func file_tex_to_pdf_proto_rawDescGZIP() []byte {
 	return file_tex_to_pdf_proto_rawDescData
 }
 
-var file_tex_to_pdf_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_tex_to_pdf_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_tex_to_pdf_proto_msgTypes = make([]protoimpl.MessageInfo, 14)
 var file_tex_to_pdf_proto_goTypes = []interface{}{
-	(*File)(nil),           // 0: tex_to_pdf.File
-	(*CompileRequest)(nil), // 1: tex_to_pdf.CompileRequest
-	(*CompileReply)(nil),   // 2: tex_to_pdf.CompileReply
+	(Phase)(0),               // 0: tex_to_pdf.Phase
+	(*File)(nil),             // 1: tex_to_pdf.File
+	(*Attachment)(nil),       // 2: tex_to_pdf.Attachment
+	(*OutputIntent)(nil),     // 3: tex_to_pdf.OutputIntent
+	(*CompileOptions)(nil),   // 4: tex_to_pdf.CompileOptions
+	(*XMPProperty)(nil),      // 5: tex_to_pdf.XMPProperty
+	(*Metadata)(nil),         // 6: tex_to_pdf.Metadata
+	(*CompileRequest)(nil),   // 7: tex_to_pdf.CompileRequest
+	(*Diagnostic)(nil),       // 8: tex_to_pdf.Diagnostic
+	(*ValidationRule)(nil),   // 9: tex_to_pdf.ValidationRule
+	(*ValidationReport)(nil), // 10: tex_to_pdf.ValidationReport
+	(*CompileReply)(nil),     // 11: tex_to_pdf.CompileReply
+	(*Progress)(nil),         // 12: tex_to_pdf.Progress
+	(*LogLine)(nil),          // 13: tex_to_pdf.LogLine
+	(*CompileEvent)(nil),     // 14: tex_to_pdf.CompileEvent
 }
 var file_tex_to_pdf_proto_depIdxs = []int32{
-	0, // 0: tex_to_pdf.CompileRequest.files:type_name -> tex_to_pdf.File
-	1, // 1: tex_to_pdf.TexCompiler.CompileToPDF:input_type -> tex_to_pdf.CompileRequest
-	2, // 2: tex_to_pdf.TexCompiler.CompileToPDF:output_type -> tex_to_pdf.CompileReply
-	2, // [2:3] is the sub-list for method output_type
-	1, // [1:2] is the sub-list for method input_type
-	1, // [1:1] is the sub-list for extension type_name
-	1, // [1:1] is the sub-list for extension extendee
-	0, // [0:1] is the sub-list for field type_name
+	2,  // 0: tex_to_pdf.CompileOptions.attachments:type_name -> tex_to_pdf.Attachment
+	3,  // 1: tex_to_pdf.CompileOptions.output_intent:type_name -> tex_to_pdf.OutputIntent
+	5,  // 2: tex_to_pdf.Metadata.custom:type_name -> tex_to_pdf.XMPProperty
+	1,  // 3: tex_to_pdf.CompileRequest.files:type_name -> tex_to_pdf.File
+	4,  // 4: tex_to_pdf.CompileRequest.options:type_name -> tex_to_pdf.CompileOptions
+	6,  // 5: tex_to_pdf.CompileRequest.metadata:type_name -> tex_to_pdf.Metadata
+	9,  // 6: tex_to_pdf.ValidationReport.failed_rules:type_name -> tex_to_pdf.ValidationRule
+	8,  // 7: tex_to_pdf.CompileReply.diagnostics:type_name -> tex_to_pdf.Diagnostic
+	10, // 8: tex_to_pdf.CompileReply.validation:type_name -> tex_to_pdf.ValidationReport
+	0,  // 9: tex_to_pdf.Progress.phase:type_name -> tex_to_pdf.Phase
+	0,  // 10: tex_to_pdf.LogLine.phase:type_name -> tex_to_pdf.Phase
+	12, // 11: tex_to_pdf.CompileEvent.progress:type_name -> tex_to_pdf.Progress
+	13, // 12: tex_to_pdf.CompileEvent.log_line:type_name -> tex_to_pdf.LogLine
+	11, // 13: tex_to_pdf.CompileEvent.result:type_name -> tex_to_pdf.CompileReply
+	7,  // 14: tex_to_pdf.TexCompiler.CompileToPDF:input_type -> tex_to_pdf.CompileRequest
+	7,  // 15: tex_to_pdf.TexCompiler.CompileStream:input_type -> tex_to_pdf.CompileRequest
+	11, // 16: tex_to_pdf.TexCompiler.CompileToPDF:output_type -> tex_to_pdf.CompileReply
+	14, // 17: tex_to_pdf.TexCompiler.CompileStream:output_type -> tex_to_pdf.CompileEvent
+	16, // [16:18] is the sub-list for method output_type
+	14, // [14:16] is the sub-list for method input_type
+	14, // [14:14] is the sub-list for extension type_name
+	14, // [14:14] is the sub-list for extension extendee
+	0,  // [0:14] is the sub-list for field type_name
 }
 
 func init() { file_tex_to_pdf_proto_init() }
@@ -253,7 +1496,7 @@ func file_tex_to_pdf_proto_init() {
 			}
 		}
 		file_tex_to_pdf_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*CompileRequest); i {
+			switch v := v.(*Attachment); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -265,6 +1508,102 @@ func file_tex_to_pdf_proto_init() {
 			}
 		}
 		file_tex_to_pdf_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*OutputIntent); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_tex_to_pdf_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CompileOptions); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_tex_to_pdf_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*XMPProperty); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_tex_to_pdf_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Metadata); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_tex_to_pdf_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CompileRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_tex_to_pdf_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Diagnostic); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_tex_to_pdf_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ValidationRule); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_tex_to_pdf_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ValidationReport); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_tex_to_pdf_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*CompileReply); i {
 			case 0:
 				return &v.state
@@ -276,19 +1615,63 @@ func file_tex_to_pdf_proto_init() {
 				return nil
 			}
 		}
+		file_tex_to_pdf_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Progress); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_tex_to_pdf_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*LogLine); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_tex_to_pdf_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CompileEvent); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	file_tex_to_pdf_proto_msgTypes[3].OneofWrappers = []interface{}{}
+	file_tex_to_pdf_proto_msgTypes[13].OneofWrappers = []interface{}{
+		(*CompileEvent_Progress)(nil),
+		(*CompileEvent_LogLine)(nil),
+		(*CompileEvent_Result)(nil),
+		(*CompileEvent_PdfChunk)(nil),
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_tex_to_pdf_proto_rawDesc,
-			NumEnums:      0,
-			NumMessages:   3,
+			NumEnums:      1,
+			NumMessages:   14,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_tex_to_pdf_proto_goTypes,
 		DependencyIndexes: file_tex_to_pdf_proto_depIdxs,
+		EnumInfos:         file_tex_to_pdf_proto_enumTypes,
 		MessageInfos:      file_tex_to_pdf_proto_msgTypes,
 	}.Build()
 	File_tex_to_pdf_proto = out.File
@@ -19,7 +19,8 @@ import (
 const _ = grpc.SupportPackageIsVersion7
 
 const (
-	TexCompiler_CompileToPDF_FullMethodName = "/tex_to_pdf.TexCompiler/CompileToPDF"
+	TexCompiler_CompileToPDF_FullMethodName  = "/tex_to_pdf.TexCompiler/CompileToPDF"
+	TexCompiler_CompileStream_FullMethodName = "/tex_to_pdf.TexCompiler/CompileStream"
 )
 
 // TexCompilerClient is the client API for TexCompiler service.
@@ -27,6 +28,9 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type TexCompilerClient interface {
 	CompileToPDF(ctx context.Context, in *CompileRequest, opts ...grpc.CallOption) (*CompileReply, error)
+	// CompileStream compiles like CompileToPDF, but reports the progress while compiling
+	// The stream ends with the result and the PDF in chunks, failures end it with the same errors as CompileToPDF
+	CompileStream(ctx context.Context, in *CompileRequest, opts ...grpc.CallOption) (TexCompiler_CompileStreamClient, error)
 }
 
 type texCompilerClient struct {
@@ -46,11 +50,46 @@ func (c *texCompilerClient) CompileToPDF(ctx context.Context, in *CompileRequest
 	return out, nil
 }
 
+func (c *texCompilerClient) CompileStream(ctx context.Context, in *CompileRequest, opts ...grpc.CallOption) (TexCompiler_CompileStreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &TexCompiler_ServiceDesc.Streams[0], TexCompiler_CompileStream_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &texCompilerCompileStreamClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type TexCompiler_CompileStreamClient interface {
+	Recv() (*CompileEvent, error)
+	grpc.ClientStream
+}
+
+type texCompilerCompileStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *texCompilerCompileStreamClient) Recv() (*CompileEvent, error) {
+	m := new(CompileEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // TexCompilerServer is the server API for TexCompiler service.
 // All implementations must embed UnimplementedTexCompilerServer
 // for forward compatibility
 type TexCompilerServer interface {
 	CompileToPDF(context.Context, *CompileRequest) (*CompileReply, error)
+	// CompileStream compiles like CompileToPDF, but reports the progress while compiling
+	// The stream ends with the result and the PDF in chunks, failures end it with the same errors as CompileToPDF
+	CompileStream(*CompileRequest, TexCompiler_CompileStreamServer) error
 	mustEmbedUnimplementedTexCompilerServer()
 }
 
@@ -61,6 +100,9 @@ type UnimplementedTexCompilerServer struct {
 func (UnimplementedTexCompilerServer) CompileToPDF(context.Context, *CompileRequest) (*CompileReply, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method CompileToPDF not implemented")
 }
+func (UnimplementedTexCompilerServer) CompileStream(*CompileRequest, TexCompiler_CompileStreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method CompileStream not implemented")
+}
 func (UnimplementedTexCompilerServer) mustEmbedUnimplementedTexCompilerServer() {}
 
 // UnsafeTexCompilerServer may be embedded to opt out of forward compatibility for this service.
@@ -92,6 +134,27 @@ func _TexCompiler_CompileToPDF_Handler(srv interface{}, ctx context.Context, dec
 	return interceptor(ctx, in, info, handler)
 }
 
+func _TexCompiler_CompileStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(CompileRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(TexCompilerServer).CompileStream(m, &texCompilerCompileStreamServer{stream})
+}
+
+type TexCompiler_CompileStreamServer interface {
+	Send(*CompileEvent) error
+	grpc.ServerStream
+}
+
+type texCompilerCompileStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *texCompilerCompileStreamServer) Send(m *CompileEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 // TexCompiler_ServiceDesc is the grpc.ServiceDesc for TexCompiler service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -104,6 +167,12 @@ var TexCompiler_ServiceDesc = grpc.ServiceDesc{
 			Handler:    _TexCompiler_CompileToPDF_Handler,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "CompileStream",
+			Handler:       _TexCompiler_CompileStream_Handler,
+			ServerStreams: true,
+		},
+	},
 	Metadata: "tex_to_pdf.proto",
 }
@@ -0,0 +1,165 @@
+package restserver
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/tilseiffert/docker-tex-to-pdf/pkg/server"
+	"gorm.io/gorm"
+)
+
+const (
+	API_KEY_PREFIX = "ttp_" // prefix of the generated keys, makes them recognizable (e.g. for secret scanners)
+	API_KEY_BYTES  = 32     // random bytes of a key
+
+	// API_KEY_TOUCH_INTERVAL limits how often the last use of a key is recorded
+	API_KEY_TOUCH_INTERVAL = time.Minute
+)
+
+// APIKeys holds an API key, only its hash is stored
+// The keys are random, so a plain SHA-256 hash is sufficient (unlike passwords, they cannot be guessed from a dictionary)
+// Revoked keys are soft-deleted
+type APIKeys struct {
+	gorm.Model
+	Name       string     `json:"name"`
+	Role       string     `json:"role"`                   // server.ROLE_CLIENT or server.ROLE_ADMIN
+	Hash       string     `json:"-" gorm:"uniqueIndex"`   // hex encoded SHA-256 of the key
+	Hint       string     `json:"hint"`                   // first characters of the key, to tell keys apart
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"` // nil if never used
+}
+
+// hashAPIKey returns the hash of a key as stored in the db
+func hashAPIKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
+
+// CreateAPIKey creates an API key with the given role and returns it, the key cannot be retrieved later
+func (srv *Server) CreateAPIKey(name string, role string) (string, *APIKeys, error) {
+
+	if name == "" {
+		return "", nil, errors.New("name is empty")
+	}
+
+	if role != server.ROLE_CLIENT && role != server.ROLE_ADMIN {
+		return "", nil, fmt.Errorf("unknown role '%s', expected %s or %s", role, server.ROLE_CLIENT, server.ROLE_ADMIN)
+	}
+
+	random := make([]byte, API_KEY_BYTES)
+
+	if _, err := rand.Read(random); err != nil {
+		return "", nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	key := API_KEY_PREFIX + hex.EncodeToString(random)
+
+	apikey := &APIKeys{
+		Name: name,
+		Role: role,
+		Hash: hashAPIKey(key),
+		Hint: key[:len(API_KEY_PREFIX)+6],
+	}
+
+	if tx := srv.db.Create(apikey); tx.Error != nil {
+		return "", nil, fmt.Errorf("failed to store key: %w", tx.Error)
+	}
+
+	return key, apikey, nil
+}
+
+// ListAPIKeys returns the API keys that have not been revoked
+func (srv *Server) ListAPIKeys() ([]APIKeys, error) {
+
+	var apikeys []APIKeys
+
+	if tx := srv.db.Order("id").Find(&apikeys); tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	return apikeys, nil
+}
+
+// RevokeAPIKey revokes an API key, the jobs created with it stay visible to admins only
+func (srv *Server) RevokeAPIKey(id uint) error {
+
+	tx := srv.db.Delete(&APIKeys{}, id)
+
+	if tx.Error != nil {
+		return tx.Error
+	}
+
+	if tx.RowsAffected == 0 {
+		return fmt.Errorf("no API key with id %d", id)
+	}
+
+	return nil
+}
+
+// Authenticate implements server.Authenticator, the id of the caller is the id of its key
+func (srv *Server) Authenticate(ctx context.Context, key string) (*server.Principal, error) {
+
+	var apikey APIKeys
+
+	tx := srv.db.WithContext(ctx).Where("hash = ?", hashAPIKey(key)).Limit(1).Find(&apikey)
+
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	if tx.RowsAffected == 0 {
+		return nil, server.ErrInvalidKey
+	}
+
+	now := time.Now()
+
+	if apikey.LastUsedAt == nil || now.Sub(*apikey.LastUsedAt) > API_KEY_TOUCH_INTERVAL {
+		// not recording the use does not affect the request
+		_ = srv.db.Model(&APIKeys{}).Where("id = ?", apikey.ID).Update("last_used_at", now)
+	}
+
+	return &server.Principal{
+		ID:   strconv.FormatUint(uint64(apikey.ID), 10),
+		Name: apikey.Name,
+		Role: apikey.Role,
+	}, nil
+}
+
+// jobOwner returns the owner of jobs created in ctx, empty if authentication is disabled
+func jobOwner(ctx context.Context) string {
+
+	if principal, ok := server.PrincipalFromContext(ctx); ok {
+		return principal.ID
+	}
+
+	return ""
+}
+
+// jobs returns the jobs visible to the caller of a request: its own jobs, or all jobs for admins and if authentication is disabled
+// Jobs of others are not found, so callers cannot tell them from jobs that do not exist
+func (srv *Server) jobs(ctx context.Context) *gorm.DB {
+
+	if principal, ok := server.PrincipalFromContext(ctx); ok && !principal.Admin() {
+		return srv.db.Where("owner = ?", principal.ID)
+	}
+
+	return srv.db
+}
+
+// requireAdmin writes an error response and returns false unless the caller of a request is an admin or authentication is disabled
+func requireAdmin(w http.ResponseWriter, r *http.Request, logger *slog.Logger) bool {
+
+	if principal, ok := server.PrincipalFromContext(r.Context()); ok && !principal.Admin() {
+		_ = server.WriteError(w, http.StatusForbidden, "admin role required [G8TN4WKE]", logger)
+		return false
+	}
+
+	return true
+}
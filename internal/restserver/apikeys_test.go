@@ -0,0 +1,196 @@
+package restserver
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tilseiffert/docker-tex-to-pdf/pkg/server"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestServer returns a server with its own SQLite database and job dir, no workers are started
+func newTestServer(t *testing.T, options *ServerOptions) *Server {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	db, err := OpenDB(DB_DRIVER_SQLITE, filepath.Join(dir, "test.db"), &gorm.Config{Logger: logger.Discard})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if options == nil {
+		options = &ServerOptions{}
+	}
+
+	options.JobDir = filepath.Join(dir, "jobs")
+
+	srv, err := NewServer(db, options)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return srv
+}
+
+// newTestAPI serves the endpoints of srv with authentication, as `serve -auth` does
+func newTestAPI(t *testing.T, srv *Server) *httptest.Server {
+	t.Helper()
+
+	rest := server.NewRestServer(slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	api := httptest.NewServer(rest.Handler(&server.RestServerOptions{
+		CallbackEndpointRegister: srv.RegisterEndpoints,
+		Authenticator:            srv,
+		PublicPaths:              PublicPaths,
+	}))
+
+	t.Cleanup(api.Close)
+
+	return api
+}
+
+// request sends a request with the given API key (none if empty) and returns the status code and the data of a JSON response
+func request(t *testing.T, api *httptest.Server, method string, path string, key string, body string) (int, json.RawMessage) {
+	t.Helper()
+
+	req, err := http.NewRequest(method, api.URL+path, strings.NewReader(body))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+
+	resp, err := api.Client().Do(req)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer resp.Body.Close()
+
+	var envelope struct {
+		Data json.RawMessage `json:"data"`
+	}
+
+	// e.g. ping answers with plain text
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		return resp.StatusCode, nil
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		t.Fatalf("%s %s: could not decode response: %v", method, path, err)
+	}
+
+	return resp.StatusCode, envelope.Data
+}
+
+func TestJobOwnership(t *testing.T) {
+
+	srv := newTestServer(t, nil)
+	api := newTestAPI(t, srv)
+
+	keys := map[string]string{}
+
+	for _, k := range []struct{ name, role string }{
+		{"owner", server.ROLE_CLIENT},
+		{"other", server.ROLE_CLIENT},
+		{"admin", server.ROLE_ADMIN},
+	} {
+		key, _, err := srv.CreateAPIKey(k.name, k.role)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		keys[k.name] = key
+	}
+
+	code, data := request(t, api, http.MethodPost, "/api/v1/createJob", keys["owner"], `{"name": "invoice", "tex_content": "\\documentclass{article}"}`)
+
+	if code != http.StatusAccepted {
+		t.Fatalf("createJob returned %d, want %d", code, http.StatusAccepted)
+	}
+
+	var created ResponseCreateJob
+
+	if err := json.Unmarshal(data, &created); err != nil || created.JobID == "" {
+		t.Fatalf("createJob returned no job id: %s", data)
+	}
+
+	job := "/api/v1/job/" + created.JobID
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		key    string
+		want   int
+	}{
+		{name: "ping without key", method: http.MethodGet, path: "/api/v1/ping", want: http.StatusOK},
+		{name: "status without key", method: http.MethodGet, path: job + "/status", want: http.StatusUnauthorized},
+		{name: "status with invalid key", method: http.MethodGet, path: job + "/status", key: API_KEY_PREFIX + "invalid", want: http.StatusUnauthorized},
+		{name: "status of owner", method: http.MethodGet, path: job + "/status", key: keys["owner"], want: http.StatusOK},
+		{name: "status of other client", method: http.MethodGet, path: job + "/status", key: keys["other"], want: http.StatusNotFound},
+		{name: "status of admin", method: http.MethodGet, path: job + "/status", key: keys["admin"], want: http.StatusOK},
+		{name: "log of other client", method: http.MethodGet, path: job + "/log", key: keys["other"], want: http.StatusNotFound},
+		{name: "cancel of other client", method: http.MethodPost, path: job + "/cancel", key: keys["other"], want: http.StatusNotFound},
+		{name: "delete of other client", method: http.MethodDelete, path: job, key: keys["other"], want: http.StatusNotFound},
+		{name: "cache of client", method: http.MethodGet, path: "/api/v1/cache", key: keys["owner"], want: http.StatusForbidden},
+		{name: "cache of admin", method: http.MethodGet, path: "/api/v1/cache", key: keys["admin"], want: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code, _ := request(t, api, tt.method, tt.path, tt.key, ""); code != tt.want {
+				t.Errorf("%s %s returned %d, want %d", tt.method, tt.path, code, tt.want)
+			}
+		})
+	}
+
+	lists := []struct {
+		key  string
+		want int
+	}{
+		{key: "owner", want: 1},
+		{key: "other", want: 0},
+		{key: "admin", want: 1},
+	}
+
+	for _, tt := range lists {
+		t.Run("list of "+tt.key, func(t *testing.T) {
+			code, data := request(t, api, http.MethodGet, "/api/v1/jobs", keys[tt.key], "")
+
+			var list ResponseJobList
+
+			if err := json.Unmarshal(data, &list); code != http.StatusOK || err != nil {
+				t.Fatalf("jobs returned %d: %s", code, data)
+			}
+
+			if len(list.Jobs) != tt.want {
+				t.Errorf("jobs listed %d jobs, want %d", len(list.Jobs), tt.want)
+			}
+		})
+	}
+
+	// the job is still there after the attempts of the other client, its owner cancels and deletes it
+	if code, _ := request(t, api, http.MethodPost, job+"/cancel", keys["owner"], ""); code != http.StatusOK {
+		t.Errorf("cancel of owner returned %d, want %d", code, http.StatusOK)
+	}
+
+	if code, _ := request(t, api, http.MethodDelete, job, keys["owner"], ""); code != http.StatusOK {
+		t.Errorf("delete of owner returned %d, want %d", code, http.StatusOK)
+	}
+}
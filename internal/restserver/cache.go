@@ -0,0 +1,523 @@
+package restserver
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/tilseiffert/docker-tex-to-pdf/internal/textopdfa"
+	"github.com/tilseiffert/docker-tex-to-pdf/pkg/server"
+	"gorm.io/gorm"
+)
+
+const (
+	DEFAULT_CACHEDIR       = "./cache"
+	DEFAULT_CACHE_MAX_SIZE = 1 << 30 // 1 GiB
+
+	// CACHE_KEY_VERSION is part of every cache key, changing it invalidates all entries (e.g. if the pipeline changes)
+	CACHE_KEY_VERSION = "textopdfa-cache-1"
+
+	cacheExtPDF    = ".pdf"
+	cacheExtTexLog = ".log"
+)
+
+// CacheEntries holds the outcome of a successful compilation, the files are stored in the cache dir named by the key
+type CacheEntries struct {
+	gorm.Model
+	CacheKey    string    `json:"key" gorm:"uniqueIndex"` // hex encoded SHA-256 of the inputs (see cacheKey)
+	JobID       string    `json:"job_id"`                 // job that was compiled
+	Size        int64     `json:"size"`                   // bytes of the stored files
+	Hits        int       `json:"hits"`
+	LastUsedAt  time.Time `json:"last_used_at" gorm:"index"` // creation or last hit, the least recently used entries are evicted first
+	TexLog      bool      `json:"tex_log"`                   // a LaTeX log is stored along with the PDF
+	Stages      string    `json:"stages"`                    // output of the commands as JSON (see textopdfa.StageLog)
+	Diagnostics string    `json:"diagnostics"`               // diagnostics as JSON, empty if there are none
+	Validation  string    `json:"validation"`                // validation report as JSON, empty if not validated
+}
+
+type ResponseCache struct {
+	Entries int   `json:"entries"`
+	Size    int64 `json:"size"`     // bytes
+	MaxSize int64 `json:"max_size"` // bytes
+	Hits    int   `json:"hits"`     // hits of the current entries
+}
+
+type ResponseCachePurge struct {
+	Entries int   `json:"entries"` // number of removed entries
+	Size    int64 `json:"size"`    // bytes freed
+}
+
+// cacheEnabled returns true if compilations are cached (see ServerOptions.CacheMaxSize)
+func (srv *Server) cacheEnabled() bool {
+	return srv.Options.CacheMaxSize > 0
+}
+
+// cachePath returns the path of a stored file of a cache entry
+func (srv *Server) cachePath(key string, ext string) string {
+	return filepath.Join(srv.cachedir, key+ext)
+}
+
+// cacheKey returns the key of a job in the cache: the hash of all files in the job directory, the main file, the options, the registry ICC
+// profile selected by them and the versions of the commands run with them. It returns an empty key if the cache is disabled or the key could
+// not be computed
+func (srv *Server) cacheKey(ctx context.Context, job *Jobs, opts *textopdfa.CompileOptions, logger *slog.Logger) string {
+
+	if !srv.cacheEnabled() {
+		return ""
+	}
+
+	versions, err := srv.toolchainVersions(ctx, opts)
+
+	if err != nil {
+		logger.Warn("Could not determine toolchain, not using the cache", "err", err)
+		return ""
+	}
+
+	// the options may only name VALIDATOR_AUTO, the result depends on the validator it picks
+	validator := opts.WithDefaults().Validator
+
+	if validator != "" {
+		validator, err = textopdfa.ResolveValidator(validator)
+
+		if err != nil {
+			logger.Warn("Could not determine validator, not using the cache", "err", err)
+			return ""
+		}
+	}
+
+	h := sha256.New()
+
+	fmt.Fprintf(h, "%s\x00%s\x00%s\x00%s\x00%s\x00", CACHE_KEY_VERSION, job.MainFile, job.Options, validator, versions)
+
+	// the options only name a registry profile, the file may be replaced under the same name
+	profile, err := textopdfa.RegistryProfilePath(opts)
+
+	if err == nil && profile != "" {
+		err = hashFile(h, "icc_profile", profile)
+	}
+
+	if err != nil {
+		logger.Warn("Could not hash ICC profile, not using the cache", "err", err)
+		return ""
+	}
+
+	// WalkDir visits the files in lexical order, so the key does not depend on the order they were written in
+	err = filepath.WalkDir(job.Path, func(path string, d fs.DirEntry, err error) error {
+
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+
+		rel, err := filepath.Rel(job.Path, path)
+
+		if err != nil {
+			return err
+		}
+
+		return hashFile(h, filepath.ToSlash(rel), path)
+	})
+
+	if err != nil {
+		logger.Warn("Could not hash job files, not using the cache", "err", err)
+		return ""
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// hashFile writes the name, size and content of a file to h
+func hashFile(h io.Writer, name string, path string) error {
+
+	file, err := os.Open(path)
+
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	info, err := file.Stat()
+
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(h, "%s\x00%d\x00", name, info.Size())
+
+	_, err = io.Copy(h, file)
+
+	return err
+}
+
+// toolchainVersions returns the versions of the commands run with the given options, one "name: version" per line
+// The versions are determined once per command, the server has to be restarted after the tools were updated
+func (srv *Server) toolchainVersions(ctx context.Context, opts *textopdfa.CompileOptions) (string, error) {
+
+	commands, err := textopdfa.Commands(opts)
+
+	if err != nil {
+		return "", err
+	}
+
+	srv.versionsMu.Lock()
+	defer srv.versionsMu.Unlock()
+
+	var versions strings.Builder
+
+	for _, command := range commands {
+		version, ok := srv.versions[command]
+
+		if !ok {
+			version, err = textopdfa.CommandVersion(ctx, command)
+
+			if err != nil {
+				return "", err
+			}
+
+			srv.versions[command] = version
+		}
+
+		fmt.Fprintf(&versions, "%s: %s\n", command, version)
+	}
+
+	return versions.String(), nil
+}
+
+// cacheLookup returns the result of a compilation with the given key, with the files copied next to the tex-file of the job
+// It returns false if there is none, an empty key is never found
+func (srv *Server) cacheLookup(key string, texfile_path string, logger *slog.Logger) (*textopdfa.Result, bool) {
+
+	if key == "" {
+		return nil, false
+	}
+
+	var entry CacheEntries
+
+	if tx := srv.db.Where("cache_key = ?", key).Limit(1).Find(&entry); tx.Error != nil || tx.RowsAffected == 0 {
+		if tx.Error != nil {
+			logger.Error("Error looking up cache entry [U4PN7HXC]", "err", tx.Error)
+		}
+
+		return nil, false
+	}
+
+	// the result is placed where the pipeline puts it (see textopdfa.CompileTexToPDFA)
+	maindir := filepath.Dir(texfile_path)
+	basename := strings.TrimSuffix(filepath.Base(texfile_path), filepath.Ext(texfile_path))
+
+	result := &textopdfa.Result{Path: filepath.Join(maindir, basename+".pdf")}
+
+	err := copyFile(srv.cachePath(key, cacheExtPDF), result.Path)
+
+	if err == nil && entry.TexLog {
+		result.TexLogPath = filepath.Join(maindir, basename+".log")
+		err = copyFile(srv.cachePath(key, cacheExtTexLog), result.TexLogPath)
+	}
+
+	if err == nil {
+		err = unmarshalCacheEntry(&entry, result)
+	}
+
+	if err != nil {
+		logger.Warn("Cache entry is broken, removing it", "key", key, "err", err)
+		os.Remove(result.Path)
+
+		if result.TexLogPath != "" {
+			os.Remove(result.TexLogPath)
+		}
+
+		srv.removeCacheEntries([]CacheEntries{entry}, logger)
+
+		return nil, false
+	}
+
+	tx := srv.db.Model(&CacheEntries{}).Where("id = ?", entry.ID).
+		Updates(map[string]interface{}{"hits": gorm.Expr("hits + 1"), "last_used_at": time.Now()})
+
+	if tx.Error != nil {
+		logger.Error("Error updating cache entry [S0JD5KQW]", "err", tx.Error)
+	}
+
+	logger.Info("Using cached result", "key", key, "compiled_by", entry.JobID)
+
+	return result, true
+}
+
+// unmarshalCacheEntry restores the output of the compilation stored in a cache entry
+func unmarshalCacheEntry(entry *CacheEntries, result *textopdfa.Result) error {
+
+	if entry.Stages != "" {
+		if err := json.Unmarshal([]byte(entry.Stages), &result.Stages); err != nil {
+			return err
+		}
+
+		result.Log = textopdfa.CombineStageLogs(result.Stages)
+	}
+
+	if entry.Diagnostics != "" {
+		if err := json.Unmarshal([]byte(entry.Diagnostics), &result.Diagnostics); err != nil {
+			return err
+		}
+	}
+
+	if entry.Validation != "" {
+		if err := json.Unmarshal([]byte(entry.Validation), &result.Validation); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// cacheStore stores the result of a successful compilation under the given key and evicts old entries if the cache is too large
+func (srv *Server) cacheStore(key string, job_id string, result *textopdfa.Result, logger *slog.Logger) {
+
+	if key == "" {
+		return
+	}
+
+	srv.cacheMu.Lock()
+	defer srv.cacheMu.Unlock()
+
+	var count int64
+
+	if tx := srv.db.Model(&CacheEntries{}).Where("cache_key = ?", key).Count(&count); tx.Error != nil || count > 0 {
+		// an identical job was compiled at the same time
+		return
+	}
+
+	entry := CacheEntries{CacheKey: key, JobID: job_id, LastUsedAt: time.Now()}
+
+	size, err := copyFileSize(result.Path, srv.cachePath(key, cacheExtPDF))
+
+	if err == nil && result.TexLogPath != "" {
+		var logsize int64
+		logsize, err = copyFileSize(result.TexLogPath, srv.cachePath(key, cacheExtTexLog))
+		size += logsize
+		entry.TexLog = true
+	}
+
+	entry.Size = size
+
+	if err == nil {
+		err = marshalCacheEntry(&entry, result)
+	}
+
+	if err == nil {
+		err = srv.db.Create(&entry).Error
+	}
+
+	if err != nil {
+		logger.Error("Error storing result in cache [N8CW2RYJ]", "key", key, "err", err)
+		os.Remove(srv.cachePath(key, cacheExtPDF))
+		os.Remove(srv.cachePath(key, cacheExtTexLog))
+		return
+	}
+
+	logger.Debug("Stored result in cache", "key", key, "size", size)
+
+	srv.evictCache(logger)
+}
+
+// marshalCacheEntry stores the output of a compilation in a cache entry
+func marshalCacheEntry(entry *CacheEntries, result *textopdfa.Result) error {
+
+	stages, err := json.Marshal(result.Stages)
+
+	if err != nil {
+		return err
+	}
+
+	entry.Stages = string(stages)
+
+	if len(result.Diagnostics) > 0 {
+		diagnostics, err := json.Marshal(result.Diagnostics)
+
+		if err != nil {
+			return err
+		}
+
+		entry.Diagnostics = string(diagnostics)
+	}
+
+	if result.Validation != nil {
+		validation, err := json.Marshal(result.Validation)
+
+		if err != nil {
+			return err
+		}
+
+		entry.Validation = string(validation)
+	}
+
+	return nil
+}
+
+// evictCache removes the least recently used entries until the cache fits into ServerOptions.CacheMaxSize, the caller must hold srv.cacheMu
+func (srv *Server) evictCache(logger *slog.Logger) {
+
+	var total int64
+
+	if tx := srv.db.Model(&CacheEntries{}).Select("COALESCE(SUM(size), 0)").Scan(&total); tx.Error != nil {
+		logger.Error("Error computing cache size [1VKE6GTB]", "err", tx.Error)
+		return
+	}
+
+	if total <= srv.Options.CacheMaxSize {
+		return
+	}
+
+	var entries []CacheEntries
+
+	if tx := srv.db.Order("last_used_at").Find(&entries); tx.Error != nil {
+		logger.Error("Error loading cache entries [7XRM3AFP]", "err", tx.Error)
+		return
+	}
+
+	var evict []CacheEntries
+
+	for _, entry := range entries {
+		if total <= srv.Options.CacheMaxSize {
+			break
+		}
+
+		evict = append(evict, entry)
+		total -= entry.Size
+	}
+
+	logger.Info("Evicting least recently used cache entries", "entries", len(evict), "remaining", total)
+
+	srv.removeCacheEntries(evict, logger)
+}
+
+// removeCacheEntries removes cache entries and their files, it returns the number of bytes freed
+func (srv *Server) removeCacheEntries(entries []CacheEntries, logger *slog.Logger) int64 {
+
+	var freed int64
+
+	for _, entry := range entries {
+		if tx := srv.db.Unscoped().Delete(&CacheEntries{}, entry.ID); tx.Error != nil {
+			logger.Error("Error removing cache entry [F2TQ9BZL]", "key", entry.CacheKey, "err", tx.Error)
+			continue
+		}
+
+		for _, ext := range []string{cacheExtPDF, cacheExtTexLog} {
+			if err := os.Remove(srv.cachePath(entry.CacheKey, ext)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				logger.Warn("Could not remove cached file", "key", entry.CacheKey, "err", err)
+			}
+		}
+
+		freed += entry.Size
+	}
+
+	return freed
+}
+
+// copyFile copies the file src to dst, replacing dst
+func copyFile(src string, dst string) error {
+	_, err := copyFileSize(src, dst)
+	return err
+}
+
+// copyFileSize copies the file src to dst via a temporary file, so dst is complete or missing, and returns its size
+func copyFileSize(src string, dst string) (int64, error) {
+
+	in, err := os.Open(src)
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer in.Close()
+
+	out, err := os.CreateTemp(filepath.Dir(dst), filepath.Base(dst)+".tmp*")
+
+	if err != nil {
+		return 0, err
+	}
+
+	size, err := io.Copy(out, in)
+
+	if err == nil {
+		// CreateTemp creates the file readable by the owner only
+		err = out.Chmod(0644)
+	}
+
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+
+	if err == nil {
+		err = os.Rename(out.Name(), dst)
+	}
+
+	if err != nil {
+		os.Remove(out.Name())
+		return 0, err
+	}
+
+	return size, nil
+}
+
+// handleCache returns the size of the compile cache
+func (srv *Server) handleCache(w http.ResponseWriter, r *http.Request) {
+
+	logger := r.Context().Value("logger").(*slog.Logger)
+	logger = logger.With("func", "restserver.handleCache")
+
+	if !requireAdmin(w, r, logger) {
+		return
+	}
+
+	resp := ResponseCache{MaxSize: srv.Options.CacheMaxSize}
+
+	tx := srv.db.Model(&CacheEntries{}).
+		Select("COUNT(*), COALESCE(SUM(size), 0), COALESCE(SUM(hits), 0)").
+		Row().Scan(&resp.Entries, &resp.Size, &resp.Hits)
+
+	if tx != nil {
+		logger.Error("Error loading cache size", "err", tx)
+		_ = server.WriteError(w, http.StatusInternalServerError, "failed to load cache size [HD5YW0CE]", logger)
+		return
+	}
+
+	_ = server.WriteResponse(w, resp, logger)
+}
+
+// handlePurgeCache removes all entries of the compile cache, jobs that used them keep their copies
+func (srv *Server) handlePurgeCache(w http.ResponseWriter, r *http.Request) {
+
+	logger := r.Context().Value("logger").(*slog.Logger)
+	logger = logger.With("func", "restserver.handlePurgeCache")
+
+	if !requireAdmin(w, r, logger) {
+		return
+	}
+
+	srv.cacheMu.Lock()
+	defer srv.cacheMu.Unlock()
+
+	var entries []CacheEntries
+
+	if tx := srv.db.Find(&entries); tx.Error != nil {
+		_ = server.WriteError(w, http.StatusInternalServerError, "failed to load cache entries [LQ8B1VSG]", logger)
+		return
+	}
+
+	freed := srv.removeCacheEntries(entries, logger)
+
+	logger.Info("Purged cache", "entries", len(entries), "size", freed)
+
+	_ = server.WriteResponse(w, ResponseCachePurge{Entries: len(entries), Size: freed}, logger)
+}
@@ -0,0 +1,200 @@
+package restserver
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+
+	"github.com/tilseiffert/docker-tex-to-pdf/pkg/server"
+	"gorm.io/gorm"
+)
+
+var (
+	errJobCancelled  = errors.New("job cancelled by request")          // cause of the context of a job cancelled via the API
+	errJobNotRunning = errors.New("job is not running on this server") // the job is compiling, but in another process
+)
+
+type ResponseJobAction struct {
+	JobID   string `json:"job_id"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// untrackJob unregisters the cancel function of a job that is done, it is registered by claimNextJob
+func (srv *Server) untrackJob(job_id string) {
+
+	srv.runningMu.Lock()
+	delete(srv.running, job_id)
+	srv.runningMu.Unlock()
+}
+
+// cancelRunningJob cancels a job running in this process, it returns false if the job is not running here
+func (srv *Server) cancelRunningJob(job_id string, cause error) bool {
+
+	srv.runningMu.Lock()
+	cancel, ok := srv.running[job_id]
+	srv.runningMu.Unlock()
+
+	if ok {
+		cancel(cause)
+	}
+
+	return ok
+}
+
+// cancelJob cancels a queued job or asks a job running in this process to stop, cause becomes the error of the job
+// It returns JOBSTATUS_CANCELLED if the job was queued, JOBSTATUS_COMPILING if it is running and stops within seconds
+// and errJobNotRunning if it is compiling in another process
+func (srv *Server) cancelJob(job_id string, cause error, logger *slog.Logger) (string, error) {
+
+	// not picked up by a worker yet, cancel it unless a worker claims it in the meantime
+	tx := srv.db.Model(&Jobs{}).Where("job_id = ? AND status = ?", job_id, JOBSTATUS_CREATED).
+		Updates(map[string]interface{}{
+			"status":         JOBSTATUS_CANCELLED,
+			"status_running": false,
+			"status_success": false,
+			"error":          cause.Error(),
+		})
+
+	if tx.Error != nil {
+		return "", tx.Error
+	}
+
+	if tx.RowsAffected == 1 {
+		srv.publish(JobEvent{JobID: job_id, Status: JOBSTATUS_CANCELLED, Err: cause}, logger)
+		return JOBSTATUS_CANCELLED, nil
+	}
+
+	// claimed by a worker, cancel the running job
+	if !srv.cancelRunningJob(job_id, cause) {
+		return "", errJobNotRunning
+	}
+
+	return JOBSTATUS_COMPILING, nil
+}
+
+// findJob loads a job by the id given in the path and writes an error response if it cannot be found or belongs to another caller
+func (srv *Server) findJob(w http.ResponseWriter, r *http.Request, logger *slog.Logger) (*Jobs, bool) {
+
+	job_id := r.PathValue("id")
+
+	if job_id == "" {
+		_ = server.WriteError(w, http.StatusBadRequest, "job_id is empty [5MZC0QWA]", logger)
+		return nil, false
+	}
+
+	var job Jobs
+	tx := srv.jobs(r.Context()).First(&job, "job_id = ?", job_id)
+
+	if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+		_ = server.WriteError(w, http.StatusNotFound, "job not found [VQ3N8HTE]", logger)
+		return nil, false
+	}
+
+	if tx.Error != nil {
+		_ = server.WriteError(w, http.StatusInternalServerError, "failed to load job [L0S7BXUJ]", logger)
+		return nil, false
+	}
+
+	return &job, true
+}
+
+func (srv *Server) handleJobCancel(w http.ResponseWriter, r *http.Request) {
+
+	logger := r.Context().Value("logger").(*slog.Logger)
+	logger = logger.With("func", "restserver.handleJobCancel")
+
+	job, ok := srv.findJob(w, r, logger)
+
+	if !ok {
+		return
+	}
+
+	logger = logger.With("job", job.JobID)
+	logger.Debug("Got request to cancel job", "status", job.Status)
+
+	switch job.Status {
+	case JOBSTATUS_CREATED, JOBSTATUS_COMPILING:
+		status, err := srv.cancelJob(job.JobID, errJobCancelled, logger)
+
+		switch {
+		case errors.Is(err, errJobNotRunning):
+			_ = server.WriteError(w, http.StatusConflict, "job is not running on this server [U4EJ7PXK]", logger)
+		case err != nil:
+			_ = server.WriteError(w, http.StatusInternalServerError, "failed to cancel job [C1V8RZ0N]", logger)
+		case status == JOBSTATUS_CANCELLED:
+			_ = server.WriteResponse(w, ResponseJobAction{JobID: job.JobID, Status: JOBSTATUS_CANCELLED, Message: "Job cancelled"}, logger)
+		default:
+			resp := ResponseJobAction{JobID: job.JobID, Status: JOBSTATUS_COMPILING, Message: "Cancellation requested, the job stops within seconds"}
+			_ = server.WriteResponseStatus(w, http.StatusAccepted, resp, logger)
+		}
+
+	default:
+		_ = server.WriteError(w, http.StatusConflict, "job cannot be cancelled in status '"+job.Status+"' [HF9D2WQS]", logger)
+	}
+}
+
+func (srv *Server) handleJobDelete(w http.ResponseWriter, r *http.Request) {
+
+	logger := r.Context().Value("logger").(*slog.Logger)
+	logger = logger.With("func", "restserver.handleJobDelete")
+
+	job, ok := srv.findJob(w, r, logger)
+
+	if !ok {
+		return
+	}
+
+	logger = logger.With("job", job.JobID)
+	logger.Debug("Got request to delete job", "status", job.Status)
+
+	// a running job would write into the deleted directory, it has to be cancelled first
+	// the row is deleted, unlike the soft delete of the janitor (see cleanupJob)
+	tx := srv.db.Unscoped().Where("job_id = ? AND status <> ?", job.JobID, JOBSTATUS_COMPILING).Delete(&Jobs{})
+
+	if tx.Error != nil {
+		_ = server.WriteError(w, http.StatusInternalServerError, "failed to delete job [7KXT3MDA]", logger)
+		return
+	}
+
+	if tx.RowsAffected == 0 {
+		_ = server.WriteError(w, http.StatusConflict, "job is compiling, cancel it first [P2GQ6YVN]", logger)
+		return
+	}
+
+	if err := srv.removeJobFiles(job, logger); err != nil {
+		_ = server.WriteError(w, http.StatusInternalServerError, "job deleted, but failed to remove its directory [D5LV2QNE]", logger)
+		return
+	}
+
+	logger.Info("Deleted job", "path", job.Path)
+
+	_ = server.WriteResponse(w, ResponseJobAction{JobID: job.JobID, Status: job.Status, Message: "Job deleted"}, logger)
+}
+
+// removeJobFiles removes the logs, the webhook deliveries and the directory of a job whose row has been deleted
+// The logs and deliveries are deleted permanently, the deliveries hold the callback secret and pending ones would be retried
+func (srv *Server) removeJobFiles(job *Jobs, logger *slog.Logger) error {
+
+	if tx := srv.db.Unscoped().Where("job_id = ?", job.JobID).Delete(&JobLogs{}); tx.Error != nil {
+		logger.Error("Failed to delete job logs [E6WQ1TZB]", "job", job.JobID, "err", tx.Error)
+	}
+
+	deliveries := srv.db.Unscoped().Model(&WebhookDeliveries{}).Select("id").Where("job_id = ?", job.JobID)
+
+	if tx := srv.db.Unscoped().Where("delivery_id IN (?)", deliveries).Delete(&WebhookAttempts{}); tx.Error != nil {
+		logger.Error("Failed to delete webhook attempts [W3PF7RMA]", "job", job.JobID, "err", tx.Error)
+	}
+
+	if tx := srv.db.Unscoped().Where("job_id = ?", job.JobID).Delete(&WebhookDeliveries{}); tx.Error != nil {
+		logger.Error("Failed to delete webhook deliveries [Q6KC9XHT]", "job", job.JobID, "err", tx.Error)
+	}
+
+	if err := os.RemoveAll(job.Path); err != nil {
+		logger.Error("Failed to remove job directory [N8JR4CUB]", "job", job.JobID, "path", job.Path, "err", err)
+		return err
+	}
+
+	return nil
+}
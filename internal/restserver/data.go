@@ -1,19 +1,70 @@
 package restserver
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"github.com/tilseiffert/docker-tex-to-pdf/internal/textopdfa"
+	"gorm.io/gorm"
+)
 
 type Jobs struct {
 	gorm.Model
-	JobID         string `json:"ulid"` // ULID, index
+	JobID         string `json:"ulid" gorm:"index"` // ULID, index
 	Name          string `json:"name"`
-	Status        string `json:"status"`
+	Status        string `json:"status" gorm:"index"`
 	StatusRunning bool   `json:"status_running"`
 	StatusSuccess bool   `json:"status_success"`
-	Error         string `json:"error"`  // any error message
-	Path          string `json:"path"`   // absolute path to the build dir
-	Result        string `json:"result"` // absolute path to the resulting PDF/A file
+	Error         string `json:"error"`              // any error message
+	ErrorStage    string `json:"error_stage"`        // stage of the pipeline in which the error occurred (see textopdfa.STAGE_*)
+	Path          string `json:"path"`               // absolute path to the build dir
+	Result        string `json:"result"`             // absolute path to the resulting PDF/A file
+	MainFile      string `json:"main_file"`          // path of the main TeX file relative to Path, empty means BUILDDIR_TEXFILE
+	Options       string `json:"options"`            // compile options as JSON (see textopdfa.CompileOptions), empty means defaults
+	TexLog        string `json:"tex_log"`            // absolute path to the LaTeX .log file, empty if there is none
+	Diagnostics   string `json:"diagnostics"`        // diagnostics as JSON (see textopdfa.Diagnostic), empty if there are none
+	Validation    string `json:"validation"`         // validation report as JSON (see textopdfa.ValidationReport), empty if not validated
+	Compliant     *bool  `json:"compliant"`          // result of the validation, nil if not validated
+	Cached        bool   `json:"cached"`             // the result was taken from the compile cache of an identical job
+	Owner         string `json:"owner" gorm:"index"` // id of the API key that created the job (see APIKeys), empty if created without authentication
+
+	CallbackURL    string `json:"callback_url"` // URL notified when the job is done (see WebhookDeliveries), empty for none
+	CallbackSecret string `json:"-"`            // key of the HMAC-SHA256 signature of the callbacks, empty for unsigned callbacks
+}
+
+// JobLogs holds the captured output of a single command run for a job
+type JobLogs struct {
+	gorm.Model
+	JobID              string `json:"ulid" gorm:"index"`
+	Seq                int    `json:"seq"` // position of the command in the pipeline
+	textopdfa.StageLog `gorm:"embedded"`
+}
+
+// WebhookDeliveries holds a webhook callback of a job and the outcome of its last delivery attempt, all attempts are kept in WebhookAttempts
+type WebhookDeliveries struct {
+	gorm.Model
+	JobID          string     `json:"job_id" gorm:"index"`
+	URL            string     `json:"url"`
+	Secret         string     `json:"-"`                  // key of the signature, copied from the job
+	Status         string     `json:"status"`             // status of the job reported by the callback
+	Payload        string     `json:"payload"`            // body of the callback (see WebhookPayload)
+	State          string     `json:"state" gorm:"index"` // one of the WEBHOOK_* states
+	Attempts       int        `json:"attempts"`           // attempts since the delivery was created or replayed
+	NextAttemptAt  *time.Time `json:"next_attempt_at"`    // when to try (again), nil if not pending
+	LastAttemptAt  *time.Time `json:"last_attempt_at"`
+	LastStatusCode int        `json:"last_status_code"` // HTTP status code of the last attempt, 0 if there was no response
+	LastError      string     `json:"last_error"`       // why the last attempt failed, empty if it succeeded
+}
+
+// WebhookAttempts holds a single attempt to deliver a webhook callback, it was made at CreatedAt
+type WebhookAttempts struct {
+	gorm.Model
+	DeliveryID uint   `json:"delivery_id" gorm:"index"`
+	Attempt    int    `json:"attempt"`     // number of the attempt, counted across replays of the delivery
+	StatusCode int    `json:"status_code"` // HTTP status code of the response, 0 if there was none
+	Error      string `json:"error"`       // why the attempt failed, empty if it succeeded
+	DurationMs int64  `json:"duration_ms"` // time until the response or the error
 }
 
 func AutoMigrate(db *gorm.DB) error {
-	return db.AutoMigrate(&Jobs{})
+	return db.AutoMigrate(&Jobs{}, &JobLogs{}, &WebhookDeliveries{}, &WebhookAttempts{}, &CacheEntries{}, &APIKeys{})
 }
@@ -0,0 +1,67 @@
+package restserver
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+const (
+	DB_DRIVER_SQLITE   = "sqlite"
+	DB_DRIVER_POSTGRES = "postgres"
+	DEFAULT_DB_DRIVER  = DB_DRIVER_SQLITE
+	DEFAULT_DB_DSN     = "file::memory:?cache=shared" // jobs are lost on restart, use a file path (e.g. "tex-to-pdfa.db") to keep them
+
+	// SQLITE_BUSY_TIMEOUT_MS is the time SQLite waits for a lock held by another connection (e.g. a worker updating a job)
+	SQLITE_BUSY_TIMEOUT_MS = 5000
+)
+
+// OpenDB opens the database of the jobs
+// driver is DB_DRIVER_SQLITE (dsn is a file path or SQLite URI) or DB_DRIVER_POSTGRES (dsn is a Postgres DSN or URL)
+func OpenDB(driver string, dsn string, config *gorm.Config) (*gorm.DB, error) {
+
+	if config == nil {
+		config = &gorm.Config{}
+	}
+
+	if dsn == "" && driver != DB_DRIVER_POSTGRES {
+		dsn = DEFAULT_DB_DSN
+	}
+
+	switch driver {
+	case DB_DRIVER_SQLITE, "":
+		// concurrent workers would otherwise fail with SQLITE_BUSY on a file-backed database
+		if !strings.Contains(dsn, "busy_timeout") {
+			delim := "?"
+
+			if strings.Contains(dsn, "?") {
+				delim = "&"
+			}
+
+			dsn += fmt.Sprintf("%s_pragma=busy_timeout(%d)", delim, SQLITE_BUSY_TIMEOUT_MS)
+		}
+
+		// SQLite stores times as text with their offset and compares them as strings, so created_at and updated_at are
+		// stored in UTC: queries comparing them pass UTC times as well (see parseTime and cleanup)
+		if config.NowFunc == nil {
+			config.NowFunc = func() time.Time {
+				return time.Now().UTC()
+			}
+		}
+
+		return gorm.Open(sqlite.Open(dsn), config)
+
+	case DB_DRIVER_POSTGRES:
+		if dsn == "" {
+			return nil, fmt.Errorf("postgres requires a DSN (e.g. \"host=localhost user=tex dbname=tex\")")
+		}
+
+		return gorm.Open(postgres.Open(dsn), config)
+	}
+
+	return nil, fmt.Errorf("unknown database driver '%s', expected %s or %s", driver, DB_DRIVER_SQLITE, DB_DRIVER_POSTGRES)
+}
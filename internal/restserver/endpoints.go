@@ -3,15 +3,20 @@ package restserver
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/rs/zerolog"
 	"github.com/tilseiffert/docker-tex-to-pdf/internal/contextkeys"
 	"github.com/tilseiffert/docker-tex-to-pdf/internal/textopdfa"
+	"github.com/tilseiffert/docker-tex-to-pdf/internal/workspace"
 	"github.com/tilseiffert/docker-tex-to-pdf/pkg/server"
 )
 
@@ -24,15 +29,22 @@ const (
 	JOBSTATUS_COMPILING     = "1 - compiling"
 	JOBSTATUS_FINISHED      = "2 - finished"
 	JOBSTATUS_ERROR         = "X - error"
-)
-
-var (
-	jobdir = DEFAULT_JOBDIR
+	JOBSTATUS_TIMEOUT       = "T - timeout"
+	JOBSTATUS_CANCELLED     = "C - cancelled"
+	JOBSTATUS_INTERRUPTED   = "I - interrupted" // stopped by a shutdown or crash of the server, see Shutdown and Recover
 )
 
 type RequestCreateJob struct {
-	Name       string `json:"name"`
-	TexContent string `json:"tex_content"`
+	Name       string                    `json:"name"`
+	TexContent string                    `json:"tex_content"` // content of the main file, optional if it is part of files or archive
+	MainFile   string                    `json:"main_file"`   // optional, path of the main TeX file in the job directory, defaults to BUILDDIR_TEXFILE
+	Files      []workspace.File          `json:"files"`       // optional, additional files like images, .cls, .lco or .bib files
+	Archive    []byte                    `json:"archive"`     // optional, zip or tar.gz archive with additional files (base64 encoded in JSON)
+	Options    *textopdfa.CompileOptions `json:"options"`     // optional, defaults see textopdfa.DefaultCompileOptions
+	Metadata   *textopdfa.Metadata       `json:"metadata"`    // optional, title, author etc. of the PDF/A file, overrides options.metadata
+
+	CallbackURL    string `json:"callback_url"`    // optional, URL receiving a POST when the job is done (see WebhookPayload)
+	CallbackSecret string `json:"callback_secret"` // optional, key of the HMAC-SHA256 signature of the callbacks (see HEADER_WEBHOOK_SIGNATURE)
 }
 
 type ResponseCreateJob struct {
@@ -41,14 +53,44 @@ type ResponseCreateJob struct {
 }
 
 type ResponseJobStatus struct {
-	JobID   string `json:"job_id"`
-	Status  string `json:"status"`
-	Running bool   `json:"running"`
-	Success bool   `json:"success"`
-	Error   string `json:"error"`
+	JobID      string    `json:"job_id"`
+	Name       string    `json:"name"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	Status     string    `json:"status"`
+	Running    bool      `json:"running"`
+	Success    bool      `json:"success"`
+	Error      string    `json:"error"`
+	ErrorStage string    `json:"error_stage,omitempty"`
+	Compliant  *bool     `json:"compliant,omitempty"` // result of the PDF/A validation, missing if the job was not validated
+	Cached     bool      `json:"cached,omitempty"`    // the result was taken from the compile cache
+
+	// errors and warnings reported by TeX, only included in the status of a single job
+	Diagnostics []textopdfa.Diagnostic `json:"diagnostics,omitempty"`
+}
+
+// newResponseJobStatus returns the status of a job as sent to clients
+func newResponseJobStatus(job *Jobs) ResponseJobStatus {
+
+	return ResponseJobStatus{
+		JobID:      job.JobID,
+		Name:       job.Name,
+		CreatedAt:  job.CreatedAt,
+		UpdatedAt:  job.UpdatedAt,
+		Status:     job.Status,
+		Running:    job.StatusRunning,
+		Success:    job.StatusSuccess,
+		Error:      job.Error,
+		ErrorStage: job.ErrorStage,
+		Compliant:  job.Compliant,
+		Cached:     job.Cached,
+	}
 }
 
-func (srv *Server) runJob(ctx context.Context, job_id string, texfile_path string) {
+// runJob compiles a job that has been claimed by a worker (status JOBSTATUS_COMPILING) and stores the outcome in the db
+func (srv *Server) runJob(ctx context.Context, job *Jobs) {
+
+	job_id := job.JobID
 
 	logger := ctx.Value("logger").(*slog.Logger)
 	logger = logger.With("func", "restserver.runJob", "job", job_id)
@@ -59,49 +101,205 @@ func (srv *Server) runJob(ctx context.Context, job_id string, texfile_path strin
 	zerologLogger = zerologLogger.With().Str("job", job_id).Str("logger", "job-zerolog").Logger()
 	ctx = context.WithValue(ctx, contextkeys.LoggerKey, zerologLogger)
 
-	// update db
+	// subscribers follow the commands and their output (see CompileJob)
+	ctx = textopdfa.WithProgress(ctx, func(progress textopdfa.ProgressEvent) {
+		srv.publish(JobEvent{JobID: job_id, Status: JOBSTATUS_COMPILING, Progress: &progress}, logger)
+	})
+
 	logger.Debug("Compiling TeX to PDF/A")
-	tx := srv.db.Model(&Jobs{}).Where("job_id = ?", job_id).Update("status", JOBSTATUS_COMPILING)
 
-	if tx.Error != nil {
-		logger.Error("Error updating job status, aborting [A9VXQIFU]", "err", tx.Error)
-		return
+	var opts *textopdfa.CompileOptions
+
+	if job.Options != "" {
+		if err := json.Unmarshal([]byte(job.Options), &opts); err != nil {
+			logger.Error("Error parsing job options [B6NW0TZ3]", "err", err)
+			srv.failJob(job_id, JOBSTATUS_ERROR, fmt.Errorf("invalid job options: %w", err), textopdfa.STAGE_PREPARE, logger)
+			return
+		}
+
+		opts.ICCProfileDir = srv.Options.ICCProfileDir
+	}
+
+	mainfile := job.MainFile
+
+	if mainfile == "" {
+		mainfile = BUILDDIR_TEXFILE
+	}
+
+	texfile_path := job.Path + "/" + mainfile
+
+	// identical jobs compiled before are served from the cache (see ServerOptions.CacheMaxSize)
+	cachekey := srv.cacheKey(ctx, job, opts, logger)
+	result, cached := srv.cacheLookup(cachekey, texfile_path, logger)
+
+	var err error
+
+	if !cached {
+		builddir_template := srv.Options.BUILDDIR_PREFIX + BUILDDIR_DELIM + job_id + BUILDDIR_DELIM
+		result, err = textopdfa.CompileTexToPDFA(ctx, texfile_path, builddir_template+BUILDDIR_PREFIX_COMPILE, opts)
 	}
 
-	builddir_template := srv.Options.BUILDDIR_PREFIX + BUILDDIR_DELIM + job_id + BUILDDIR_DELIM
-	result, err := textopdfa.CompileTexToPDFA(ctx, texfile_path, builddir_template+BUILDDIR_PREFIX_COMPILE)
+	if result != nil {
+		srv.saveJobDetails(job_id, result, logger)
+	}
 
 	if err != nil {
 		logger.Error("Error compiling TeX to PDF/A [ITGMFXSI]", "err", err)
 
-		// update db
-		tx := srv.db.Model(&Jobs{}).Where("job_id = ?", job_id).
-			Update("status", JOBSTATUS_ERROR).
-			Update("status_running", false).
-			Update("status_success", false).
-			Update("error", err.Error())
+		stage := textopdfa.STAGE_PREPARE
+		var stageErr *textopdfa.StageError
 
-		if tx.Error != nil {
-			logger.Error("Error updating job status [JO79QRDU]", "err", tx.Error)
+		if errors.As(err, &stageErr) {
+			stage = stageErr.Stage
 		}
 
+		status := JOBSTATUS_ERROR
+
+		if errors.Is(err, textopdfa.ErrTimeout) {
+			status = JOBSTATUS_TIMEOUT
+		}
+
+		if errors.Is(err, textopdfa.ErrCancelled) {
+			status = JOBSTATUS_CANCELLED
+			err = context.Cause(ctx)
+
+			if errors.Is(err, errShutdown) {
+				status = JOBSTATUS_INTERRUPTED
+			}
+		}
+
+		srv.failJob(job_id, status, err, stage, logger)
+
 		return
 	}
 
-	logger.Info("Successfully compiled TeX to PDF/A", "path", result)
-	tx = srv.db.Model(&Jobs{}).Where("job_id = ?", job_id).
-		Update("result", result).
+	logger.Info("Successfully compiled TeX to PDF/A", "path", result.Path, "cached", cached)
+
+	if !cached {
+		srv.cacheStore(cachekey, job_id, result, logger)
+	}
+
+	tx := srv.db.Model(&Jobs{}).Where("job_id = ?", job_id).
+		Update("result", result.Path).
 		Update("status", JOBSTATUS_FINISHED).
 		Update("status_running", false).
-		Update("status_success", true)
+		Update("status_success", true).
+		Update("cached", cached)
 
 	if tx.Error != nil {
 		logger.Error("Error updating job status [EW8QVQF0]", "err", tx.Error)
 	}
 
+	srv.publish(JobEvent{JobID: job_id, Status: JOBSTATUS_FINISHED}, logger)
+
 	logger.Debug("Bye")
 }
 
+// saveJobDetails stores the output of all commands run for a job, the path to its LaTeX log, its diagnostics and validation result
+func (srv *Server) saveJobDetails(job_id string, result *textopdfa.Result, logger *slog.Logger) {
+
+	logs := make([]JobLogs, 0, len(result.Stages))
+
+	for i, stage := range result.Stages {
+		logs = append(logs, JobLogs{JobID: job_id, Seq: i, StageLog: stage})
+	}
+
+	if len(logs) > 0 {
+		if tx := srv.db.Create(&logs); tx.Error != nil {
+			logger.Error("Error saving job logs [3QHZ8VYC]", "err", tx.Error)
+		}
+	}
+
+	details := map[string]interface{}{
+		"tex_log":     result.TexLogPath,
+		"diagnostics": "",
+	}
+
+	if len(result.Diagnostics) > 0 {
+		if diagnostics, err := json.Marshal(result.Diagnostics); err == nil {
+			details["diagnostics"] = string(diagnostics)
+		}
+	}
+
+	if result.Validation != nil {
+		if validation, err := json.Marshal(result.Validation); err == nil {
+			details["validation"] = string(validation)
+			details["compliant"] = result.Validation.Compliant
+		}
+	}
+
+	tx := srv.db.Model(&Jobs{}).Where("job_id = ?", job_id).Updates(details)
+
+	if tx.Error != nil {
+		logger.Error("Error saving job details [M7EK0RNW]", "err", tx.Error)
+	}
+}
+
+// failJob marks a job as failed with the given status (e.g. JOBSTATUS_ERROR) and error
+func (srv *Server) failJob(job_id string, status string, err error, stage string, logger *slog.Logger) {
+
+	tx := srv.db.Model(&Jobs{}).Where("job_id = ?", job_id).
+		Update("status", status).
+		Update("status_running", false).
+		Update("status_success", false).
+		Update("error", err.Error()).
+		Update("error_stage", stage)
+
+	if tx.Error != nil {
+		logger.Error("Error updating job status [JO79QRDU]", "err", tx.Error)
+	}
+
+	srv.publish(JobEvent{JobID: job_id, Status: status, Err: err}, logger)
+}
+
+// writeJobFiles writes the archive, the files and the tex content of a request into the job directory
+// On failure, it returns the HTTP status code to report along with the error
+func writeJobFiles(builddir string, req *RequestCreateJob) (int, error) {
+
+	ws := workspace.New(builddir)
+
+	if _, err := ws.Path(req.MainFile); err != nil {
+		return http.StatusBadRequest, fmt.Errorf("invalid main_file: %w", err)
+	}
+
+	err := func() error {
+		if len(req.Archive) > 0 {
+			if err := ws.ExtractArchive(req.Archive); err != nil {
+				return err
+			}
+		}
+
+		if err := ws.WriteFiles(req.Files); err != nil {
+			return err
+		}
+
+		if req.TexContent == "" {
+			return nil
+		}
+
+		if ws.Exists(req.MainFile) {
+			return fmt.Errorf("%w: main file '%s' is given as file and as tex_content", errRequestBody, req.MainFile)
+		}
+
+		return ws.Write(req.MainFile, strings.NewReader(req.TexContent))
+	}()
+
+	switch {
+	case errors.Is(err, workspace.ErrTooLarge):
+		return http.StatusRequestEntityTooLarge, err
+	case errors.Is(err, workspace.ErrInvalidPath), errors.Is(err, workspace.ErrUnsupportedArchive), errors.Is(err, errRequestBody):
+		return http.StatusBadRequest, err
+	case err != nil:
+		return http.StatusInternalServerError, err
+	}
+
+	if !ws.Exists(req.MainFile) {
+		return http.StatusBadRequest, fmt.Errorf("main file '%s' not found in files or archive", req.MainFile)
+	}
+
+	return 0, nil
+}
+
 func (srv *Server) handleCreateJob(w http.ResponseWriter, r *http.Request) {
 	// var err error
 
@@ -112,103 +310,156 @@ func (srv *Server) handleCreateJob(w http.ResponseWriter, r *http.Request) {
 
 	// ===== Parse request body =====
 
-	var req RequestCreateJob
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	req, err := parseCreateJobRequest(w, r)
 
-		if err.Error() == "EOF" {
-			_ = server.WriteError(w, http.StatusBadRequest, "Request body is empty [6W3VLJ97]", logger)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
 
+		if errors.As(err, &maxBytesErr) {
+			_ = server.WriteError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body exceeds %d bytes [W2KD9Q5M]", maxBytesErr.Limit), logger)
 			return
 		}
 
-		_ = server.WriteError(w, http.StatusBadRequest, err.Error()+" [ZPA89CTE]", logger)
+		if errors.Is(err, errRequestBody) {
+			_ = server.WriteError(w, http.StatusBadRequest, err.Error()+" [ZPA89CTE]", logger)
+			return
+		}
 
+		_ = server.WriteError(w, http.StatusInternalServerError, err.Error()+" [6W3VLJ97]", logger)
 		return
 	}
 
-	// ===== Validate request =====
+	// ===== Create job =====
 
-	if req.Name == "" {
-		_ = server.WriteError(w, http.StatusBadRequest, "name is empty [HY85SV7R]", logger)
+	job_id, err := srv.NewULID()
+
+	if err != nil {
+		_ = server.WriteError(w, http.StatusInternalServerError, "failed to generate job ID [J7WL1VI4]", logger)
 		return
 	}
 
-	if req.TexContent == "" {
-		_ = server.WriteError(w, http.StatusBadRequest, "tex_content is empty [RYA39AGA]", logger)
+	builddir, code, err := srv.createJob(r.Context(), job_id.String(), req, logger)
+
+	if err != nil {
+		_ = server.WriteError(w, code, err.Error(), logger)
 		return
 	}
 
-	// ===== Prepare job =====
+	// ===== Write response =====
 
-	job_id, err := srv.NewULID()
+	resp := ResponseCreateJob{
+		JobID:   job_id.String(),
+		Message: "Job queued with builddir: " + builddir,
+	}
 
-	if err != nil {
-		_ = server.WriteError(w, http.StatusInternalServerError, "failed to generate job ID [J7WL1VI4]", logger)
-		return
+	_ = server.WriteResponseStatus(w, http.StatusAccepted, resp, logger)
+
+	// _ = server.WriteError(w, http.StatusNotImplemented, "", logger)
+}
+
+// createJob validates a request, writes its files into a new job directory and queues the job
+// The job belongs to the caller in ctx (see jobOwner), it returns the job directory, or the HTTP status code to report along with the error
+func (srv *Server) createJob(ctx context.Context, job_id string, req *RequestCreateJob, logger *slog.Logger) (string, int, error) {
+
+	// ===== Validate request =====
+
+	if req.Name == "" {
+		return "", http.StatusBadRequest, errors.New("name is empty [HY85SV7R]")
 	}
 
-	logger = logger.With("job", job_id.String())
+	if req.TexContent == "" && len(req.Files) == 0 && len(req.Archive) == 0 {
+		return "", http.StatusBadRequest, errors.New("tex_content, files and archive are empty [RYA39AGA]")
+	}
 
-	// create job directory
-	builddir := jobdir + "/" + job_id.String()
-	err = os.MkdirAll(builddir, 0755)
+	if req.MainFile == "" {
+		req.MainFile = BUILDDIR_TEXFILE
+	}
 
-	// builddir_template := srv.Options.BUILDDIR_PREFIX + BUILDDIR_DELIM + job_id.String() + BUILDDIR_DELIM
-	// builddir, err := os.MkdirTemp("", builddir_template)
-	// // defer os.RemoveAll(builddir)
+	if req.Metadata != nil {
+		if req.Options == nil {
+			req.Options = &textopdfa.CompileOptions{}
+		}
 
-	if err != nil {
-		_ = server.WriteError(w, http.StatusInternalServerError, "failed to create build directory [J7WL1VI4]", logger)
-		return
+		req.Options.Metadata = req.Metadata
 	}
 
-	logger.Debug("Created build directory", "builddir", builddir)
+	if req.Options != nil {
+		req.Options.ICCProfileDir = srv.Options.ICCProfileDir
+	}
+
+	if err := req.Options.Validate(); err != nil {
+		return "", http.StatusBadRequest, fmt.Errorf("invalid options [Q4XN2BLE]: %w", err)
+	}
 
-	// write tex content to file
-	texfile_path := builddir + "/" + BUILDDIR_TEXFILE
+	if err := srv.validateCallback(ctx, req); err != nil {
+		return "", http.StatusBadRequest, fmt.Errorf("%w [B6RJ0TWE]", err)
+	}
 
-	err = os.WriteFile(texfile_path, []byte(req.TexContent), 0644)
+	// ===== Prepare job =====
 
-	if err != nil {
-		_ = server.WriteError(w, http.StatusInternalServerError, "failed to write tex content to file [RICMARTU]", logger)
-		return
+	logger = logger.With("job", job_id)
+
+	// create job directory
+	builddir := filepath.Join(srv.jobdir, job_id)
+
+	if err := os.MkdirAll(builddir, 0755); err != nil {
+		return "", http.StatusInternalServerError, errors.New("failed to create build directory [J7WL1VI4]")
 	}
 
-	logger.Debug("Wrote tex content to file", "texfile_path", texfile_path)
+	logger.Debug("Created build directory", "builddir", builddir)
+
+	// write files to job directory
+	if code, err := writeJobFiles(builddir, req); err != nil {
+		os.RemoveAll(builddir)
+		return "", code, fmt.Errorf("%w [RICMARTU]", err)
+	}
+
+	logger.Debug("Wrote files to build directory", "main_file", req.MainFile, "files", len(req.Files), "archive", len(req.Archive) > 0)
+
+	// add to db, this queues the job
+
+	options := ""
 
-	// add to db
+	if req.Options != nil {
+		data, err := json.Marshal(req.Options)
+
+		if err != nil {
+			os.RemoveAll(builddir)
+			return "", http.StatusInternalServerError, errors.New("failed to encode options [T0ZC6KXA]")
+		}
+
+		options = string(data)
+	}
 
 	tx := srv.db.Create(&Jobs{
-		JobID:         job_id.String(),
+		JobID:         job_id,
 		Name:          req.Name,
 		Status:        JOBSTATUS_CREATED,
 		StatusRunning: true,
 		StatusSuccess: false,
 		Path:          builddir,
+		MainFile:      req.MainFile,
+		Options:       options,
+
+		Owner:          jobOwner(ctx),
+		CallbackURL:    req.CallbackURL,
+		CallbackSecret: req.CallbackSecret,
 	})
 
 	if tx.Error != nil {
-		_ = server.WriteError(w, http.StatusInternalServerError, "failed to write job to db [PBYSS5CV]", logger)
-		return
+		os.RemoveAll(builddir)
+		return "", http.StatusInternalServerError, errors.New("failed to write job to db [PBYSS5CV]")
 	}
 
 	logger.Debug("Added job to db")
 
-	// === Compile TeX to PDF/A ===
-
-	logger.Debug("Compiling TeX to PDF/A")
-	srv.runJob(r.Context(), job_id.String(), texfile_path)
-
-	// ===== Write response =====
+	srv.publish(JobEvent{JobID: job_id, Status: JOBSTATUS_CREATED}, logger)
 
-	resp := ResponseCreateJob{
-		JobID:   job_id.String(),
-		Message: "Job created with builddir: " + builddir,
-	}
+	// === Queue job ===
 
-	_ = server.WriteResponse(w, resp, logger)
+	srv.notifyWorkers()
 
-	// _ = server.WriteError(w, http.StatusNotImplemented, "", logger)
+	return builddir, 0, nil
 }
 
 func (srv *Server) handleJobStatus(w http.ResponseWriter, r *http.Request) {
@@ -231,7 +482,7 @@ func (srv *Server) handleJobStatus(w http.ResponseWriter, r *http.Request) {
 	// ===== Get job status =====
 
 	var job Jobs
-	tx := srv.db.First(&job, "job_id = ?", job_id)
+	tx := srv.jobs(r.Context()).First(&job, "job_id = ?", job_id)
 
 	if tx.Error != nil {
 		_ = server.WriteError(w, http.StatusNotFound, "job not found [79E9DQFC]", logger)
@@ -240,12 +491,12 @@ func (srv *Server) handleJobStatus(w http.ResponseWriter, r *http.Request) {
 
 	// ===== Write response =====
 
-	resp := ResponseJobStatus{
-		JobID:   job.JobID,
-		Status:  job.Status,
-		Running: job.StatusRunning,
-		Success: job.StatusSuccess,
-		Error:   job.Error,
+	resp := newResponseJobStatus(&job)
+
+	if job.Diagnostics != "" {
+		if err := json.Unmarshal([]byte(job.Diagnostics), &resp.Diagnostics); err != nil {
+			logger.Error("Error parsing job diagnostics [R4DX9KWN]", "err", err)
+		}
 	}
 
 	_ = server.WriteResponse(w, resp, logger)
@@ -274,7 +525,7 @@ func (srv *Server) handleJobGetResult(w http.ResponseWriter, r *http.Request) {
 	// ===== Get job status =====
 
 	var job Jobs
-	tx := srv.db.First(&job, "job_id = ?", job_id)
+	tx := srv.jobs(r.Context()).First(&job, "job_id = ?", job_id)
 
 	if tx.Error != nil {
 		_ = server.WriteError(w, http.StatusNotFound, "job not found [I2JH1HX5]", logger)
@@ -0,0 +1,134 @@
+package restserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/tilseiffert/docker-tex-to-pdf/internal/textopdfa"
+)
+
+// errCallerGone is the cause of the context of a job whose caller stopped waiting for it (see CompileJob)
+var errCallerGone = errors.New("job cancelled, the caller stopped waiting for it")
+
+// CompileJob queues a job like POST /api/v1/createJob and waits until it is done, so other frontends (e.g. gRPC) share the job store and workers
+// The job is visible via the REST API under the returned id as soon as it is queued
+// onEvent (optional) is called with the events of the job, starting with its creation (JOBSTATUS_CREATED), events may be dropped if it blocks
+// If ctx is done before, the job is cancelled and ctx.Err() is returned. The context must carry the logger (key "logger")
+// The result holds the output of the compilation if it got that far, errors wrap the textopdfa errors (e.g. textopdfa.ErrInvalidInput)
+func (srv *Server) CompileJob(ctx context.Context, req *RequestCreateJob, onEvent func(JobEvent)) (string, *textopdfa.Result, error) {
+
+	logger := ctx.Value("logger").(*slog.Logger)
+	logger = logger.With("func", "restserver.CompileJob")
+
+	id, err := srv.NewULID()
+
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to generate job ID: %w", err)
+	}
+
+	job_id := id.String()
+	logger = logger.With("job", job_id)
+
+	// subscribe before queueing, so the final event cannot be missed
+	events, unsubscribe := srv.events.subscribe(job_id)
+	defer unsubscribe()
+
+	if _, code, err := srv.createJob(ctx, job_id, req, logger); err != nil {
+		if code < http.StatusInternalServerError {
+			return "", nil, fmt.Errorf("%w: %w", textopdfa.ErrInvalidInput, err)
+		}
+
+		return "", nil, err
+	}
+
+	logger.Debug("Waiting for job")
+
+	var final JobEvent
+
+	for final.Status == "" {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return job_id, nil, errors.New("job events closed unexpectedly")
+			}
+
+			if onEvent != nil {
+				onEvent(event)
+			}
+
+			if event.Final() {
+				final = event
+			}
+
+		case <-ctx.Done():
+			// on shutdown, Shutdown interrupts the job instead, so it can be queued again (see Recover)
+			if srv.stopping.Load() {
+				return job_id, nil, ctx.Err()
+			}
+
+			logger.Info("Caller stopped waiting, cancelling job", "err", ctx.Err())
+
+			if _, err := srv.cancelJob(job_id, errCallerGone, logger); err != nil {
+				logger.Error("Failed to cancel job [W3KD8NZA]", "err", err)
+			}
+
+			return job_id, nil, ctx.Err()
+		}
+	}
+
+	var job Jobs
+
+	if tx := srv.db.First(&job, "job_id = ?", job_id); tx.Error != nil {
+		return job_id, nil, fmt.Errorf("failed to load job: %w", tx.Error)
+	}
+
+	result, err := srv.jobResult(&job)
+
+	if err != nil {
+		return job_id, nil, fmt.Errorf("failed to load job result: %w", err)
+	}
+
+	switch final.Status {
+	case JOBSTATUS_FINISHED:
+		return job_id, result, nil
+	case JOBSTATUS_CANCELLED, JOBSTATUS_INTERRUPTED:
+		return job_id, result, fmt.Errorf("%w: %w", textopdfa.ErrCancelled, final.Err)
+	default:
+		return job_id, result, final.Err
+	}
+}
+
+// jobResult returns the outcome of a job as recorded in the db
+func (srv *Server) jobResult(job *Jobs) (*textopdfa.Result, error) {
+
+	stages, err := srv.jobStages(job.JobID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	result := &textopdfa.Result{
+		Path:       job.Result,
+		Log:        textopdfa.CombineStageLogs(stages),
+		Stages:     stages,
+		TexLogPath: job.TexLog,
+	}
+
+	if job.Diagnostics != "" {
+		if err := json.Unmarshal([]byte(job.Diagnostics), &result.Diagnostics); err != nil {
+			return nil, err
+		}
+	}
+
+	if job.Validation != "" {
+		if err := json.Unmarshal([]byte(job.Validation), &result.Validation); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
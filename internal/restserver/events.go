@@ -0,0 +1,109 @@
+package restserver
+
+import (
+	"log/slog"
+	"sync"
+
+	"github.com/tilseiffert/docker-tex-to-pdf/internal/textopdfa"
+)
+
+// EVENT_BUFFER is the number of events buffered per subscriber, further events (e.g. lines of output) are dropped until the subscriber catches up
+// The final event of a job is never dropped
+const EVENT_BUFFER = 256
+
+// JobEvent is a change of the status of a job or the progress of its compilation
+type JobEvent struct {
+	JobID    string                   `json:"job_id"`
+	Status   string                   `json:"status"`
+	Progress *textopdfa.ProgressEvent `json:"progress,omitempty"` // a command started or wrote a line of output, the status is unchanged
+	Err      error                    `json:"-"`                  // why the job failed, only set for final events of failed jobs
+}
+
+// Final returns true if the job will not change anymore (finished, failed, cancelled or interrupted)
+func (e JobEvent) Final() bool {
+	return e.Status != JOBSTATUS_CREATED && e.Status != JOBSTATUS_COMPILING
+}
+
+// publish publishes an event of a job to its subscribers, final events also queue the webhook callback of the job (see queueWebhook)
+func (srv *Server) publish(event JobEvent, logger *slog.Logger) {
+
+	srv.events.publish(event)
+
+	if event.Final() {
+		srv.queueWebhook(event, logger)
+	}
+}
+
+// broker distributes the events of the jobs to their subscribers
+type broker struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan JobEvent]struct{} // by job id
+}
+
+func newBroker() *broker {
+	return &broker{subscribers: make(map[string]map[chan JobEvent]struct{})}
+}
+
+// subscribe returns a channel receiving the events of a job, it is closed after the final event
+// The returned function unsubscribes, it must be called unless the channel has been closed
+func (b *broker) subscribe(job_id string) (<-chan JobEvent, func()) {
+
+	events := make(chan JobEvent, EVENT_BUFFER)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.subscribers[job_id] == nil {
+		b.subscribers[job_id] = make(map[chan JobEvent]struct{})
+	}
+
+	b.subscribers[job_id][events] = struct{}{}
+
+	return events, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		if _, ok := b.subscribers[job_id][events]; ok {
+			delete(b.subscribers[job_id], events)
+			close(events)
+		}
+
+		if len(b.subscribers[job_id]) == 0 {
+			delete(b.subscribers, job_id)
+		}
+	}
+}
+
+// publish sends an event to the subscribers of its job, it never blocks
+// A final event closes the channels of all subscribers of the job
+func (b *broker) publish(event JobEvent) {
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for events := range b.subscribers[event.JobID] {
+		select {
+		case events <- event:
+		default:
+			if !event.Final() {
+				continue
+			}
+
+			// make room for the final event by dropping the oldest one, only publish sends (under b.mu), so this cannot block
+			select {
+			case <-events:
+			default:
+			}
+
+			events <- event
+		}
+	}
+
+	if event.Final() {
+		for events := range b.subscribers[event.JobID] {
+			close(events)
+		}
+
+		delete(b.subscribers, event.JobID)
+	}
+}
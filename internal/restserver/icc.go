@@ -0,0 +1,36 @@
+package restserver
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/tilseiffert/docker-tex-to-pdf/internal/textopdfa"
+	"github.com/tilseiffert/docker-tex-to-pdf/pkg/server"
+)
+
+type ResponseICCProfiles struct {
+	Profiles []textopdfa.ICCProfile `json:"profiles"`
+}
+
+// handleICCProfiles lists the ICC profiles of the registry, selectable as output intent via options.output_intent.profile
+func (srv *Server) handleICCProfiles(w http.ResponseWriter, r *http.Request) {
+
+	logger := r.Context().Value("logger").(*slog.Logger)
+	logger = logger.With("func", "restserver.handleICCProfiles")
+
+	dir := srv.Options.ICCProfileDir
+
+	if dir == "" {
+		dir = textopdfa.DEFAULT_ICC_PROFILE_DIR
+	}
+
+	profiles, err := textopdfa.ICCProfiles(dir)
+
+	if err != nil {
+		logger.Error("Error listing ICC profiles [H4QX8ZMB]", "err", err, "dir", dir)
+		_ = server.WriteError(w, http.StatusInternalServerError, "failed to list ICC profiles [7RDK2QXE]", logger)
+		return
+	}
+
+	_ = server.WriteResponse(w, ResponseICCProfiles{Profiles: profiles}, logger)
+}
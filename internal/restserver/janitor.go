@@ -0,0 +1,180 @@
+package restserver
+
+import (
+	"context"
+	"io/fs"
+	"log/slog"
+	"path/filepath"
+	"time"
+)
+
+const (
+	DEFAULT_JANITOR_INTERVAL = 10 * time.Minute
+
+	// JANITOR_LOW_WATER is the share of ServerOptions.JobDirMaxSize the janitor frees the job dir to once it is exceeded, so it does not run at the limit
+	JANITOR_LOW_WATER = 0.9
+)
+
+// doneStatuses are the statuses of jobs the janitor may remove, queued and compiling jobs are never removed
+var doneStatuses = []string{JOBSTATUS_FINISHED, JOBSTATUS_ERROR, JOBSTATUS_TIMEOUT, JOBSTATUS_CANCELLED, JOBSTATUS_INTERRUPTED}
+
+// janitorRun counts what a run of the janitor reclaimed
+type janitorRun struct {
+	jobs     map[string]int // removed jobs by status
+	bytes    int64          // freed disk space
+	failures int            // jobs that could not be removed
+}
+
+// janitor removes old jobs (see ServerOptions.Retention and ServerOptions.JobDirMaxSize) until ctx is done, it is started by StartWorkers
+func (srv *Server) janitor(ctx context.Context) {
+
+	logger := ctx.Value("logger").(*slog.Logger)
+	logger = logger.With("func", "restserver.janitor")
+
+	if len(srv.Options.Retention) == 0 && srv.Options.JobDirMaxSize <= 0 {
+		logger.Debug("No retention configured, keeping all jobs")
+		return
+	}
+
+	interval := srv.Options.JanitorInterval
+
+	if interval <= 0 {
+		interval = DEFAULT_JANITOR_INTERVAL
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		srv.cleanup(ctx, logger)
+
+		select {
+		case <-ctx.Done():
+			logger.Debug("Stopping janitor")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// cleanup removes the jobs whose retention has expired and, if the job dir exceeds its maximum size, the oldest jobs that are done
+// Rows of removed jobs are soft-deleted, their directories and logs are removed
+func (srv *Server) cleanup(ctx context.Context, logger *slog.Logger) {
+
+	run := janitorRun{jobs: make(map[string]int)}
+
+	// === Expired jobs ===
+
+	for status, retention := range srv.Options.Retention {
+		if retention <= 0 {
+			continue
+		}
+
+		var jobs []Jobs
+
+		if tx := srv.db.Where("status = ? AND updated_at < ?", status, time.Now().UTC().Add(-retention)).Find(&jobs); tx.Error != nil {
+			logger.Error("Failed to load expired jobs [WB5K2NQJ]", "status", status, "err", tx.Error)
+			continue
+		}
+
+		for i := range jobs {
+			if ctx.Err() != nil {
+				break
+			}
+
+			srv.cleanupJob(&jobs[i], dirSize(jobs[i].Path), &run, logger)
+		}
+	}
+
+	// === High-water mark ===
+
+	if maxSize := srv.Options.JobDirMaxSize; maxSize > 0 && ctx.Err() == nil {
+		usage := dirSize(srv.jobdir)
+
+		if usage > maxSize {
+			logger.Warn("Job dir exceeds its maximum size, removing the oldest jobs", "size", usage, "max_size", maxSize)
+
+			var jobs []Jobs
+
+			if tx := srv.db.Where("status IN ?", doneStatuses).Order("updated_at").Find(&jobs); tx.Error != nil {
+				logger.Error("Failed to load jobs [0RCX6PHM]", "err", tx.Error)
+			}
+
+			low := int64(float64(maxSize) * JANITOR_LOW_WATER)
+
+			for i := 0; i < len(jobs) && usage > low && ctx.Err() == nil; i++ {
+				size := dirSize(jobs[i].Path)
+
+				if srv.cleanupJob(&jobs[i], size, &run, logger) {
+					usage -= size
+				}
+			}
+
+			if usage > maxSize {
+				logger.Warn("Job dir still exceeds its maximum size, the remaining jobs are queued or compiling", "size", usage, "max_size", maxSize)
+			}
+		}
+	}
+
+	if len(run.jobs) == 0 && run.failures == 0 {
+		logger.Debug("Nothing to clean up")
+		return
+	}
+
+	removed := 0
+
+	for _, count := range run.jobs {
+		removed += count
+	}
+
+	logger.Info("Cleaned up jobs", "jobs", removed, "by_status", run.jobs, "bytes", run.bytes, "failures", run.failures)
+}
+
+// cleanupJob removes a job unless its status changed since it was loaded, it returns true if it was removed
+func (srv *Server) cleanupJob(job *Jobs, size int64, run *janitorRun, logger *slog.Logger) bool {
+
+	tx := srv.db.Where("job_id = ? AND status = ?", job.JobID, job.Status).Delete(&Jobs{})
+
+	if tx.Error != nil {
+		logger.Error("Failed to delete job [6TNJ3WCY]", "job", job.JobID, "err", tx.Error)
+		run.failures++
+		return false
+	}
+
+	if tx.RowsAffected == 0 {
+		return false
+	}
+
+	if err := srv.removeJobFiles(job, logger); err != nil {
+		run.failures++
+		return false
+	}
+
+	logger.Debug("Removed job", "job", job.JobID, "status", job.Status, "bytes", size)
+
+	run.jobs[job.Status]++
+	run.bytes += size
+
+	return true
+}
+
+// dirSize returns the size of the files in a directory tree, unreadable files are skipped
+func dirSize(path string) int64 {
+
+	var size int64
+
+	_ = filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+
+		if err != nil {
+			return nil
+		}
+
+		if info, err := d.Info(); err == nil && info.Mode().IsRegular() {
+			size += info.Size()
+		}
+
+		return nil
+	})
+
+	return size
+}
@@ -0,0 +1,178 @@
+package restserver
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/oklog/ulid"
+	"github.com/tilseiffert/docker-tex-to-pdf/pkg/server"
+	"gorm.io/gorm"
+)
+
+const (
+	DEFAULT_LIST_LIMIT = 50
+	MAX_LIST_LIMIT     = 500
+)
+
+type ResponseJobList struct {
+	Jobs       []ResponseJobStatus `json:"jobs"`
+	NextCursor string              `json:"next_cursor,omitempty"` // pass as cursor to get the next page, empty on the last page
+}
+
+// listFilter are the filters of a job list request
+type listFilter struct {
+	statuses      []string
+	name          string
+	createdAfter  time.Time
+	createdBefore time.Time
+	cursor        string
+	limit         int
+}
+
+// parseTime parses a query parameter as RFC 3339 timestamp in UTC, empty means the zero time
+func parseTime(query url.Values, key string) (time.Time, error) {
+	value := query.Get(key)
+
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s is not a RFC 3339 timestamp (e.g. 2006-01-02T15:04:05Z)", key)
+	}
+
+	// SQLite compares the times as strings, they are stored in UTC (see OpenDB)
+	return t.UTC(), nil
+}
+
+// parseListFilter parses the query parameters of a job list request
+//   - status: only jobs with one of the given statuses (repeatable or comma separated, e.g. "X - error")
+//   - name: only jobs whose name contains the given string
+//   - created_after, created_before: only jobs created in the given range (RFC 3339)
+//   - cursor: only jobs older than the job with the given id (next_cursor of the previous page)
+//   - limit: maximum number of jobs (default DEFAULT_LIST_LIMIT, at most MAX_LIST_LIMIT)
+func parseListFilter(query url.Values) (*listFilter, error) {
+	var err error
+
+	filter := &listFilter{
+		name:   query.Get("name"),
+		cursor: query.Get("cursor"),
+		limit:  DEFAULT_LIST_LIMIT,
+	}
+
+	for _, value := range query["status"] {
+		for _, status := range strings.Split(value, ",") {
+			if status = strings.TrimSpace(status); status != "" {
+				filter.statuses = append(filter.statuses, status)
+			}
+		}
+	}
+
+	if filter.createdAfter, err = parseTime(query, "created_after"); err != nil {
+		return nil, err
+	}
+
+	if filter.createdBefore, err = parseTime(query, "created_before"); err != nil {
+		return nil, err
+	}
+
+	// ULIDs are case insensitive, but the cursor is compared with the upper case job ids as string
+	if filter.cursor != "" {
+		filter.cursor = strings.ToUpper(filter.cursor)
+
+		if _, err := ulid.ParseStrict(filter.cursor); err != nil {
+			return nil, fmt.Errorf("cursor is not a job id")
+		}
+	}
+
+	if value := query.Get("limit"); value != "" {
+		filter.limit, err = strconv.Atoi(value)
+
+		if err != nil || filter.limit < 1 || filter.limit > MAX_LIST_LIMIT {
+			return nil, fmt.Errorf("limit must be a number between 1 and %d", MAX_LIST_LIMIT)
+		}
+	}
+
+	return filter, nil
+}
+
+// apply adds the filter to a query on Jobs, newest jobs first
+// ULIDs sort by creation time, so the job id is used for ordering and as cursor
+func (filter *listFilter) apply(tx *gorm.DB) *gorm.DB {
+
+	if len(filter.statuses) > 0 {
+		tx = tx.Where("status IN ?", filter.statuses)
+	}
+
+	if filter.name != "" {
+		escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(filter.name)
+		tx = tx.Where(`name LIKE ? ESCAPE '\'`, "%"+escaped+"%")
+	}
+
+	if !filter.createdAfter.IsZero() {
+		tx = tx.Where("created_at >= ?", filter.createdAfter)
+	}
+
+	if !filter.createdBefore.IsZero() {
+		tx = tx.Where("created_at < ?", filter.createdBefore)
+	}
+
+	if filter.cursor != "" {
+		tx = tx.Where("job_id < ?", filter.cursor)
+	}
+
+	return tx.Order("job_id DESC").Limit(filter.limit + 1)
+}
+
+func (srv *Server) handleJobList(w http.ResponseWriter, r *http.Request) {
+
+	logger := r.Context().Value("logger").(*slog.Logger)
+	logger = logger.With("func", "restserver.handleJobList")
+
+	logger.Debug("Got request to list jobs")
+
+	// ===== Parse query =====
+
+	filter, err := parseListFilter(r.URL.Query())
+
+	if err != nil {
+		_ = server.WriteError(w, http.StatusBadRequest, err.Error()+" [D5RW1MHX]", logger)
+		return
+	}
+
+	// ===== Get jobs =====
+
+	var jobs []Jobs
+	tx := filter.apply(srv.jobs(r.Context()).Model(&Jobs{})).Find(&jobs)
+
+	if tx.Error != nil {
+		logger.Error("Failed to list jobs", "err", tx.Error)
+		_ = server.WriteError(w, http.StatusInternalServerError, "failed to list jobs [KA6P9ZGE]", logger)
+		return
+	}
+
+	// ===== Write response =====
+
+	resp := ResponseJobList{
+		Jobs: make([]ResponseJobStatus, 0, len(jobs)),
+	}
+
+	// one more job than requested was loaded to find out if there is a next page
+	if len(jobs) > filter.limit {
+		jobs = jobs[:filter.limit]
+		resp.NextCursor = jobs[len(jobs)-1].JobID
+	}
+
+	for i := range jobs {
+		resp.Jobs = append(resp.Jobs, newResponseJobStatus(&jobs[i]))
+	}
+
+	_ = server.WriteResponse(w, resp, logger)
+}
@@ -0,0 +1,109 @@
+package restserver
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+
+	"github.com/tilseiffert/docker-tex-to-pdf/internal/textopdfa"
+	"github.com/tilseiffert/docker-tex-to-pdf/pkg/server"
+)
+
+type ResponseJobLog struct {
+	JobID  string               `json:"job_id"`
+	Status string               `json:"status"`
+	Stages []textopdfa.StageLog `json:"stages"` // output of each command in the order they were run
+	Log    string               `json:"log"`    // output of all commands as a single text
+}
+
+// jobStages returns the recorded output of all commands run for a job
+func (srv *Server) jobStages(job_id string) ([]textopdfa.StageLog, error) {
+	var logs []JobLogs
+
+	if tx := srv.db.Where("job_id = ?", job_id).Order("seq").Find(&logs); tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	stages := make([]textopdfa.StageLog, 0, len(logs))
+
+	for _, log := range logs {
+		stages = append(stages, log.StageLog)
+	}
+
+	return stages, nil
+}
+
+func (srv *Server) handleJobLog(w http.ResponseWriter, r *http.Request) {
+
+	logger := r.Context().Value("logger").(*slog.Logger)
+	logger = logger.With("func", "restserver.handleJobLog")
+
+	job, ok := srv.findJob(w, r, logger)
+
+	if !ok {
+		return
+	}
+
+	logger.Debug("Got request to get job log for job " + job.JobID)
+
+	stages, err := srv.jobStages(job.JobID)
+
+	if err != nil {
+		logger.Error("Failed to load job logs", "err", err)
+		_ = server.WriteError(w, http.StatusInternalServerError, "failed to load job logs [0WB5TQJR]", logger)
+		return
+	}
+
+	resp := ResponseJobLog{
+		JobID:  job.JobID,
+		Status: job.Status,
+		Stages: stages,
+		Log:    textopdfa.CombineStageLogs(stages),
+	}
+
+	_ = server.WriteResponse(w, resp, logger)
+}
+
+func (srv *Server) handleJobTexLog(w http.ResponseWriter, r *http.Request) {
+
+	logger := r.Context().Value("logger").(*slog.Logger)
+	logger = logger.With("func", "restserver.handleJobTexLog")
+
+	job, ok := srv.findJob(w, r, logger)
+
+	if !ok {
+		return
+	}
+
+	logger.Debug("Got request to get LaTeX log for job " + job.JobID)
+
+	if job.TexLog == "" {
+		_ = server.WriteError(w, http.StatusNotFound, "job has no LaTeX log (yet) [YH2C5LDS]", logger)
+		return
+	}
+
+	file, err := os.Open(job.TexLog)
+
+	if err != nil {
+		_ = server.WriteError(w, http.StatusNotFound, "could not open LaTeX log [G1ZA7MXV]: "+err.Error(), logger)
+		return
+	}
+
+	defer file.Close()
+
+	fileInfo, err := file.Stat()
+
+	if err != nil {
+		_ = server.WriteError(w, http.StatusInternalServerError, "could not get file info [9TFN3EKU]: "+err.Error(), logger)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", fileInfo.Size()))
+
+	if _, err := io.Copy(w, file); err != nil {
+		logger.Error("Could not send LaTeX log", "err", err)
+	}
+}
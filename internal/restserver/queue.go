@@ -0,0 +1,151 @@
+package restserver
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+const (
+	DEFAULT_WORKERS     = 2
+	QUEUE_POLL_INTERVAL = 5 * time.Second
+)
+
+// StartWorkers starts the pool of workers compiling the queued jobs (see ServerOptions.Workers), the webhook dispatcher and the janitor
+// The context must carry the logger (key "logger"), the workers stop picking up new jobs when it is done
+func (srv *Server) StartWorkers(ctx context.Context) {
+
+	logger := ctx.Value("logger").(*slog.Logger)
+	logger = logger.With("func", "restserver.StartWorkers")
+
+	workers := srv.Options.Workers
+
+	if workers <= 0 {
+		workers = DEFAULT_WORKERS
+	}
+
+	logger.Info("Starting workers", "workers", workers)
+
+	for i := 0; i < workers; i++ {
+		srv.workers.Add(1)
+
+		go func(n int) {
+			defer srv.workers.Done()
+			srv.worker(ctx, n)
+		}(i)
+	}
+
+	srv.workers.Add(1)
+
+	go func() {
+		defer srv.workers.Done()
+		srv.webhookDispatcher(ctx)
+	}()
+
+	srv.workers.Add(1)
+
+	go func() {
+		defer srv.workers.Done()
+		srv.janitor(ctx)
+	}()
+}
+
+// notifyWorkers wakes up an idle worker to pick up a newly queued job, it never blocks
+func (srv *Server) notifyWorkers() {
+
+	select {
+	case srv.queue <- struct{}{}:
+	default:
+		// all workers are busy or already notified, the job is picked up by the next free worker
+	}
+}
+
+// worker picks up queued jobs one after another until ctx is done
+// Besides being notified, it polls the db regularly to catch jobs queued while all workers were busy
+func (srv *Server) worker(ctx context.Context, n int) {
+
+	logger := ctx.Value("logger").(*slog.Logger)
+	logger = logger.With("func", "restserver.worker", "worker", n)
+
+	ticker := time.NewTicker(QUEUE_POLL_INTERVAL)
+	defer ticker.Stop()
+
+	for {
+		// work off all queued jobs
+		for ctx.Err() == nil {
+			// detach the job from ctx, so it is neither cancelled by a stopping worker nor by the client that created it
+			// it can only be cancelled via the API (see handleJobCancel)
+			jobctx, cancel := context.WithCancelCause(context.WithoutCancel(ctx))
+			job, err := srv.claimNextJob(cancel)
+
+			if err != nil {
+				cancel(nil)
+				logger.Error("Error claiming next job [1YQG7K2D]", "err", err)
+				break
+			}
+
+			if job == nil {
+				cancel(nil)
+				break
+			}
+
+			srv.publish(JobEvent{JobID: job.JobID, Status: JOBSTATUS_COMPILING}, logger)
+
+			srv.runJob(jobctx, job)
+
+			srv.untrackJob(job.JobID)
+			cancel(nil)
+		}
+
+		select {
+		case <-ctx.Done():
+			logger.Debug("Stopping worker")
+			return
+		case <-srv.queue:
+		case <-ticker.C:
+		}
+	}
+}
+
+// claimNextJob marks the oldest queued job as compiling, registers cancel as its cancel function and returns it
+// It returns nil if there is no queued job. Claiming is atomic, so a job is never picked up twice
+func (srv *Server) claimNextJob(cancel context.CancelCauseFunc) (*Jobs, error) {
+
+	for {
+		// Find instead of First, an empty queue is not an error and must not be logged as one every poll
+		var jobs []Jobs
+		tx := srv.db.Where("status = ?", JOBSTATUS_CREATED).Order("job_id").Limit(1).Find(&jobs)
+
+		if tx.Error != nil {
+			return nil, tx.Error
+		}
+
+		if len(jobs) == 0 {
+			return nil, nil
+		}
+
+		job := jobs[0]
+
+		// the cancel function is registered together with the status, otherwise cancelJob could find a compiling job
+		// that is not running yet (see cancelRunningJob)
+		srv.runningMu.Lock()
+		tx = srv.db.Model(&Jobs{}).Where("job_id = ? AND status = ?", job.JobID, JOBSTATUS_CREATED).Update("status", JOBSTATUS_COMPILING)
+
+		if tx.Error == nil && tx.RowsAffected == 1 {
+			srv.running[job.JobID] = cancel
+		}
+
+		srv.runningMu.Unlock()
+
+		if tx.Error != nil {
+			return nil, tx.Error
+		}
+
+		if tx.RowsAffected == 1 {
+			job.Status = JOBSTATUS_COMPILING
+			return &job, nil
+		}
+
+		// claimed by another worker in the meantime, try the next one
+	}
+}
@@ -0,0 +1,146 @@
+package restserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/oklog/ulid"
+)
+
+var (
+	errInterrupted = errors.New("job interrupted by a restart of the server")
+	errShutdown    = errors.New("job interrupted by a shutdown of the server")
+	errJobDirLost  = errors.New("job directory does not exist anymore")
+)
+
+// Recover repairs the state a previous run of the server left behind, it must be called before StartWorkers
+//   - jobs still compiling were interrupted by a crash: they are marked as interrupted
+//   - interrupted jobs (by a crash or Shutdown) are queued again, if ServerOptions.RequeueInterrupted is set
+//   - queued jobs whose directory is gone are marked as failed
+//   - directories in the job dir without a job and build dirs left in the temp dir are removed
+//
+// It assumes that no other server instance uses the same database and job dir
+func (srv *Server) Recover(ctx context.Context) error {
+
+	logger := ctx.Value("logger").(*slog.Logger)
+	logger = logger.With("func", "restserver.Recover")
+
+	// === Interrupted jobs ===
+
+	var crashed []Jobs
+
+	if tx := srv.db.Where("status = ?", JOBSTATUS_COMPILING).Find(&crashed); tx.Error != nil {
+		return fmt.Errorf("could not load interrupted jobs: %w", tx.Error)
+	}
+
+	for _, job := range crashed {
+		logger.Warn("Marking job left compiling as interrupted", "job", job.JobID)
+		srv.failJob(job.JobID, JOBSTATUS_INTERRUPTED, errInterrupted, "", logger)
+	}
+
+	var interrupted []Jobs
+
+	if srv.Options.RequeueInterrupted {
+		if tx := srv.db.Where("status = ?", JOBSTATUS_INTERRUPTED).Find(&interrupted); tx.Error != nil {
+			return fmt.Errorf("could not load interrupted jobs: %w", tx.Error)
+		}
+	}
+
+	for _, job := range interrupted {
+		logger.Info("Queueing interrupted job again", "job", job.JobID)
+
+		// the logs of the interrupted run are deleted permanently, the job writes new ones
+		if tx := srv.db.Unscoped().Where("job_id = ?", job.JobID).Delete(&JobLogs{}); tx.Error != nil {
+			logger.Error("Failed to delete logs of interrupted job [9DXR3KWA]", "job", job.JobID, "err", tx.Error)
+		}
+
+		tx := srv.db.Model(&Jobs{}).Where("job_id = ? AND status = ?", job.JobID, JOBSTATUS_INTERRUPTED).
+			Updates(map[string]interface{}{
+				"status":         JOBSTATUS_CREATED,
+				"status_running": true,
+				"status_success": false,
+				"error":          "",
+				"error_stage":    "",
+				"result":         "",
+				"tex_log":        "",
+				"diagnostics":    "",
+				"validation":     "",
+				"compliant":      nil,
+				"cached":         false,
+			})
+
+		if tx.Error != nil {
+			return fmt.Errorf("could not queue interrupted job %s: %w", job.JobID, tx.Error)
+		}
+	}
+
+	// === Queued jobs without files ===
+
+	var queued []Jobs
+
+	if tx := srv.db.Where("status = ?", JOBSTATUS_CREATED).Find(&queued); tx.Error != nil {
+		return fmt.Errorf("could not load queued jobs: %w", tx.Error)
+	}
+
+	for _, job := range queued {
+		if _, err := os.Stat(job.Path); err != nil {
+			logger.Warn("Marking queued job without directory as failed", "job", job.JobID, "path", job.Path)
+			srv.failJob(job.JobID, JOBSTATUS_ERROR, errJobDirLost, "", logger)
+		}
+	}
+
+	// === Orphaned directories ===
+
+	var ids []string
+
+	if tx := srv.db.Model(&Jobs{}).Pluck("job_id", &ids); tx.Error != nil {
+		return fmt.Errorf("could not load job ids: %w", tx.Error)
+	}
+
+	known := make(map[string]bool, len(ids))
+
+	for _, id := range ids {
+		known[id] = true
+	}
+
+	entries, err := os.ReadDir(srv.jobdir)
+
+	if err != nil {
+		return fmt.Errorf("could not read job dir: %w", err)
+	}
+
+	for _, entry := range entries {
+		// only remove what looks like a job directory, anything else in the job dir is left alone
+		if _, err := ulid.Parse(entry.Name()); err != nil || !entry.IsDir() || known[entry.Name()] {
+			continue
+		}
+
+		path := filepath.Join(srv.jobdir, entry.Name())
+		logger.Info("Removing orphaned job directory", "path", path)
+
+		if err := os.RemoveAll(path); err != nil {
+			logger.Error("Failed to remove orphaned job directory [C4VN7PLE]", "path", path, "err", err)
+		}
+	}
+
+	// build dirs are removed when a compilation ends, these were left by a killed process
+	if srv.Options.BUILDDIR_PREFIX != "" {
+		builddirs, _ := filepath.Glob(filepath.Join(os.TempDir(), srv.Options.BUILDDIR_PREFIX+BUILDDIR_DELIM+"*"))
+
+		for _, path := range builddirs {
+			logger.Info("Removing stale build directory", "path", path)
+
+			if err := os.RemoveAll(path); err != nil {
+				logger.Error("Failed to remove stale build directory [H2TB8MZQ]", "path", path, "err", err)
+			}
+		}
+	}
+
+	logger.Info("Recovered jobs", "crashed", len(crashed), "requeued", len(interrupted))
+
+	return nil
+}
@@ -1,9 +1,15 @@
 package restserver
 
 import (
+	"context"
+	"fmt"
 	"log/slog"
 	"math/rand"
 	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/oklog/ulid"
@@ -11,13 +17,51 @@ import (
 )
 
 type Server struct {
-	db      *gorm.DB
-	Entropy *rand.Rand // Entropy source for generating ULIDs.
-	Options *ServerOptions
+	db       *gorm.DB
+	Entropy  *rand.Rand // Entropy source for generating ULIDs, guarded by entropyMu
+	Options  *ServerOptions
+	jobdir   string        // absolute path of the directory holding a directory per job
+	queue    chan struct{} // notifies idle workers about newly queued jobs
+	events   *broker       // status changes of the jobs, see CompileJob
+	webhooks chan struct{} // notifies the webhook dispatcher about newly queued deliveries
+
+	webhookGuard  *webhookGuard // addresses the callbacks may be sent to, see ServerOptions.WebhookAllowlist
+	webhookClient *http.Client  // client sending the callbacks, see newWebhookClient
+
+	entropyMu  sync.Mutex // Entropy is not safe for concurrent use, job ids are generated by HTTP handlers and gRPC calls
+	runningMu  sync.Mutex
+	running    map[string]context.CancelCauseFunc // cancel functions of the jobs running in this process, by job id
+	workers    sync.WaitGroup                     // running workers, see Shutdown
+	cachedir   string                             // absolute path of the compile cache, see ServerOptions.CacheMaxSize
+	cacheMu    sync.Mutex                         // serializes storing and evicting cache entries
+	versionsMu sync.Mutex
+	versions   map[string]string // versions of the commands by name, see toolchainVersions
+
+	stopping atomic.Bool   // Shutdown was called, running jobs are interrupted by it
+	stopped  chan struct{} // closed when Shutdown is called, ends the event streams of queued jobs
 }
 
 type ServerOptions struct {
 	BUILDDIR_PREFIX string
+	JobDir          string // directory holding the files of the jobs, empty means DEFAULT_JOBDIR
+	Workers         int    // number of jobs compiled in parallel, 0 means DEFAULT_WORKERS
+	ICCProfileDir   string // directory of the ICC profiles selectable as output intent, empty means textopdfa.DEFAULT_ICC_PROFILE_DIR
+	CacheDir        string // directory of the compile cache, empty means DEFAULT_CACHEDIR
+	CacheMaxSize    int64  // maximum size of the compile cache in bytes, the least recently used entries are evicted first, 0 disables the cache
+	PublicURL       string // base URL of the server used in webhook callbacks (e.g. https://tex.example.com), empty means relative URLs
+
+	// WebhookAllowlist are host names, addresses and CIDR ranges (e.g. "hooks.internal", "10.0.0.0/8") webhook callbacks may be sent to
+	// although they are loopback, private or link-local addresses, which are refused otherwise
+	WebhookAllowlist []string
+
+	// Retention is how long jobs are kept after they are done by status (e.g. JOBSTATUS_FINISHED), jobs with other statuses are kept forever
+	// Removed jobs are soft-deleted, their directories are removed (see janitor)
+	Retention       map[string]time.Duration
+	JobDirMaxSize   int64         // maximum size of the job dir in bytes, the oldest jobs that are done are removed early if it is exceeded, 0 means unlimited
+	JanitorInterval time.Duration // interval of the cleanup of old jobs, 0 means DEFAULT_JANITOR_INTERVAL
+
+	// RequeueInterrupted queues jobs interrupted by a restart of the server again, instead of marking them as failed (see Recover)
+	RequeueInterrupted bool
 }
 
 func NewServer(db *gorm.DB, options *ServerOptions) (*Server, error) {
@@ -28,15 +72,69 @@ func NewServer(db *gorm.DB, options *ServerOptions) (*Server, error) {
 		return nil, err
 	}
 
+	jobdir := options.JobDir
+
+	if jobdir == "" {
+		jobdir = DEFAULT_JOBDIR
+	}
+
+	jobdir, err = filepath.Abs(jobdir)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(jobdir, 0755); err != nil {
+		return nil, fmt.Errorf("could not create job dir: %w", err)
+	}
+
+	cachedir := options.CacheDir
+
+	if cachedir == "" {
+		cachedir = DEFAULT_CACHEDIR
+	}
+
+	cachedir, err = filepath.Abs(cachedir)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if options.CacheMaxSize > 0 {
+		if err := os.MkdirAll(cachedir, 0755); err != nil {
+			return nil, fmt.Errorf("could not create cache dir: %w", err)
+		}
+	}
+
+	guard, err := newWebhookGuard(options.WebhookAllowlist)
+
+	if err != nil {
+		return nil, err
+	}
+
 	server := &Server{
-		db:      db,
-		Entropy: rand.New(rand.NewSource(time.Now().UnixNano())),
-		Options: options,
+		db:       db,
+		Entropy:  rand.New(rand.NewSource(time.Now().UnixNano())),
+		Options:  options,
+		jobdir:   jobdir,
+		cachedir: cachedir,
+		queue:    make(chan struct{}, 1),
+		events:   newBroker(),
+		webhooks: make(chan struct{}, 1),
+		running:  make(map[string]context.CancelCauseFunc),
+		stopped:  make(chan struct{}),
+		versions: make(map[string]string),
+
+		webhookGuard:  guard,
+		webhookClient: newWebhookClient(guard),
 	}
 
 	return server, nil
 }
 
+// PublicPaths are the paths of the endpoints that do not require an API key (see server.RestServerOptions.PublicPaths)
+var PublicPaths = []string{"/api/v1/ping"}
+
 func (srv *Server) RegisterEndpoints(muxer *http.ServeMux) {
 
 	path := "/api/v1/"
@@ -85,10 +183,36 @@ func (srv *Server) RegisterEndpoints(muxer *http.ServeMux) {
 
 	muxer.HandleFunc("POST "+path+"createJob", srv.handleCreateJob)
 
+	muxer.HandleFunc("GET "+path+"jobs", srv.handleJobList)
+
 	muxer.HandleFunc("GET "+path+"job/{id}/status", srv.handleJobStatus)
 
 	muxer.HandleFunc("GET "+path+"job/{id}/result", srv.handleJobGetResult)
 
+	muxer.HandleFunc("GET "+path+"job/{id}/log", srv.handleJobLog)
+
+	muxer.HandleFunc("GET "+path+"job/{id}/events", srv.handleJobEvents)
+
+	muxer.HandleFunc("GET "+path+"job/{id}/log/tex", srv.handleJobTexLog)
+
+	muxer.HandleFunc("GET "+path+"job/{id}/validation", srv.handleJobValidation)
+
+	muxer.HandleFunc("GET "+path+"icc-profiles", srv.handleICCProfiles)
+
+	muxer.HandleFunc("POST "+path+"job/{id}/cancel", srv.handleJobCancel)
+
+	muxer.HandleFunc("DELETE "+path+"job/{id}", srv.handleJobDelete)
+
+	muxer.HandleFunc("GET "+path+"cache", srv.handleCache)
+
+	muxer.HandleFunc("DELETE "+path+"cache", srv.handlePurgeCache)
+
+	muxer.HandleFunc("GET "+path+"webhooks/deliveries", srv.handleWebhookDeliveries)
+
+	muxer.HandleFunc("GET "+path+"webhooks/deliveries/{id}", srv.handleWebhookDelivery)
+
+	muxer.HandleFunc("POST "+path+"webhooks/deliveries/{id}/replay", srv.handleWebhookReplay)
+
 	// muxer.HandleFunc("GET "+path+"test", func(w http.ResponseWriter, r *http.Request) {
 
 	// 	logger := r.Context().Value("logger").(*slog.Logger)
@@ -117,7 +241,11 @@ func (srv *Server) RegisterEndpoints(muxer *http.ServeMux) {
 
 }
 
+// NewULID generates a new ULID, it is safe for concurrent use
 func (srv *Server) NewULID() (ulid.ULID, error) {
 
+	srv.entropyMu.Lock()
+	defer srv.entropyMu.Unlock()
+
 	return ulid.New(ulid.Timestamp(time.Now()), srv.Entropy)
 }
@@ -0,0 +1,84 @@
+package restserver
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// SHUTDOWN_GRACE_PERIOD is the time interrupted jobs get to record their state before they are marked as interrupted anyway
+const SHUTDOWN_GRACE_PERIOD = 10 * time.Second
+
+// Shutdown waits for the running jobs to finish until ctx is done, then interrupts the remaining ones
+// The workers must be stopped first by cancelling the context passed to StartWorkers, so they do not pick up new jobs
+// The context must carry the logger (key "logger")
+// Interrupted jobs get the status JOBSTATUS_INTERRUPTED and are queued again on the next start if ServerOptions.RequeueInterrupted is set (see Recover)
+// Queued jobs stay queued
+func (srv *Server) Shutdown(ctx context.Context) error {
+
+	logger := ctx.Value("logger").(*slog.Logger)
+	logger = logger.With("func", "restserver.Shutdown")
+
+	if !srv.stopping.Swap(true) {
+		close(srv.stopped)
+	}
+
+	done := make(chan struct{})
+
+	go func() {
+		srv.workers.Wait()
+		close(done)
+	}()
+
+	srv.runningMu.Lock()
+	logger.Info("Waiting for running jobs", "jobs", len(srv.running))
+	srv.runningMu.Unlock()
+
+	select {
+	case <-done:
+		logger.Info("All jobs finished")
+		return nil
+	case <-ctx.Done():
+	}
+
+	// === Interrupt remaining jobs ===
+
+	srv.runningMu.Lock()
+	ids := make([]string, 0, len(srv.running))
+
+	for job_id, cancel := range srv.running {
+		ids = append(ids, job_id)
+		cancel(errShutdown)
+	}
+
+	srv.runningMu.Unlock()
+
+	logger.Warn("Deadline exceeded, interrupting running jobs", "jobs", ids)
+
+	// the jobs record their state themselves when their commands are killed, unless that takes too long
+	select {
+	case <-done:
+	case <-time.After(SHUTDOWN_GRACE_PERIOD):
+	}
+
+	for _, job_id := range ids {
+		tx := srv.db.Model(&Jobs{}).Where("job_id = ? AND status = ?", job_id, JOBSTATUS_COMPILING).
+			Updates(map[string]interface{}{
+				"status":         JOBSTATUS_INTERRUPTED,
+				"status_running": false,
+				"status_success": false,
+				"error":          errShutdown.Error(),
+			})
+
+		if tx.Error != nil {
+			logger.Error("Failed to mark job as interrupted [R5NW2KDA]", "job", job_id, "err", tx.Error)
+		}
+
+		if tx.RowsAffected == 1 {
+			srv.publish(JobEvent{JobID: job_id, Status: JOBSTATUS_INTERRUPTED, Err: errShutdown}, logger)
+		}
+	}
+
+	return fmt.Errorf("interrupted %d running jobs: %w", len(ids), ctx.Err())
+}
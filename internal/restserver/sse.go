@@ -0,0 +1,167 @@
+package restserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/tilseiffert/docker-tex-to-pdf/pkg/server"
+)
+
+// SSE_KEEPALIVE is the interval of the comments sent on idle event streams, so proxies do not close them
+const SSE_KEEPALIVE = 15 * time.Second
+
+// Names of the server-sent events of GET /api/v1/job/{id}/events
+const (
+	SSE_EVENT_STATUS  = "status"  // status of the job (see ResponseJobStatus), sent first, for each change and last
+	SSE_EVENT_COMMAND = "command" // a command of the pipeline started (see textopdfa.ProgressEvent)
+	SSE_EVENT_LOG     = "log"     // a line of output of the running command (see textopdfa.ProgressEvent)
+)
+
+// writeSSE writes a server-sent event with data encoded as JSON and flushes it
+func writeSSE(w http.ResponseWriter, flusher http.Flusher, event string, data interface{}) error {
+
+	payload, err := json.Marshal(data)
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+		return err
+	}
+
+	flusher.Flush()
+
+	return nil
+}
+
+// handleJobEvents streams the status changes and the output of the commands of a job as server-sent events (text/event-stream)
+// The stream starts with the current status and ends after the final status, it ends right away for jobs that are done
+// Lines of output are dropped if the client does not keep up, status events are not
+func (srv *Server) handleJobEvents(w http.ResponseWriter, r *http.Request) {
+
+	logger := r.Context().Value("logger").(*slog.Logger)
+	logger = logger.With("func", "restserver.handleJobEvents")
+
+	job_id := r.PathValue("id")
+
+	if job_id == "" {
+		_ = server.WriteError(w, http.StatusBadRequest, "job_id is empty [E2KV8RQT]", logger)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+
+	if !ok {
+		_ = server.WriteError(w, http.StatusInternalServerError, "streaming not supported [9YHC3NMA]", logger)
+		return
+	}
+
+	// subscribe before loading the job, so no change is missed
+	events, unsubscribe := srv.events.subscribe(job_id)
+	defer unsubscribe()
+
+	var job Jobs
+
+	if tx := srv.jobs(r.Context()).First(&job, "job_id = ?", job_id); tx.Error != nil {
+		_ = server.WriteError(w, http.StatusNotFound, "job not found [PX6W1ZDL]", logger)
+		return
+	}
+
+	logger = logger.With("job", job_id)
+	logger.Debug("Streaming job events")
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // disable buffering of nginx
+	w.WriteHeader(http.StatusOK)
+
+	if err := writeSSE(w, flusher, SSE_EVENT_STATUS, newResponseJobStatus(&job)); err != nil {
+		logger.Debug("Client gone", "err", err)
+		return
+	}
+
+	if (JobEvent{Status: job.Status}).Final() {
+		return
+	}
+
+	keepalive := time.NewTicker(SSE_KEEPALIVE)
+	defer keepalive.Stop()
+
+	status := job.Status
+	stopped := srv.stopped
+
+	for {
+		var err error
+
+		select {
+		case <-r.Context().Done():
+			logger.Debug("Client gone", "err", r.Context().Err())
+			return
+
+		case <-stopped:
+			// queued jobs are not compiled before the restart, running ones are finished or interrupted by Shutdown
+			if status == JOBSTATUS_CREATED {
+				logger.Debug("Shutting down, ending stream of queued job")
+				return
+			}
+
+			stopped = nil
+
+		case <-keepalive.C:
+			_, err = fmt.Fprint(w, ": keepalive\n\n")
+			flusher.Flush()
+
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+
+			switch {
+			case event.Progress == nil:
+				status = event.Status
+				err = srv.writeStatusSSE(w, flusher, event)
+
+				if err == nil && event.Final() {
+					return
+				}
+
+			case event.Progress.Line == "":
+				err = writeSSE(w, flusher, SSE_EVENT_COMMAND, event.Progress)
+
+			default:
+				err = writeSSE(w, flusher, SSE_EVENT_LOG, event.Progress)
+			}
+		}
+
+		if err != nil {
+			logger.Debug("Client gone", "err", err)
+			return
+		}
+	}
+}
+
+// writeStatusSSE writes the status of a job after a status event, as recorded in the db if possible (including the error)
+func (srv *Server) writeStatusSSE(w http.ResponseWriter, flusher http.Flusher, event JobEvent) error {
+
+	var job Jobs
+
+	if tx := srv.db.First(&job, "job_id = ?", event.JobID); tx.Error == nil && job.Status == event.Status {
+		return writeSSE(w, flusher, SSE_EVENT_STATUS, newResponseJobStatus(&job))
+	}
+
+	resp := ResponseJobStatus{
+		JobID:   event.JobID,
+		Status:  event.Status,
+		Running: !event.Final(),
+	}
+
+	if event.Err != nil {
+		resp.Error = event.Err.Error()
+	}
+
+	return writeSSE(w, flusher, SSE_EVENT_STATUS, resp)
+}
@@ -0,0 +1,171 @@
+package restserver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+
+	"github.com/tilseiffert/docker-tex-to-pdf/internal/workspace"
+)
+
+const (
+	MAX_REQUEST_SIZE   = workspace.MAX_TOTAL_SIZE + 1<<20 // files plus some room for the other fields
+	MAX_MULTIPART_MEM  = 32 << 20                         // multipart data above this size is buffered on disk
+	FORM_FIELD_FILES   = "files"
+	FORM_FIELD_ARCHIVE = "archive"
+)
+
+// errRequestBody marks errors caused by a malformed request body, they are reported as 400 Bad Request
+var errRequestBody = errors.New("invalid request body")
+
+// parseCreateJobRequest parses the request to create a job, supported are
+//   - application/json: RequestCreateJob, files and archive base64 encoded
+//   - multipart/form-data: the fields of RequestCreateJob as form fields or query parameters (options and metadata as JSON),
+//     any number of file parts named "files" (stored under their base name) and one file part named "archive"
+//   - application/zip, application/gzip: the body is the archive, name, main_file, options, metadata (as JSON),
+//     callback_url and callback_secret are query parameters
+//
+// The callback is validated along with the other fields by createJob
+func parseCreateJobRequest(w http.ResponseWriter, r *http.Request) (*RequestCreateJob, error) {
+
+	r.Body = http.MaxBytesReader(w, r.Body, MAX_REQUEST_SIZE)
+
+	mediatype, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	if err != nil {
+		// keep accepting requests without (valid) content type as JSON
+		mediatype = "application/json"
+	}
+
+	switch mediatype {
+	case "multipart/form-data":
+		return parseMultipartRequest(r)
+	case "application/zip", "application/x-zip-compressed", "application/gzip", "application/x-gzip", "application/x-tar+gzip":
+		return parseArchiveRequest(r)
+	}
+
+	var req RequestCreateJob
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+
+		if err == io.EOF {
+			return nil, fmt.Errorf("%w: request body is empty", errRequestBody)
+		}
+
+		return nil, fmt.Errorf("%w: %w", errRequestBody, err)
+	}
+
+	return &req, nil
+}
+
+// parseJSONField parses a form field or query parameter given as JSON (e.g. options) into target, empty values are ignored
+func parseJSONField(name string, value string, target any) error {
+
+	if value == "" {
+		return nil
+	}
+
+	if err := json.Unmarshal([]byte(value), target); err != nil {
+		return fmt.Errorf("%w: %s: %w", errRequestBody, name, err)
+	}
+
+	return nil
+}
+
+// readFormFile reads the content of an uploaded file
+func readFormFile(header *multipart.FileHeader) ([]byte, error) {
+	file, err := header.Open()
+
+	if err != nil {
+		return nil, fmt.Errorf("could not open uploaded file '%s': %w", header.Filename, err)
+	}
+
+	defer file.Close()
+
+	return io.ReadAll(file)
+}
+
+func parseMultipartRequest(r *http.Request) (*RequestCreateJob, error) {
+
+	if err := r.ParseMultipartForm(MAX_MULTIPART_MEM); err != nil {
+		return nil, fmt.Errorf("%w: %w", errRequestBody, err)
+	}
+
+	req := &RequestCreateJob{
+		Name:       r.FormValue("name"),
+		TexContent: r.FormValue("tex_content"),
+		MainFile:   r.FormValue("main_file"),
+
+		CallbackURL:    r.FormValue("callback_url"),
+		CallbackSecret: r.FormValue("callback_secret"),
+	}
+
+	if err := parseJSONField("options", r.FormValue("options"), &req.Options); err != nil {
+		return nil, err
+	}
+
+	if err := parseJSONField("metadata", r.FormValue("metadata"), &req.Metadata); err != nil {
+		return nil, err
+	}
+
+	for _, header := range r.MultipartForm.File[FORM_FIELD_FILES] {
+		content, err := readFormFile(header)
+
+		if err != nil {
+			return nil, err
+		}
+
+		req.Files = append(req.Files, workspace.File{Name: header.Filename, Content: content})
+	}
+
+	if headers := r.MultipartForm.File[FORM_FIELD_ARCHIVE]; len(headers) > 0 {
+
+		if len(headers) > 1 {
+			return nil, fmt.Errorf("%w: only one archive is supported", errRequestBody)
+		}
+
+		content, err := readFormFile(headers[0])
+
+		if err != nil {
+			return nil, err
+		}
+
+		req.Archive = content
+	}
+
+	return req, nil
+}
+
+func parseArchiveRequest(r *http.Request) (*RequestCreateJob, error) {
+	query := r.URL.Query()
+
+	req := &RequestCreateJob{
+		Name:     query.Get("name"),
+		MainFile: query.Get("main_file"),
+
+		CallbackURL:    query.Get("callback_url"),
+		CallbackSecret: query.Get("callback_secret"),
+	}
+
+	if err := parseJSONField("options", query.Get("options"), &req.Options); err != nil {
+		return nil, err
+	}
+
+	if err := parseJSONField("metadata", query.Get("metadata"), &req.Metadata); err != nil {
+		return nil, err
+	}
+
+	content, err := io.ReadAll(r.Body)
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errRequestBody, err)
+	}
+
+	req.Archive = content
+
+	return req, nil
+}
@@ -0,0 +1,48 @@
+package restserver
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/tilseiffert/docker-tex-to-pdf/internal/textopdfa"
+	"github.com/tilseiffert/docker-tex-to-pdf/pkg/server"
+)
+
+type ResponseJobValidation struct {
+	JobID      string                      `json:"job_id"`
+	Status     string                      `json:"status"`
+	Validation *textopdfa.ValidationReport `json:"validation"`
+}
+
+func (srv *Server) handleJobValidation(w http.ResponseWriter, r *http.Request) {
+
+	logger := r.Context().Value("logger").(*slog.Logger)
+	logger = logger.With("func", "restserver.handleJobValidation")
+
+	job, ok := srv.findJob(w, r, logger)
+
+	if !ok {
+		return
+	}
+
+	logger.Debug("Got request to get validation result for job " + job.JobID)
+
+	if job.Validation == "" {
+		_ = server.WriteError(w, http.StatusNotFound, "job was not validated (yet), see option validator [2XKJ8MWD]", logger)
+		return
+	}
+
+	resp := ResponseJobValidation{
+		JobID:  job.JobID,
+		Status: job.Status,
+	}
+
+	if err := json.Unmarshal([]byte(job.Validation), &resp.Validation); err != nil {
+		logger.Error("Error parsing validation report [T2WN6RCJ]", "err", err)
+		_ = server.WriteError(w, http.StatusInternalServerError, "failed to load validation report [F5PB1NQS]", logger)
+		return
+	}
+
+	_ = server.WriteResponse(w, resp, logger)
+}
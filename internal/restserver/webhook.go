@@ -0,0 +1,719 @@
+package restserver
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tilseiffert/docker-tex-to-pdf/internal/textopdfa"
+	"github.com/tilseiffert/docker-tex-to-pdf/pkg/server"
+	"gorm.io/gorm"
+)
+
+const (
+	WEBHOOK_PENDING   = "pending"   // waiting for the next attempt
+	WEBHOOK_DELIVERED = "delivered" // the receiver answered with 2xx
+	WEBHOOK_FAILED    = "failed"    // all attempts failed, can be replayed
+
+	WEBHOOK_EVENT         = "job.done"
+	WEBHOOK_MAX_ATTEMPTS  = 8
+	WEBHOOK_BACKOFF       = 10 * time.Second // wait before the second attempt, doubled for each further one
+	WEBHOOK_MAX_BACKOFF   = time.Hour
+	WEBHOOK_TIMEOUT       = 10 * time.Second
+	WEBHOOK_POLL_INTERVAL = 5 * time.Second
+
+	HEADER_WEBHOOK_SIGNATURE = "X-Textopdf-Signature-256" // "sha256=" and the hex encoded HMAC-SHA256 of the body, keyed with the callback secret
+	HEADER_WEBHOOK_DELIVERY  = "X-Textopdf-Delivery"      // id of the delivery, the same for all attempts
+	HEADER_WEBHOOK_EVENT     = "X-Textopdf-Event"
+)
+
+// webhookStatuses are the statuses of jobs that trigger their callback
+// Interrupted jobs trigger it only if they are not queued again (see ServerOptions.RequeueInterrupted and Recover)
+var webhookStatuses = []string{JOBSTATUS_FINISHED, JOBSTATUS_ERROR, JOBSTATUS_TIMEOUT, JOBSTATUS_CANCELLED}
+
+// WebhookPayload is the body of a webhook callback
+type WebhookPayload struct {
+	Event       string                 `json:"event"` // WEBHOOK_EVENT
+	JobID       string                 `json:"job_id"`
+	Name        string                 `json:"name"`
+	Status      string                 `json:"status"`
+	Success     bool                   `json:"success"`
+	Error       string                 `json:"error,omitempty"`
+	ErrorStage  string                 `json:"error_stage,omitempty"`
+	StatusURL   string                 `json:"status_url"`
+	ResultURL   string                 `json:"result_url,omitempty"` // only for successful jobs
+	Compliant   *bool                  `json:"compliant,omitempty"`
+	Diagnostics []textopdfa.Diagnostic `json:"diagnostics,omitempty"`
+	Timestamp   time.Time              `json:"timestamp"`
+}
+
+type ResponseWebhookDelivery struct {
+	ID             uint            `json:"id"`
+	JobID          string          `json:"job_id"`
+	URL            string          `json:"url"`
+	Status         string          `json:"status"`
+	State          string          `json:"state"`
+	Attempts       int             `json:"attempts"` // since the delivery was created or replayed
+	CreatedAt      time.Time       `json:"created_at"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	LastAttemptAt  *time.Time      `json:"last_attempt_at,omitempty"`
+	LastStatusCode int             `json:"last_status_code,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	Payload        json.RawMessage `json:"payload,omitempty"` // only included for a single delivery
+
+	AttemptLog []ResponseWebhookAttempt `json:"attempt_log,omitempty"` // all attempts, oldest first, only included for a single delivery
+}
+
+type ResponseWebhookAttempt struct {
+	Attempt    int       `json:"attempt"`
+	Timestamp  time.Time `json:"timestamp"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+}
+
+type ResponseWebhookDeliveries struct {
+	Deliveries []ResponseWebhookDelivery `json:"deliveries"`
+}
+
+// newResponseWebhookDelivery returns a delivery as sent to clients
+func newResponseWebhookDelivery(delivery *WebhookDeliveries, payload bool) ResponseWebhookDelivery {
+
+	resp := ResponseWebhookDelivery{
+		ID:             delivery.ID,
+		JobID:          delivery.JobID,
+		URL:            delivery.URL,
+		Status:         delivery.Status,
+		State:          delivery.State,
+		Attempts:       delivery.Attempts,
+		CreatedAt:      delivery.CreatedAt,
+		NextAttemptAt:  delivery.NextAttemptAt,
+		LastAttemptAt:  delivery.LastAttemptAt,
+		LastStatusCode: delivery.LastStatusCode,
+		LastError:      delivery.LastError,
+	}
+
+	if payload {
+		resp.Payload = json.RawMessage(delivery.Payload)
+	}
+
+	return resp
+}
+
+// validateCallback checks the callback URL and secret of a request
+// The host of the URL must not resolve to an internal address (see webhookGuard), unless it is allowed by ServerOptions.WebhookAllowlist
+func (srv *Server) validateCallback(ctx context.Context, req *RequestCreateJob) error {
+
+	if req.CallbackURL == "" {
+		if req.CallbackSecret != "" {
+			return errors.New("callback_secret requires a callback_url")
+		}
+
+		return nil
+	}
+
+	u, err := url.Parse(req.CallbackURL)
+
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid callback_url '%s', expected an absolute http or https URL", req.CallbackURL)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, WEBHOOK_TIMEOUT)
+	defer cancel()
+
+	if _, err := srv.webhookGuard.resolve(ctx, u.Hostname()); err != nil {
+		return fmt.Errorf("invalid callback_url '%s': %w", req.CallbackURL, err)
+	}
+
+	return nil
+}
+
+// webhookGuard restricts the addresses webhook callbacks are sent to, as the callback URLs are given by clients
+// Loopback, private and link-local addresses (e.g. cloud metadata services) are refused unless their host or address is allowed
+type webhookGuard struct {
+	hosts    []string       // host names that may resolve to internal addresses
+	prefixes []netip.Prefix // internal addresses that may be reached
+}
+
+// newWebhookGuard returns a guard allowing the given host names, addresses and CIDR ranges (e.g. "hooks.internal", "10.1.2.3", "10.0.0.0/8")
+func newWebhookGuard(allowlist []string) (*webhookGuard, error) {
+
+	guard := &webhookGuard{}
+
+	for _, entry := range allowlist {
+		entry = strings.TrimSpace(entry)
+
+		switch {
+		case entry == "":
+			continue
+
+		case strings.Contains(entry, "/"):
+			prefix, err := netip.ParsePrefix(entry)
+
+			if err != nil {
+				return nil, fmt.Errorf("invalid webhook allowlist entry '%s': %w", entry, err)
+			}
+
+			guard.prefixes = append(guard.prefixes, prefix.Masked())
+
+		default:
+			if addr, err := netip.ParseAddr(entry); err == nil {
+				guard.prefixes = append(guard.prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+				continue
+			}
+
+			guard.hosts = append(guard.hosts, strings.ToLower(strings.TrimSuffix(entry, ".")))
+		}
+	}
+
+	return guard, nil
+}
+
+// internalAddr returns true for addresses that are not reachable from the internet
+func internalAddr(addr netip.Addr) bool {
+	return addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsUnspecified()
+}
+
+// allowed returns true if a callback to host may be sent to addr
+func (guard *webhookGuard) allowed(host string, addr netip.Addr) bool {
+
+	addr = addr.Unmap()
+
+	if !internalAddr(addr) {
+		return true
+	}
+
+	if slices.Contains(guard.hosts, strings.ToLower(strings.TrimSuffix(host, "."))) {
+		return true
+	}
+
+	for _, prefix := range guard.prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// resolve returns the addresses of a host, it fails if any of them is not allowed
+// All addresses are checked, so a host cannot pass with one public address and be reached at an internal one
+func (guard *webhookGuard) resolve(ctx context.Context, host string) ([]netip.Addr, error) {
+
+	var addrs []netip.Addr
+
+	if addr, err := netip.ParseAddr(host); err == nil {
+		addrs = []netip.Addr{addr}
+	} else {
+		addrs, err = net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+
+		if err != nil {
+			return nil, fmt.Errorf("could not resolve host '%s': %w", host, err)
+		}
+	}
+
+	for _, addr := range addrs {
+		if !guard.allowed(host, addr) {
+			return nil, fmt.Errorf("host '%s' resolves to the internal address %s, which is not allowed for callbacks", host, addr.Unmap())
+		}
+	}
+
+	return addrs, nil
+}
+
+// dialContext connects to an address checked by resolve, so the host cannot resolve to another address after validateCallback (DNS rebinding)
+func (guard *webhookGuard) dialContext(ctx context.Context, network string, address string) (net.Conn, error) {
+
+	host, port, err := net.SplitHostPort(address)
+
+	if err != nil {
+		return nil, err
+	}
+
+	addrs, err := guard.resolve(ctx, host)
+
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := &net.Dialer{Timeout: WEBHOOK_TIMEOUT}
+
+	for _, addr := range addrs {
+		var conn net.Conn
+
+		conn, err = dialer.DialContext(ctx, network, net.JoinHostPort(addr.Unmap().String(), port))
+
+		if err == nil {
+			return conn, nil
+		}
+	}
+
+	return nil, err
+}
+
+// newWebhookClient returns the client sending the callbacks: it does not follow redirects, as they could lead to internal addresses,
+// and uses no proxy, so the addresses checked by guard are the ones connected to
+func newWebhookClient(guard *webhookGuard) *http.Client {
+	return &http.Client{
+		Timeout: WEBHOOK_TIMEOUT,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+		Transport: &http.Transport{
+			DialContext:         guard.dialContext,
+			TLSHandshakeTimeout: WEBHOOK_TIMEOUT,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     90 * time.Second,
+		},
+	}
+}
+
+// signWebhook returns the signature of a body (see HEADER_WEBHOOK_SIGNATURE)
+func signWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookBackoff returns the time to wait after the given failed attempt (starting at 1)
+func webhookBackoff(attempt int) time.Duration {
+	backoff := WEBHOOK_BACKOFF
+
+	for i := 1; i < attempt && backoff < WEBHOOK_MAX_BACKOFF; i++ {
+		backoff *= 2
+	}
+
+	return min(backoff, WEBHOOK_MAX_BACKOFF)
+}
+
+// jobURL returns the URL of an endpoint of a job, absolute if ServerOptions.PublicURL is set
+func (srv *Server) jobURL(job_id string, endpoint string) string {
+	return strings.TrimSuffix(srv.Options.PublicURL, "/") + "/api/v1/job/" + job_id + "/" + endpoint
+}
+
+// newWebhookPayload returns the callback body of a job
+func (srv *Server) newWebhookPayload(job *Jobs) *WebhookPayload {
+
+	payload := &WebhookPayload{
+		Event:      WEBHOOK_EVENT,
+		JobID:      job.JobID,
+		Name:       job.Name,
+		Status:     job.Status,
+		Success:    job.StatusSuccess,
+		Error:      job.Error,
+		ErrorStage: job.ErrorStage,
+		StatusURL:  srv.jobURL(job.JobID, "status"),
+		Compliant:  job.Compliant,
+		Timestamp:  time.Now().UTC(),
+	}
+
+	if job.StatusSuccess {
+		payload.ResultURL = srv.jobURL(job.JobID, "result")
+	}
+
+	if job.Diagnostics != "" {
+		_ = json.Unmarshal([]byte(job.Diagnostics), &payload.Diagnostics)
+	}
+
+	return payload
+}
+
+// queueWebhook queues the callback of a job that is done, if it has a callback URL
+func (srv *Server) queueWebhook(event JobEvent, logger *slog.Logger) {
+
+	interrupted := event.Status == JOBSTATUS_INTERRUPTED && !srv.Options.RequeueInterrupted
+
+	if !slices.Contains(webhookStatuses, event.Status) && !interrupted {
+		return
+	}
+
+	var job Jobs
+
+	if tx := srv.db.First(&job, "job_id = ?", event.JobID); tx.Error != nil {
+		logger.Error("Failed to load job for webhook [Z8QM2VKC]", "job", event.JobID, "err", tx.Error)
+		return
+	}
+
+	if job.CallbackURL == "" {
+		return
+	}
+
+	payload, err := json.Marshal(srv.newWebhookPayload(&job))
+
+	if err != nil {
+		logger.Error("Failed to encode webhook payload [6HWT0PLN]", "job", job.JobID, "err", err)
+		return
+	}
+
+	now := time.Now()
+
+	tx := srv.db.Create(&WebhookDeliveries{
+		JobID:         job.JobID,
+		URL:           job.CallbackURL,
+		Secret:        job.CallbackSecret,
+		Status:        job.Status,
+		Payload:       string(payload),
+		State:         WEBHOOK_PENDING,
+		NextAttemptAt: &now,
+	})
+
+	if tx.Error != nil {
+		logger.Error("Failed to queue webhook [K1RS5DXF]", "job", job.JobID, "err", tx.Error)
+		return
+	}
+
+	logger.Debug("Queued webhook", "job", job.JobID, "url", job.CallbackURL)
+
+	srv.notifyWebhooks()
+}
+
+// notifyWebhooks wakes up the webhook dispatcher, it never blocks
+func (srv *Server) notifyWebhooks() {
+
+	select {
+	case srv.webhooks <- struct{}{}:
+	default:
+	}
+}
+
+// webhookDispatcher delivers the due webhook callbacks one after another until ctx is done, it is started by StartWorkers
+// Pending deliveries are kept in the db, so they are resumed after a restart
+func (srv *Server) webhookDispatcher(ctx context.Context) {
+
+	logger := ctx.Value("logger").(*slog.Logger)
+	logger = logger.With("func", "restserver.webhookDispatcher")
+
+	ticker := time.NewTicker(WEBHOOK_POLL_INTERVAL)
+	defer ticker.Stop()
+
+	for {
+		for ctx.Err() == nil {
+			var delivery WebhookDeliveries
+			tx := srv.db.Where("state = ? AND next_attempt_at <= ?", WEBHOOK_PENDING, time.Now()).Order("next_attempt_at").Limit(1).Find(&delivery)
+
+			if tx.Error != nil {
+				logger.Error("Error loading due webhooks [4GZP7WXN]", "err", tx.Error)
+				break
+			}
+
+			if tx.RowsAffected == 0 {
+				break
+			}
+
+			srv.deliverWebhook(ctx, &delivery, logger)
+		}
+
+		select {
+		case <-ctx.Done():
+			logger.Debug("Stopping webhook dispatcher")
+			return
+		case <-srv.webhooks:
+		case <-ticker.C:
+		}
+	}
+}
+
+// deliverWebhook makes an attempt to deliver a callback and records its outcome
+func (srv *Server) deliverWebhook(ctx context.Context, delivery *WebhookDeliveries, logger *slog.Logger) {
+
+	logger = logger.With("delivery", delivery.ID, "job", delivery.JobID)
+
+	// a shutdown must not abort a running attempt, it is limited by the timeout anyway
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), WEBHOOK_TIMEOUT)
+	defer cancel()
+
+	start := time.Now()
+	code, err := srv.postWebhook(ctx, delivery)
+	duration := time.Since(start)
+
+	attempt := delivery.Attempts + 1
+	now := time.Now()
+
+	updates := map[string]interface{}{
+		"attempts":         attempt,
+		"last_attempt_at":  now,
+		"last_status_code": code,
+		"last_error":       "",
+		"state":            WEBHOOK_DELIVERED,
+		"next_attempt_at":  nil,
+	}
+
+	switch {
+	case err == nil:
+		logger.Info("Delivered webhook", "attempt", attempt, "code", code)
+
+	case attempt >= WEBHOOK_MAX_ATTEMPTS:
+		logger.Warn("Failed to deliver webhook, giving up", "attempt", attempt, "err", err)
+		updates["state"] = WEBHOOK_FAILED
+		updates["last_error"] = err.Error()
+
+	default:
+		next := now.Add(webhookBackoff(attempt))
+		logger.Info("Failed to deliver webhook, retrying", "attempt", attempt, "next_attempt_at", next, "err", err)
+		updates["state"] = WEBHOOK_PENDING
+		updates["last_error"] = err.Error()
+		updates["next_attempt_at"] = next
+	}
+
+	tx := srv.db.Model(&WebhookDeliveries{}).Where("id = ?", delivery.ID).Updates(updates)
+
+	if tx.Error != nil {
+		logger.Error("Failed to record webhook attempt [T9BN3CJE]", "err", tx.Error)
+	}
+
+	// the job and its deliveries may have been deleted during the attempt (see removeJobFiles)
+	if tx.Error == nil && tx.RowsAffected == 0 {
+		logger.Debug("Webhook delivery was deleted during the attempt")
+		return
+	}
+
+	srv.recordWebhookAttempt(delivery, code, err, duration, logger)
+}
+
+// recordWebhookAttempt adds an attempt to the history of a delivery (see WebhookAttempts)
+func (srv *Server) recordWebhookAttempt(delivery *WebhookDeliveries, code int, err error, duration time.Duration, logger *slog.Logger) {
+
+	var previous int64
+
+	if tx := srv.db.Model(&WebhookAttempts{}).Where("delivery_id = ?", delivery.ID).Count(&previous); tx.Error != nil {
+		logger.Error("Failed to count webhook attempts [N2FH8ZRD]", "err", tx.Error)
+	}
+
+	record := &WebhookAttempts{
+		DeliveryID: delivery.ID,
+		Attempt:    int(previous) + 1,
+		StatusCode: code,
+		DurationMs: duration.Milliseconds(),
+	}
+
+	if err != nil {
+		record.Error = err.Error()
+	}
+
+	if tx := srv.db.Create(record); tx.Error != nil {
+		logger.Error("Failed to record webhook attempt [7QKD0WSV]", "err", tx.Error)
+	}
+}
+
+// postWebhook sends a callback, it returns the HTTP status code of the response (0 if there was none) and an error unless it is 2xx
+func (srv *Server) postWebhook(ctx context.Context, delivery *WebhookDeliveries) (int, error) {
+
+	body := []byte(delivery.Payload)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(body))
+
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "tex-to-pdfa-webhook")
+	req.Header.Set(HEADER_WEBHOOK_EVENT, WEBHOOK_EVENT)
+	req.Header.Set(HEADER_WEBHOOK_DELIVERY, strconv.FormatUint(uint64(delivery.ID), 10))
+
+	if delivery.Secret != "" {
+		req.Header.Set(HEADER_WEBHOOK_SIGNATURE, signWebhook(delivery.Secret, body))
+	}
+
+	resp, err := srv.webhookClient.Do(req)
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer resp.Body.Close()
+
+	// redirects are not followed (see newWebhookClient), they fail like other answers that are not 2xx
+
+	// read a little of the body, so the connection can be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receiver answered with status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// findWebhookDelivery loads a delivery by the id given in the path and writes an error response if it cannot be found
+func (srv *Server) findWebhookDelivery(w http.ResponseWriter, r *http.Request, logger *slog.Logger) (*WebhookDeliveries, bool) {
+
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 64)
+
+	if err != nil {
+		_ = server.WriteError(w, http.StatusBadRequest, "invalid delivery id [0LWF6XRA]", logger)
+		return nil, false
+	}
+
+	var delivery WebhookDeliveries
+	tx := srv.db.First(&delivery, id)
+
+	if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+		_ = server.WriteError(w, http.StatusNotFound, "delivery not found [H5CX9TQM]", logger)
+		return nil, false
+	}
+
+	if tx.Error != nil {
+		_ = server.WriteError(w, http.StatusInternalServerError, "failed to load delivery [8PVA2NKS]", logger)
+		return nil, false
+	}
+
+	return &delivery, true
+}
+
+// handleWebhookDeliveries lists the webhook deliveries, newest first
+//   - state: only deliveries in the given state (e.g. failed)
+//   - job_id: only deliveries of the given job
+//   - limit: maximum number of deliveries (default DEFAULT_LIST_LIMIT, at most MAX_LIST_LIMIT)
+func (srv *Server) handleWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+
+	logger := r.Context().Value("logger").(*slog.Logger)
+	logger = logger.With("func", "restserver.handleWebhookDeliveries")
+
+	if !requireAdmin(w, r, logger) {
+		return
+	}
+
+	query := r.URL.Query()
+	tx := srv.db.Model(&WebhookDeliveries{})
+
+	if state := query.Get("state"); state != "" {
+		tx = tx.Where("state = ?", state)
+	}
+
+	if job_id := query.Get("job_id"); job_id != "" {
+		tx = tx.Where("job_id = ?", job_id)
+	}
+
+	limit := DEFAULT_LIST_LIMIT
+
+	if value := query.Get("limit"); value != "" {
+		var err error
+		limit, err = strconv.Atoi(value)
+
+		if err != nil || limit < 1 || limit > MAX_LIST_LIMIT {
+			_ = server.WriteError(w, http.StatusBadRequest, fmt.Sprintf("limit must be a number between 1 and %d [V7DJ4EQB]", MAX_LIST_LIMIT), logger)
+			return
+		}
+	}
+
+	var deliveries []WebhookDeliveries
+
+	if tx := tx.Order("id DESC").Limit(limit).Find(&deliveries); tx.Error != nil {
+		logger.Error("Failed to list webhook deliveries", "err", tx.Error)
+		_ = server.WriteError(w, http.StatusInternalServerError, "failed to list webhook deliveries [2SXN8GUY]", logger)
+		return
+	}
+
+	resp := ResponseWebhookDeliveries{
+		Deliveries: make([]ResponseWebhookDelivery, 0, len(deliveries)),
+	}
+
+	for i := range deliveries {
+		resp.Deliveries = append(resp.Deliveries, newResponseWebhookDelivery(&deliveries[i], false))
+	}
+
+	_ = server.WriteResponse(w, resp, logger)
+}
+
+func (srv *Server) handleWebhookDelivery(w http.ResponseWriter, r *http.Request) {
+
+	logger := r.Context().Value("logger").(*slog.Logger)
+	logger = logger.With("func", "restserver.handleWebhookDelivery")
+
+	if !requireAdmin(w, r, logger) {
+		return
+	}
+
+	delivery, ok := srv.findWebhookDelivery(w, r, logger)
+
+	if !ok {
+		return
+	}
+
+	var attempts []WebhookAttempts
+
+	if tx := srv.db.Where("delivery_id = ?", delivery.ID).Order("attempt").Find(&attempts); tx.Error != nil {
+		logger.Error("Failed to load webhook attempts", "err", tx.Error)
+		_ = server.WriteError(w, http.StatusInternalServerError, "failed to load delivery attempts [JX4T9BPA]", logger)
+		return
+	}
+
+	resp := newResponseWebhookDelivery(delivery, true)
+	resp.AttemptLog = make([]ResponseWebhookAttempt, 0, len(attempts))
+
+	for _, attempt := range attempts {
+		resp.AttemptLog = append(resp.AttemptLog, ResponseWebhookAttempt{
+			Attempt:    attempt.Attempt,
+			Timestamp:  attempt.CreatedAt,
+			StatusCode: attempt.StatusCode,
+			Error:      attempt.Error,
+			DurationMs: attempt.DurationMs,
+		})
+	}
+
+	_ = server.WriteResponse(w, resp, logger)
+}
+
+// handleWebhookReplay queues a delivery again with the same payload, e.g. after the receiver was fixed
+func (srv *Server) handleWebhookReplay(w http.ResponseWriter, r *http.Request) {
+
+	logger := r.Context().Value("logger").(*slog.Logger)
+	logger = logger.With("func", "restserver.handleWebhookReplay")
+
+	if !requireAdmin(w, r, logger) {
+		return
+	}
+
+	delivery, ok := srv.findWebhookDelivery(w, r, logger)
+
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+
+	tx := srv.db.Model(&WebhookDeliveries{}).Where("id = ? AND state <> ?", delivery.ID, WEBHOOK_PENDING).
+		Updates(map[string]interface{}{
+			"state":           WEBHOOK_PENDING,
+			"attempts":        0,
+			"next_attempt_at": now,
+		})
+
+	if tx.Error != nil {
+		_ = server.WriteError(w, http.StatusInternalServerError, "failed to replay delivery [QJ3E6MZH]", logger)
+		return
+	}
+
+	if tx.RowsAffected == 0 {
+		_ = server.WriteError(w, http.StatusConflict, "delivery is still pending [5NUK1BVW]", logger)
+		return
+	}
+
+	logger.Info("Replaying webhook", "delivery", delivery.ID, "job", delivery.JobID)
+
+	srv.notifyWebhooks()
+
+	delivery.State = WEBHOOK_PENDING
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = &now
+
+	_ = server.WriteResponseStatus(w, http.StatusAccepted, newResponseWebhookDelivery(delivery, false), logger)
+}
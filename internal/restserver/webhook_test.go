@@ -0,0 +1,173 @@
+package restserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func TestNewWebhookGuard(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		allowlist []string
+		wantErr   bool
+	}{
+		{name: "empty", allowlist: nil},
+		{name: "entries", allowlist: []string{"hooks.internal", " 10.1.2.3 ", "10.0.0.0/8", "fd00::/8", ""}},
+		{name: "invalid range", allowlist: []string{"10.0.0.0/33"}, wantErr: true},
+		{name: "invalid address in range", allowlist: []string{"hooks.internal/8"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newWebhookGuard(tt.allowlist); (err != nil) != tt.wantErr {
+				t.Errorf("newWebhookGuard(%q) returned error %v, want error %v", tt.allowlist, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWebhookGuardAllowed(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		allowlist []string
+		host      string
+		addr      string
+		want      bool
+	}{
+		{name: "public IPv4", host: "example.com", addr: "93.184.215.14", want: true},
+		{name: "public IPv6", host: "example.com", addr: "2606:2800:21f:cb07:6820:80da:af6b:8b2c", want: true},
+		{name: "loopback", host: "localhost", addr: "127.0.0.1"},
+		{name: "loopback IPv6", host: "localhost", addr: "::1"},
+		{name: "IPv4-mapped loopback", host: "example.com", addr: "::ffff:127.0.0.1"},
+		{name: "private", host: "example.com", addr: "10.0.0.5"},
+		{name: "private 192.168", host: "example.com", addr: "192.168.1.1"},
+		{name: "unique local IPv6", host: "example.com", addr: "fd00::1"},
+		{name: "metadata service", host: "metadata.google.internal", addr: "169.254.169.254"},
+		{name: "link-local IPv6", host: "example.com", addr: "fe80::1"},
+		{name: "unspecified", host: "example.com", addr: "0.0.0.0"},
+		{name: "allowed range", allowlist: []string{"10.1.0.0/16"}, host: "hooks", addr: "10.1.2.3", want: true},
+		{name: "outside allowed range", allowlist: []string{"10.1.0.0/16"}, host: "hooks", addr: "10.2.0.1"},
+		{name: "allowed address", allowlist: []string{"127.0.0.1"}, host: "localhost", addr: "127.0.0.1", want: true},
+		{name: "allowed address mapped", allowlist: []string{"127.0.0.1"}, host: "localhost", addr: "::ffff:127.0.0.1", want: true},
+		{name: "allowed host", allowlist: []string{"hooks.internal"}, host: "Hooks.Internal.", addr: "10.0.0.1", want: true},
+		{name: "other host", allowlist: []string{"hooks.internal"}, host: "evil.internal", addr: "10.0.0.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			guard, err := newWebhookGuard(tt.allowlist)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := guard.allowed(tt.host, netip.MustParseAddr(tt.addr)); got != tt.want {
+				t.Errorf("allowed(%q, %s) with allowlist %q = %v, want %v", tt.host, tt.addr, tt.allowlist, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateCallback(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		url     string
+		secret  string
+		wantErr string // part of the error, empty if the callback is valid
+	}{
+		{name: "none", url: ""},
+		{name: "public address", url: "https://93.184.215.14/hooks/tex", secret: "s3cret"},
+		{name: "secret without URL", secret: "s3cret", wantErr: "requires a callback_url"},
+		{name: "relative", url: "/hooks/tex", wantErr: "expected an absolute http or https URL"},
+		{name: "other scheme", url: "ftp://93.184.215.14/tex", wantErr: "expected an absolute http or https URL"},
+		{name: "loopback", url: "http://127.0.0.1:8080/hooks", wantErr: "internal address"},
+		{name: "loopback IPv6", url: "http://[::1]/hooks", wantErr: "internal address"},
+		{name: "localhost", url: "http://localhost/hooks", wantErr: "internal address"},
+		{name: "metadata service", url: "http://169.254.169.254/latest/meta-data/", wantErr: "internal address"},
+		{name: "private", url: "http://10.0.0.1/hooks", wantErr: "internal address"},
+	}
+
+	srv := newTestServer(t, nil)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := srv.validateCallback(context.Background(), &RequestCreateJob{CallbackURL: tt.url, CallbackSecret: tt.secret})
+
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("validateCallback(%q) returned error %v, want none", tt.url, err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("validateCallback(%q) returned error %v, want %q", tt.url, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWebhookClient(t *testing.T) {
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "/hooks", http.StatusTemporaryRedirect)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	defer receiver.Close()
+
+	tests := []struct {
+		name      string
+		allowlist []string
+		path      string
+		wantCode  int // 0 if the connection is refused
+	}{
+		// the callback URL was valid when the job was created, but the address is checked again when connecting
+		{name: "internal address", path: "/hooks"},
+		{name: "allowed address", allowlist: []string{"127.0.0.1"}, path: "/hooks", wantCode: http.StatusNoContent},
+		{name: "redirect is not followed", allowlist: []string{"127.0.0.1"}, path: "/redirect", wantCode: http.StatusTemporaryRedirect},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			guard, err := newWebhookGuard(tt.allowlist)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := newWebhookClient(guard).Post(receiver.URL+tt.path, "application/json", strings.NewReader("{}"))
+
+			if tt.wantCode == 0 {
+				if err == nil {
+					resp.Body.Close()
+					t.Fatalf("POST to %s succeeded, want the connection to be refused", receiver.URL)
+				}
+
+				if !strings.Contains(err.Error(), "internal address") {
+					t.Errorf("POST to %s returned error %v, want an internal address error", receiver.URL, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.wantCode {
+				t.Errorf("POST to %s returned %d, want %d", receiver.URL+tt.path, resp.StatusCode, tt.wantCode)
+			}
+		})
+	}
+}
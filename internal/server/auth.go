@@ -0,0 +1,98 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	httpserver "github.com/tilseiffert/docker-tex-to-pdf/pkg/server"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpcmetadata "google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// PUBLIC_METHOD_PREFIX is the prefix of the methods callable without API key, the reflection service only describes the API
+const PUBLIC_METHOD_PREFIX = "/grpc.reflection."
+
+// apiKeyFromMetadata returns the API key of a call: the bearer token of the "authorization" metadata or the "x-api-key" metadata
+func apiKeyFromMetadata(ctx context.Context) string {
+
+	md, _ := grpcmetadata.FromIncomingContext(ctx)
+
+	for _, value := range md.Get("authorization") {
+		if token, ok := strings.CutPrefix(value, "Bearer "); ok {
+			return strings.TrimSpace(token)
+		}
+	}
+
+	if values := md.Get(strings.ToLower(httpserver.HEADER_API_KEY)); len(values) > 0 {
+		return strings.TrimSpace(values[0])
+	}
+
+	return ""
+}
+
+// authenticate checks the API key of a call and returns a context carrying its caller (see httpserver.PrincipalFromContext)
+func authenticate(ctx context.Context, auth httpserver.Authenticator, method string) (context.Context, error) {
+
+	if strings.HasPrefix(method, PUBLIC_METHOD_PREFIX) {
+		return ctx, nil
+	}
+
+	key := apiKeyFromMetadata(ctx)
+
+	if key == "" {
+		return nil, status.Error(codes.Unauthenticated, "API key required")
+	}
+
+	principal, err := auth.Authenticate(ctx, key)
+
+	if errors.Is(err, httpserver.ErrInvalidKey) {
+		return nil, status.Error(codes.Unauthenticated, "invalid API key")
+	}
+
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to check API key: %v", err)
+	}
+
+	return httpserver.WithPrincipal(ctx, principal), nil
+}
+
+// unaryAuthInterceptor rejects unary calls without a valid API key
+func unaryAuthInterceptor(auth httpserver.Authenticator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+
+		ctx, err := authenticate(ctx, auth, info.FullMethod)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// authStream is a server stream with the context of its authenticated caller
+type authStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authStream) Context() context.Context {
+	return s.ctx
+}
+
+// streamAuthInterceptor rejects streaming calls without a valid API key
+func streamAuthInterceptor(auth httpserver.Authenticator) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+
+		ctx, err := authenticate(stream.Context(), auth, info.FullMethod)
+
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &authStream{ServerStream: stream, ctx: ctx})
+	}
+}
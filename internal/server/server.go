@@ -1,38 +1,486 @@
 package server
 
 import (
+	"bytes"
 	"context"
+	"crypto/rand"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net"
+	"os"
+	"path"
+	"path/filepath"
 	"strconv"
+	"strings"
 
+	"github.com/oklog/ulid"
+	"github.com/rs/zerolog"
+	slogzerolog "github.com/samber/slog-zerolog"
+	"github.com/tilseiffert/docker-tex-to-pdf/internal/contextkeys"
 	pb "github.com/tilseiffert/docker-tex-to-pdf/internal/protobuf"
+	"github.com/tilseiffert/docker-tex-to-pdf/internal/restserver"
+	"github.com/tilseiffert/docker-tex-to-pdf/internal/textopdfa"
+	"github.com/tilseiffert/docker-tex-to-pdf/internal/workspace"
+	httpserver "github.com/tilseiffert/docker-tex-to-pdf/pkg/server"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpcmetadata "google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
 )
 
 const (
-	StandardPort = 50051
+	StandardPort      = 50051
+	BUILDDIR_TEMPLATE = "tex-to-pdfa_grpc_*"
+	BUILDDIR_COMPILE  = "tex-to-pdfa_grpc_build_*"
+	TEXFILE           = "main.tex"
+	JOB_ID_HEADER     = "x-job-id" // response header holding the id of the job of a call, if the server runs with a JobEngine
 )
 
+// JobEngine compiles requests as jobs of a shared job store and worker pool (see restserver.Server.CompileJob)
+type JobEngine interface {
+	CompileJob(ctx context.Context, req *restserver.RequestCreateJob, onEvent func(restserver.JobEvent)) (string, *textopdfa.Result, error)
+}
+
 // server is used to implement the TexCompilerServer interface
 type server struct {
 	pb.UnimplementedTexCompilerServer
+
+	engine JobEngine // nil compiles each request in a build directory of its own
+}
+
+// writeFiles writes all files of a request into dir
+// File names are relative paths, names escaping dir (e.g. "../x" or "/etc/x") are rejected
+func writeFiles(dir string, files []*pb.File) error {
+	ws := workspace.New(dir)
+
+	for _, file := range files {
+		if err := ws.Write(file.GetName(), bytes.NewReader(file.GetContent())); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// findTexFile returns the name of the main TeX file of a request
+// This is the requested main file, TEXFILE if present, otherwise the only .tex file of the request
+func findTexFile(req *pb.CompileRequest) (string, error) {
+	var candidates []string
+
+	if req.GetMainFile() != "" {
+		for _, file := range req.GetFiles() {
+			if file.GetName() == req.GetMainFile() {
+				return req.GetMainFile(), nil
+			}
+		}
+
+		return "", fmt.Errorf("main file '%s' not found in files", req.GetMainFile())
+	}
+
+	for _, file := range req.GetFiles() {
+		if file.GetName() == TEXFILE {
+			return TEXFILE, nil
+		}
+
+		if strings.HasSuffix(file.GetName(), ".tex") {
+			candidates = append(candidates, file.GetName())
+		}
+	}
+
+	if len(candidates) != 1 {
+		return "", fmt.Errorf("expected '%s' or exactly one .tex file, found %d .tex files", TEXFILE, len(candidates))
+	}
+
+	return candidates[0], nil
+}
+
+// compileOptions converts the compile options of a request, nil means defaults
+func compileOptions(opts *pb.CompileOptions) *textopdfa.CompileOptions {
+
+	if opts == nil {
+		return nil
+	}
+
+	result := &textopdfa.CompileOptions{
+		Engine:                  opts.GetEngine(),
+		PDFAPart:                int(opts.GetPdfaPart()),
+		ColorConversionStrategy: opts.GetColorConversionStrategy(),
+		SkipPDFA1:               opts.GetSkipPdfa1(),
+		TimeoutSeconds:          int(opts.GetTimeoutSeconds()),
+		StageTimeoutSeconds:     int(opts.GetStageTimeoutSeconds()),
+		Validator:               opts.GetValidator(),
+		FacturXProfile:          opts.GetFacturxProfile(),
+	}
+
+	for _, attachment := range opts.GetAttachments() {
+		result.Attachments = append(result.Attachments, textopdfa.Attachment{
+			File:         attachment.GetFile(),
+			Name:         attachment.GetName(),
+			MIMEType:     attachment.GetMimeType(),
+			Relationship: attachment.GetRelationship(),
+			Description:  attachment.GetDescription(),
+		})
+	}
+
+	if intent := opts.GetOutputIntent(); intent != nil {
+		result.OutputIntent = &textopdfa.OutputIntent{
+			Profile:      intent.GetProfile(),
+			File:         intent.GetFile(),
+			Identifier:   intent.GetIdentifier(),
+			Condition:    intent.GetCondition(),
+			RegistryName: intent.GetRegistryName(),
+		}
+	}
+
+	if opts.CompatibilityPolicy != nil {
+		policy := int(opts.GetCompatibilityPolicy())
+		result.CompatibilityPolicy = &policy
+	}
+
+	return result
+}
+
+// metadata converts the metadata of a request
+func metadata(m *pb.Metadata) *textopdfa.Metadata {
+
+	result := &textopdfa.Metadata{
+		Title:    m.GetTitle(),
+		Author:   m.GetAuthor(),
+		Subject:  m.GetSubject(),
+		Keywords: m.GetKeywords(),
+		Creator:  m.GetCreator(),
+		Language: m.GetLanguage(),
+	}
+
+	for _, property := range m.GetCustom() {
+		result.Custom = append(result.Custom, textopdfa.XMPProperty{
+			Namespace: property.GetNamespace(),
+			Prefix:    property.GetPrefix(),
+			Name:      property.GetName(),
+			Value:     property.GetValue(),
+		})
+	}
+
+	return result
+}
+
+// diagnostics converts the diagnostics of a compilation into their protobuf representation
+func diagnostics(result *textopdfa.Result) []*pb.Diagnostic {
+	var diags []*pb.Diagnostic
+
+	for _, diagnostic := range result.Diagnostics {
+		diags = append(diags, &pb.Diagnostic{
+			Severity: diagnostic.Severity,
+			Kind:     diagnostic.Kind,
+			Message:  diagnostic.Message,
+			File:     diagnostic.File,
+			Line:     int32(diagnostic.Line),
+			Package:  diagnostic.Package,
+		})
+	}
+
+	return diags
+}
+
+// validation converts the validation report of a compilation into its protobuf representation, nil if there is none
+func validation(result *textopdfa.Result) *pb.ValidationReport {
+
+	if result.Validation == nil {
+		return nil
+	}
+
+	report := &pb.ValidationReport{
+		Validator: result.Validation.Validator,
+		Profile:   result.Validation.Profile,
+		Compliant: result.Validation.Compliant,
+	}
+
+	for _, rule := range result.Validation.FailedRules {
+		report.FailedRules = append(report.FailedRules, &pb.ValidationRule{
+			Specification: rule.Specification,
+			Clause:        rule.Clause,
+			TestNumber:    int32(rule.TestNumber),
+			Description:   rule.Description,
+			FailedChecks:  int32(rule.FailedChecks),
+		})
+	}
+
+	return report
+}
+
+// compileError converts an error of the compile pipeline into a gRPC status error
+// The collected log and diagnostics are attached as a CompileReply detail, so clients can show why the compilation failed
+func compileError(err error, result *textopdfa.Result) error {
+	code := codes.Internal
+
+	switch {
+	case errors.Is(err, textopdfa.ErrToolMissing):
+		code = codes.Unavailable
+	case errors.Is(err, textopdfa.ErrTimeout):
+		code = codes.DeadlineExceeded
+	case errors.Is(err, textopdfa.ErrCancelled):
+		code = codes.Canceled
+	case errors.Is(err, textopdfa.ErrInvalidInput), errors.Is(err, textopdfa.ErrCompileFailed):
+		code = codes.InvalidArgument
+	case errors.Is(err, textopdfa.ErrConversionFailed):
+		code = codes.FailedPrecondition
+	}
+
+	st := status.New(code, err.Error())
+
+	if result == nil {
+		return st.Err()
+	}
+
+	withLog, detailErr := st.WithDetails(&pb.CompileReply{Log: result.Log, Diagnostics: diagnostics(result)})
+
+	if detailErr != nil {
+		return st.Err()
+	}
+
+	return withLog.Err()
+}
+
+// newRequest assigns an id to a call and returns the context and logger of the call
+func newRequest(ctx context.Context) (context.Context, zerolog.Logger, error) {
+
+	request_id, err := ulid.New(ulid.Now(), ulid.Monotonic(rand.Reader, 0))
+
+	if err != nil {
+		return ctx, zerolog.Logger{}, status.Errorf(codes.Internal, "failed to generate request ID: %v", err)
+	}
+
+	logger := zerolog.New(zerolog.ConsoleWriter{Out: os.Stderr}).With().Timestamp().
+		Str("request", request_id.String()).Str("logger", "grpc-zerolog").Logger()
+
+	return context.WithValue(ctx, contextkeys.LoggerKey, logger), logger, nil
+}
+
+// validateRequest checks a request and returns the name of its main TeX file
+func validateRequest(req *pb.CompileRequest) (string, error) {
+
+	if len(req.GetFiles()) == 0 {
+		return "", status.Error(codes.InvalidArgument, "no files given")
+	}
+
+	texfile, err := findTexFile(req)
+
+	if err != nil {
+		return "", status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return texfile, nil
 }
 
 // CompileToPDF is the implementation of the gRPC method CompileToPDF
+// It writes all files of the request into a build directory and compiles them to PDF/A
+// With a JobEngine, the request is compiled as job instead, see compileJob
 func (s *server) CompileToPDF(ctx context.Context, req *pb.CompileRequest) (*pb.CompileReply, error) {
-	// Hier wird Ihre Logik zum Kompilieren der TeX-Datei in PDF implementiert
-	// ...
 
-	// Ein einfaches Beispiel:
-	pdfContent := []byte("This would be the binary content of the PDF.")
-	logMessage := "Compiled successfully."
+	ctx, logger, err := newRequest(ctx)
 
-	return &pb.CompileReply{PdfContent: pdfContent, Log: logMessage}, nil
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info().Int("files", len(req.GetFiles())).Msg("Got request to compile TeX to PDF/A")
+
+	texfile, err := validateRequest(req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return s.compile(ctx, req, texfile, logger, nil)
+}
+
+// compile compiles a validated request and reports its progress to p (nil reports nothing)
+func (s *server) compile(ctx context.Context, req *pb.CompileRequest, texfile string, logger zerolog.Logger, p *progress) (*pb.CompileReply, error) {
+
+	if s.engine != nil {
+		return s.compileJob(ctx, req, texfile, logger, p)
+	}
+
+	// ===== Validate request =====
+
+	opts := compileOptions(req.GetOptions())
+
+	if req.GetMetadata() != nil {
+		if opts == nil {
+			opts = &textopdfa.CompileOptions{}
+		}
+
+		opts.Metadata = metadata(req.GetMetadata())
+	}
+
+	if err := opts.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid options: "+err.Error())
+	}
+
+	// ===== Prepare build =====
+
+	workdir, err := os.MkdirTemp("", BUILDDIR_TEMPLATE)
+
+	if err != nil {
+		logger.Error().Err(err).Msg("Could not create working dir")
+		return nil, status.Error(codes.Internal, "could not create working dir")
+	}
+
+	defer os.RemoveAll(workdir)
+
+	if err := writeFiles(workdir, req.GetFiles()); err != nil {
+		if errors.Is(err, workspace.ErrInvalidPath) || errors.Is(err, workspace.ErrTooLarge) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+
+		logger.Error().Err(err).Msg("Could not write files")
+		return nil, status.Error(codes.Internal, "could not write files")
+	}
+
+	// ===== Compile TeX to PDF/A =====
+
+	// without job engine, a client reading the progress slowly slows down the commands
+	if p != nil {
+		ctx = textopdfa.WithProgress(ctx, p.report)
+	}
+
+	result, err := textopdfa.CompileTexToPDFA(ctx, filepath.Join(workdir, texfile), BUILDDIR_COMPILE, opts)
+
+	if err != nil {
+		logger.Error().Err(err).Msg("Error compiling TeX to PDF/A")
+		return nil, compileError(err, result)
+	}
+
+	pdfContent, err := os.ReadFile(result.Path)
+
+	if err != nil {
+		logger.Error().Err(err).Str("path", result.Path).Msg("Could not read resulting PDF/A file")
+		return nil, status.Error(codes.Internal, "could not read resulting PDF/A file")
+	}
+
+	logger.Info().Int("bytes", len(pdfContent)).Msg("Successfully compiled TeX to PDF/A")
+
+	return &pb.CompileReply{
+		PdfContent:  pdfContent,
+		Log:         result.Log,
+		Diagnostics: diagnostics(result),
+		Validation:  validation(result),
+		PdfSize:     int64(len(pdfContent)),
+	}, nil
+}
+
+// compileJob compiles a request as job of the JobEngine, so it shares the workers with the REST API and is visible there
+// The id of the job is sent as response header JOB_ID_HEADER as soon as it is queued and is part of the reply
+func (s *server) compileJob(ctx context.Context, req *pb.CompileRequest, texfile string, logger zerolog.Logger, p *progress) (*pb.CompileReply, error) {
+
+	files := make([]workspace.File, 0, len(req.GetFiles()))
+
+	for _, file := range req.GetFiles() {
+		files = append(files, workspace.File{Name: file.GetName(), Content: file.GetContent()})
+	}
+
+	jobreq := &restserver.RequestCreateJob{
+		Name:     strings.TrimSuffix(path.Base(texfile), path.Ext(texfile)),
+		MainFile: texfile,
+		Files:    files,
+		Options:  compileOptions(req.GetOptions()),
+	}
+
+	if req.GetMetadata() != nil {
+		jobreq.Metadata = metadata(req.GetMetadata())
+	}
+
+	// the job engine logs with slog
+	ctx = context.WithValue(ctx, "logger", slog.New(slogzerolog.Option{Logger: &logger}.NewZerologHandler()))
+
+	// clients can follow the job via the REST API while waiting
+	job_id, result, err := s.engine.CompileJob(ctx, jobreq, func(event restserver.JobEvent) {
+		if event.Status == restserver.JOBSTATUS_CREATED {
+			_ = grpc.SendHeader(ctx, grpcmetadata.Pairs(JOB_ID_HEADER, event.JobID))
+		}
+
+		p.jobEvent(event)
+	})
+
+	logger = logger.With().Str("job", job_id).Logger()
+
+	if err != nil {
+		logger.Error().Err(err).Msg("Error compiling job")
+		return nil, compileError(err, result)
+	}
+
+	pdfContent, err := os.ReadFile(result.Path)
+
+	if err != nil {
+		logger.Error().Err(err).Str("path", result.Path).Msg("Could not read resulting PDF/A file")
+		return nil, status.Error(codes.Internal, "could not read resulting PDF/A file")
+	}
+
+	logger.Info().Int("bytes", len(pdfContent)).Msg("Successfully compiled job")
+
+	return &pb.CompileReply{
+		PdfContent:  pdfContent,
+		Log:         result.Log,
+		Diagnostics: diagnostics(result),
+		Validation:  validation(result),
+		JobId:       job_id,
+		PdfSize:     int64(len(pdfContent)),
+	}, nil
+}
+
+// NewServer creates a gRPC server with the TexCompiler and the reflection service registered
+// If engine is nil, each call compiles in a build directory of its own, otherwise calls are run as jobs of the engine
+// If auth is not nil, calls require an API key (metadata "authorization: Bearer <key>" or "x-api-key")
+func NewServer(engine JobEngine, auth httpserver.Authenticator) *grpc.Server {
+
+	var opts []grpc.ServerOption
+
+	// calls need an API key like REST requests, their caller owns the jobs they create
+	if auth != nil {
+		opts = append(opts, grpc.UnaryInterceptor(unaryAuthInterceptor(auth)), grpc.StreamInterceptor(streamAuthInterceptor(auth)))
+	}
+
+	// Create a new gRPC server
+	s := grpc.NewServer(opts...)
+
+	// Register the TexCompilerServer with the gRPC server
+	pb.RegisterTexCompilerServer(s, &server{engine: engine})
+
+	// Register reflection service on gRPC server.
+	reflection.Register(s)
+
+	return s
+}
+
+// Serve listens on the given address (e.g. ":50051") and serves s in the background until it is stopped (see Shutdown)
+// It returns the address listened on
+func Serve(s *grpc.Server, address string) (net.Addr, error) {
+
+	lis, err := net.Listen("tcp", address)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed create listener: %w", err)
+	}
+
+	serve(s, lis)
+
+	return lis.Addr(), nil
 }
 
-// Start starts the gRPC server on the given port
+// serve serves s on lis in the background, errors are logged
+func serve(s *grpc.Server, lis net.Listener) {
+
+	go func() {
+		if err := s.Serve(lis); err != nil {
+			logger := zerolog.New(zerolog.ConsoleWriter{Out: os.Stderr}).With().Timestamp().Str("logger", "grpc-zerolog").Logger()
+			logger.Error().Err(err).Str("address", lis.Addr().String()).Msg("gRPC server stopped serving")
+		}
+	}()
+}
+
+// Start starts a standalone gRPC server (without job engine) on the given port and returns it, it serves in the background until it is stopped (see Shutdown)
 // If port is 0, the standard port 50051 is used
 func Start(port int) (*grpc.Server, error) {
 
@@ -51,23 +499,28 @@ func Start(port int) (*grpc.Server, error) {
 		}
 	}
 
-	// if err != nil {
-	// 	return nil, fmt.Errorf("failed create listener: %w", err)
-	// }
+	s := NewServer(nil, nil)
+	serve(s, lis)
 
-	// Create a new gRPC server
-	s := grpc.NewServer()
+	return s, nil
+}
 
-	// Register the TexCompilerServer with the gRPC server
-	pb.RegisterTexCompilerServer(s, &server{})
+// Shutdown stops the gRPC server gracefully: it stops accepting new calls and waits for the running ones (e.g. compilations) until ctx is done
+// Calls still running then are cancelled, which kills their compilations, and ctx.Err() is returned
+func Shutdown(ctx context.Context, s *grpc.Server) error {
+	done := make(chan struct{})
 
-	// Register reflection service on gRPC server.
-	reflection.Register(s)
+	go func() {
+		s.GracefulStop()
+		close(done)
+	}()
 
-	// Start the gRPC server
-	if err := s.Serve(lis); err != nil {
-		return nil, fmt.Errorf("failed to start grpc-server: %w", err)
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.Stop()
+		<-done
+		return ctx.Err()
 	}
-
-	return s, nil
 }
@@ -0,0 +1,175 @@
+package server
+
+import (
+	"sync"
+
+	"github.com/rs/zerolog"
+	pb "github.com/tilseiffert/docker-tex-to-pdf/internal/protobuf"
+	"github.com/tilseiffert/docker-tex-to-pdf/internal/restserver"
+	"github.com/tilseiffert/docker-tex-to-pdf/internal/textopdfa"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// PDF_CHUNK_SIZE is the size of the PDF chunks sent by CompileStream, well below gRPC's default message limit of 4 MB
+const PDF_CHUNK_SIZE = 1 << 20
+
+// phases maps the stages of the pipeline to the phases reported to clients, stages without phase (e.g. the output stage) are not reported
+var phases = map[string]pb.Phase{
+	textopdfa.STAGE_COMPILE:  pb.Phase_PHASE_COMPILING,
+	textopdfa.STAGE_PDFA1:    pb.Phase_PHASE_CONVERTING_PDFA1,
+	textopdfa.STAGE_PDFA:     pb.Phase_PHASE_CONVERTING_PDFA,
+	textopdfa.STAGE_VALIDATE: pb.Phase_PHASE_VALIDATING,
+}
+
+// progress converts the progress of a compilation into CompileEvents, it is safe for concurrent use
+// The methods of a nil progress report nothing
+type progress struct {
+	mu     sync.Mutex
+	send   func(*pb.CompileEvent) error
+	logger zerolog.Logger
+	job_id string
+	phase  pb.Phase
+	pass   int
+	failed bool // sending failed, the client is gone
+}
+
+func newProgress(send func(*pb.CompileEvent) error, logger zerolog.Logger) *progress {
+	return &progress{send: send, logger: logger}
+}
+
+// sendEvent sends an event unless sending failed before, the caller must hold p.mu
+func (p *progress) sendEvent(event *pb.CompileEvent) {
+
+	if p.failed {
+		return
+	}
+
+	if err := p.send(event); err != nil {
+		p.logger.Debug().Err(err).Msg("Could not send progress, stopping to report it")
+		p.failed = true
+	}
+}
+
+// enter reports a phase, unless it is the current one, the caller must hold p.mu
+func (p *progress) enter(phase pb.Phase, pass int, command string) {
+
+	if phase == p.phase && pass == p.pass {
+		return
+	}
+
+	p.phase, p.pass = phase, pass
+
+	p.sendEvent(&pb.CompileEvent{Event: &pb.CompileEvent_Progress{Progress: &pb.Progress{
+		Phase:   phase,
+		Pass:    int32(pass),
+		Command: command,
+		JobId:   p.job_id,
+	}}})
+}
+
+// report reports a progress event of the pipeline (see textopdfa.WithProgress)
+func (p *progress) report(event textopdfa.ProgressEvent) {
+
+	phase, ok := phases[event.Stage]
+
+	if p == nil || !ok {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if event.Line == "" {
+		p.enter(phase, event.Pass, event.Command)
+		return
+	}
+
+	p.sendEvent(&pb.CompileEvent{Event: &pb.CompileEvent_LogLine{LogLine: &pb.LogLine{
+		Phase:  phase,
+		Line:   event.Line,
+		Stderr: event.Stderr,
+	}}})
+}
+
+// jobEvent reports an event of the job compiling the request (see restserver.Server.CompileJob)
+func (p *progress) jobEvent(event restserver.JobEvent) {
+
+	if p == nil {
+		return
+	}
+
+	if event.Progress != nil {
+		p.report(*event.Progress)
+		return
+	}
+
+	if event.Status == restserver.JOBSTATUS_CREATED {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		p.job_id = event.JobID
+		p.enter(pb.Phase_PHASE_QUEUED, 1, "")
+	}
+}
+
+// done reports the end of the compilation
+func (p *progress) done() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.enter(pb.Phase_PHASE_DONE, 1, "")
+}
+
+// CompileStream is the implementation of the gRPC method CompileStream
+// It compiles like CompileToPDF, reporting the phases and the output of the commands, and sends the result followed by the PDF in chunks
+func (s *server) CompileStream(req *pb.CompileRequest, stream pb.TexCompiler_CompileStreamServer) error {
+
+	ctx, logger, err := newRequest(stream.Context())
+
+	if err != nil {
+		return err
+	}
+
+	logger.Info().Int("files", len(req.GetFiles())).Msg("Got request to compile TeX to PDF/A with progress")
+
+	texfile, err := validateRequest(req)
+
+	if err != nil {
+		return err
+	}
+
+	p := newProgress(stream.Send, logger)
+
+	reply, err := s.compile(ctx, req, texfile, logger, p)
+
+	if err != nil {
+		return err
+	}
+
+	// the compilation is over, nothing reports concurrently anymore
+	p.done()
+
+	if p.failed {
+		return status.Error(codes.Unavailable, "could not send progress")
+	}
+
+	pdfContent := reply.PdfContent
+	reply.PdfContent = nil
+
+	if err := stream.Send(&pb.CompileEvent{Event: &pb.CompileEvent_Result{Result: reply}}); err != nil {
+		return err
+	}
+
+	for offset := 0; offset < len(pdfContent); offset += PDF_CHUNK_SIZE {
+		chunk := pdfContent[offset:min(offset+PDF_CHUNK_SIZE, len(pdfContent))]
+
+		if err := stream.Send(&pb.CompileEvent{Event: &pb.CompileEvent_PdfChunk{PdfChunk: chunk}}); err != nil {
+			return err
+		}
+	}
+
+	logger.Debug().Int("bytes", len(pdfContent)).Msg("Sent PDF")
+
+	return nil
+}
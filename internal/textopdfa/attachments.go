@@ -0,0 +1,189 @@
+package textopdfa
+
+import (
+	"fmt"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// AFRelationship values of embedded files allowed by PDF/A-3
+const (
+	AF_SOURCE               = "Source"
+	AF_DATA                 = "Data"
+	AF_ALTERNATIVE          = "Alternative"
+	AF_SUPPLEMENT           = "Supplement"
+	AF_UNSPECIFIED          = "Unspecified"
+	DEFAULT_AF_RELATIONSHIP = AF_UNSPECIFIED
+	DEFAULT_MIME_TYPE       = "application/octet-stream"
+)
+
+const (
+	// FACTURX_FILENAME is the name the XML invoice must be embedded with
+	FACTURX_FILENAME  = "factur-x.xml"
+	FACTURX_NAMESPACE = "urn:factur-x:pdfa:CrossIndustryDocument:invoice:1p0#"
+	FACTURX_VERSION   = "1.0"
+)
+
+// afRelationships are the supported AFRelationship values
+var afRelationships = []string{AF_SOURCE, AF_DATA, AF_ALTERNATIVE, AF_SUPPLEMENT, AF_UNSPECIFIED}
+
+// facturxProfiles maps the accepted Factur-X profile names to the conformance level written to the XMP metadata
+var facturxProfiles = map[string]string{
+	"MINIMUM":  "MINIMUM",
+	"BASIC WL": "BASIC WL",
+	"BASIC":    "BASIC",
+	"EN16931":  "EN 16931",
+	"EN 16931": "EN 16931",
+	"EXTENDED": "EXTENDED",
+}
+
+// Attachment is a file embedded into the PDF/A-3 file
+type Attachment struct {
+	// File is the path of the file, relative to the directory of the main TeX file
+	File string `json:"file"`
+
+	// Name is the name of the embedded file, defaults to the base name of File
+	Name string `json:"name,omitempty"`
+
+	// MIMEType is the MIME type of the file (e.g. "text/xml"), defaults to the type of the file extension
+	MIMEType string `json:"mime_type,omitempty"`
+
+	// Relationship is the AFRelationship of the file to the document (Source, Data, Alternative, Supplement or Unspecified)
+	// It defaults to Data for the Factur-X invoice and to Unspecified otherwise
+	Relationship string `json:"relationship,omitempty"`
+
+	// Description is an optional description of the file shown by PDF viewers
+	Description string `json:"description,omitempty"`
+}
+
+// withDefaults returns a copy of the attachment with all unset values replaced by their defaults
+func (a Attachment) withDefaults(facturx bool) Attachment {
+
+	if a.Name == "" {
+		a.Name = path.Base(filepath.ToSlash(a.File))
+	}
+
+	if a.MIMEType == "" {
+		a.MIMEType = DEFAULT_MIME_TYPE
+
+		if mediatype, _, err := mime.ParseMediaType(mime.TypeByExtension(path.Ext(a.Name))); err == nil {
+			a.MIMEType = mediatype
+		}
+	}
+
+	if a.Relationship == "" {
+		a.Relationship = DEFAULT_AF_RELATIONSHIP
+
+		if facturx && a.Name == FACTURX_FILENAME {
+			a.Relationship = AF_DATA
+		}
+	}
+
+	return a
+}
+
+// validate checks an attachment with defaults applied
+func (a Attachment) validate() error {
+
+	if a.File == "" || !filepath.IsLocal(filepath.FromSlash(a.File)) {
+		return fmt.Errorf("invalid attachment file '%s', expected a path relative to the main TeX file", a.File)
+	}
+
+	if strings.ContainsAny(a.Name, "/\\") {
+		return fmt.Errorf("invalid attachment name '%s', expected a file name without directories", a.Name)
+	}
+
+	if _, _, err := mime.ParseMediaType(a.MIMEType); err != nil || !strings.Contains(a.MIMEType, "/") {
+		return fmt.Errorf("invalid MIME type '%s' of attachment '%s'", a.MIMEType, a.Name)
+	}
+
+	if !slices.Contains(afRelationships, a.Relationship) {
+		return fmt.Errorf("invalid relationship '%s' of attachment '%s', expected one of %v", a.Relationship, a.Name, afRelationships)
+	}
+
+	return nil
+}
+
+// FacturXProfileNames returns the accepted names of Factur-X profiles, sorted
+func FacturXProfileNames() []string {
+	names := make([]string, 0, len(facturxProfiles))
+
+	for name := range facturxProfiles {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	return names
+}
+
+// facturxLevel returns the XMP conformance level of a Factur-X profile name (case insensitive)
+func facturxLevel(profile string) (string, bool) {
+	level, ok := facturxProfiles[strings.ToUpper(strings.TrimSpace(profile))]
+	return level, ok
+}
+
+// addAttachments adds pdfmarks embedding the attachments as associated files of the document
+// dir is the directory the attachment files are relative to
+func (p *pdfmarks) addAttachments(attachments []Attachment, dir string) error {
+
+	if len(attachments) == 0 {
+		return nil
+	}
+
+	af := p.object("af")
+	p.add("[ /_objdef %s /type /array /OBJ pdfmark", af)
+
+	for _, attachment := range attachments {
+		file := filepath.Join(dir, filepath.FromSlash(attachment.File))
+		info, err := os.Stat(file)
+
+		if err != nil || !info.Mode().IsRegular() {
+			return fmt.Errorf("attachment '%s' not found", attachment.File)
+		}
+
+		p.files = append(p.files, file)
+
+		stream := p.object("attachment")
+		filespec := p.object("filespec")
+
+		p.add("[ /_objdef %s /type /stream /OBJ pdfmark", stream)
+		p.add("[ %s << /Type /EmbeddedFile /Subtype %s /Params << /ModDate %s /Size %d >> >> /PUT pdfmark",
+			stream, psName(attachment.MIMEType), psString("D:"+info.ModTime().UTC().Format("20060102150405")+"Z"), info.Size())
+		p.add("[ %s %s (r) file /PUT pdfmark", stream, psString(file))
+		p.add("[ %s /CLOSE pdfmark", stream)
+
+		p.add("[ /_objdef %s /type /dict /OBJ pdfmark", filespec)
+		p.add("[ %s << /Type /Filespec /F %s /UF %s /Desc %s /AFRelationship /%s /EF << /F %s /UF %s >> >> /PUT pdfmark",
+			filespec, psString(attachment.Name), psTextString(attachment.Name), psTextString(attachment.Description),
+			attachment.Relationship, stream, stream)
+		p.add("[ %s %s /APPEND pdfmark", af, filespec)
+		p.add("[ /Name %s /FS %s /EMBED pdfmark", psString(attachment.Name), filespec)
+	}
+
+	p.add("[ {Catalog} << /AF %s >> /PUT pdfmark", af)
+
+	return nil
+}
+
+// addFacturX adds the Factur-X extension schema and properties to the XMP metadata
+func (p *pdfmarks) addFacturX(level string) {
+
+	p.addXMPSchema("Factur-X PDFA Extension Schema", FACTURX_NAMESPACE, "fx", []xmpPropertyDescription{
+		{"DocumentFileName", "The name of the embedded XML document"},
+		{"DocumentType", "The type of the hybrid document in capital letters, e.g. INVOICE or ORDER"},
+		{"Version", "The actual version of the standard applying to the embedded XML document"},
+		{"ConformanceLevel", "The conformance level of the embedded XML document"},
+	})
+
+	p.addXMP(`<rdf:Description rdf:about="" xmlns:fx="` + FACTURX_NAMESPACE + `">` +
+		`<fx:DocumentType>INVOICE</fx:DocumentType>` +
+		`<fx:DocumentFileName>` + FACTURX_FILENAME + `</fx:DocumentFileName>` +
+		`<fx:Version>` + FACTURX_VERSION + `</fx:Version>` +
+		`<fx:ConformanceLevel>` + level + `</fx:ConformanceLevel>` +
+		`</rdf:Description>`)
+}
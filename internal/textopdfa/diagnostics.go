@@ -0,0 +1,400 @@
+package textopdfa
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	SEVERITY_ERROR   = "error"
+	SEVERITY_WARNING = "warning"
+
+	DIAGNOSTIC_FATAL               = "fatal"               // TeX stopped, no PDF was produced
+	DIAGNOSTIC_ERROR               = "error"               // any other error, e.g. an undefined control sequence
+	DIAGNOSTIC_MISSING_PACKAGE     = "missing_package"     // a package or class file could not be found
+	DIAGNOSTIC_UNDEFINED_REFERENCE = "undefined_reference" // a \ref or \cite to an undefined label or citation
+	DIAGNOSTIC_OVERFULL_BOX        = "overfull_box"
+	DIAGNOSTIC_UNDERFULL_BOX       = "underfull_box"
+	DIAGNOSTIC_WARNING             = "warning" // any other warning
+
+	// TEX_LOG_LINE_LENGTH is the length TeX wraps lines of its log at (max_print_line)
+	TEX_LOG_LINE_LENGTH = 79
+
+	// MAX_ERROR_CONTEXT_LINES is the maximum number of lines following an error that are searched for its line number
+	MAX_ERROR_CONTEXT_LINES = 20
+)
+
+// Diagnostic is an error or warning found in the output of a TeX engine
+type Diagnostic struct {
+	Severity string `json:"severity"`          // one of the SEVERITY_* constants
+	Kind     string `json:"kind"`              // one of the DIAGNOSTIC_* constants
+	Message  string `json:"message"`           // message as written by TeX, continuation lines joined
+	File     string `json:"file,omitempty"`    // file the diagnostic refers to, relative to the directory of the main TeX file if inside it
+	Line     int    `json:"line,omitempty"`    // line in File, 0 if unknown
+	Package  string `json:"package,omitempty"` // package or class that raised the diagnostic, empty for TeX and LaTeX itself
+}
+
+var (
+	patternFileLineError = regexp.MustCompile(`^(\S+\.\w+):(\d+): (.+)$`)
+	patternErrorLine     = regexp.MustCompile(`^l\.(\d+)`)
+	patternMissingFile   = regexp.MustCompile("^LaTeX Error: File `([^']+)\\.(sty|cls)' not found")
+	patternPackageError  = regexp.MustCompile(`^(?:Package|Class) (\S+) Error: (.*)$`)
+	patternWarning       = regexp.MustCompile(`^(?:(?:Package|Class) (\S+)|LaTeX|LaTeX Font|pdfTeX) [Ww]arning:? ?(.*)$`)
+	patternBox           = regexp.MustCompile(`^(Overfull|Underfull) \\[hv]box .*?(?:lines? (\d+)(?:--\d+)?)?$`)
+	patternContinuation  = regexp.MustCompile(`^\(([\w.-]+)\)\s+(.*)$`)
+	patternInputLine     = regexp.MustCompile(`on input line (\d+)`)
+	patternUndefinedRef  = regexp.MustCompile(`(?:Reference|Citation) .* undefined|There were undefined (?:references|citations)`)
+	patternFatal         = regexp.MustCompile(`^(?:Emergency stop|TeX capacity exceeded|I can't find file|==> Fatal error occurred|\*\*\* \(job aborted)`)
+	patternFileName      = regexp.MustCompile(`^"?(\.{0,2}/[^\s()"]*|[\w~-][^\s()"]*\.[A-Za-z][\w]*)`)
+)
+
+// ParseDiagnostics extracts errors and warnings from the log or output of a TeX engine (including rubber)
+// Files are reported as written in the log, see diagnose for normalized file names
+func ParseDiagnostics(log string) []Diagnostic {
+	parser := &diagnosticParser{lines: unwrapLog(log)}
+	parser.parse()
+
+	return parser.diagnostics
+}
+
+// unwrapLog splits a log into lines and joins lines TeX has wrapped at TEX_LOG_LINE_LENGTH
+func unwrapLog(log string) []string {
+	var lines []string
+	var current strings.Builder
+
+	for _, line := range strings.Split(strings.ReplaceAll(log, "\r\n", "\n"), "\n") {
+		current.WriteString(line)
+
+		if len(line) == TEX_LOG_LINE_LENGTH {
+			continue
+		}
+
+		lines = append(lines, current.String())
+		current.Reset()
+	}
+
+	if current.Len() > 0 {
+		lines = append(lines, current.String())
+	}
+
+	return lines
+}
+
+// diagnosticParser walks through a log line by line, keeping track of the file TeX is reading
+type diagnosticParser struct {
+	lines       []string
+	pos         int
+	files       []string // TeX prints "(file" when opening and ")" when closing a file, non-file parentheses are stacked as ""
+	diagnostics []Diagnostic
+}
+
+// currentFile returns the innermost file TeX is reading, empty if unknown
+func (p *diagnosticParser) currentFile() string {
+
+	for i := len(p.files) - 1; i >= 0; i-- {
+		if p.files[i] != "" {
+			return p.files[i]
+		}
+	}
+
+	return ""
+}
+
+// trackFiles updates the stack of open files from the parentheses in line
+func (p *diagnosticParser) trackFiles(line string) {
+
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '(':
+			name := patternFileName.FindStringSubmatch(line[i+1:])
+
+			if name == nil {
+				p.files = append(p.files, "")
+				continue
+			}
+
+			p.files = append(p.files, name[1])
+			i += len(name[0])
+
+		case ')':
+			if len(p.files) > 0 {
+				p.files = p.files[:len(p.files)-1]
+			}
+		}
+	}
+}
+
+// next returns the next line and advances, ok is false at the end of the log
+func (p *diagnosticParser) next() (line string, ok bool) {
+
+	if p.pos >= len(p.lines) {
+		return "", false
+	}
+
+	line = p.lines[p.pos]
+	p.pos++
+
+	return line, true
+}
+
+// continuation appends the continuation lines of a message, e.g. "(hyperref)    the rest of the message"
+func (p *diagnosticParser) continuation(message string) string {
+
+	for p.pos < len(p.lines) {
+		match := patternContinuation.FindStringSubmatch(p.lines[p.pos])
+
+		if match == nil {
+			break
+		}
+
+		message += " " + match[2]
+		p.pos++
+	}
+
+	return message
+}
+
+// isDiagnostic reports whether line starts a diagnostic
+func isDiagnostic(line string) bool {
+	return strings.HasPrefix(line, "! ") ||
+		patternFileLineError.MatchString(line) ||
+		patternBox.MatchString(line) ||
+		patternWarning.MatchString(line) ||
+		patternFatal.MatchString(line)
+}
+
+func (p *diagnosticParser) parse() {
+
+	for {
+		line, ok := p.next()
+
+		if !ok {
+			return
+		}
+
+		switch {
+		case strings.HasPrefix(line, "! "):
+			p.parseError(strings.TrimPrefix(line, "! "))
+
+		case patternFileLineError.MatchString(line):
+			match := patternFileLineError.FindStringSubmatch(line)
+			number, _ := strconv.Atoi(match[2])
+			p.parseFileLineError(match[1], number, match[3])
+
+		case patternBox.MatchString(line):
+			p.parseBox(line)
+
+		case patternWarning.MatchString(line):
+			match := patternWarning.FindStringSubmatch(line)
+			p.parseWarning(match[1], p.continuation(match[2]))
+
+		case patternFatal.MatchString(line):
+			p.diagnostics = append(p.diagnostics, Diagnostic{
+				Severity: SEVERITY_ERROR,
+				Kind:     DIAGNOSTIC_FATAL,
+				Message:  line,
+				File:     p.currentFile(),
+			})
+
+		default:
+			p.trackFiles(line)
+		}
+	}
+}
+
+// classifyError returns the diagnostic for an error message (without the leading "! ")
+func classifyError(message string) Diagnostic {
+
+	diagnostic := Diagnostic{Severity: SEVERITY_ERROR, Kind: DIAGNOSTIC_ERROR, Message: message}
+
+	if match := patternMissingFile.FindStringSubmatch(message); match != nil {
+		diagnostic.Kind = DIAGNOSTIC_MISSING_PACKAGE
+		diagnostic.Package = match[1]
+		return diagnostic
+	}
+
+	if match := patternPackageError.FindStringSubmatch(message); match != nil {
+		diagnostic.Package = match[1]
+	}
+
+	if patternFatal.MatchString(message) {
+		diagnostic.Kind = DIAGNOSTIC_FATAL
+	}
+
+	return diagnostic
+}
+
+// parseError handles a TeX error ("! message") followed by context lines with the line number ("l.42 ...") and help text
+// The context lines contain the user's text, so they must not be used for tracking files
+func (p *diagnosticParser) parseError(message string) {
+
+	diagnostic := classifyError(p.continuation(message))
+	diagnostic.File = p.currentFile()
+
+	for i := 0; i < MAX_ERROR_CONTEXT_LINES && p.pos < len(p.lines); i++ {
+		line := p.lines[p.pos]
+
+		// the next diagnostic started before a line number was found
+		if isDiagnostic(line) {
+			break
+		}
+
+		p.pos++
+
+		if match := patternErrorLine.FindStringSubmatch(line); match != nil {
+			diagnostic.Line, _ = strconv.Atoi(match[1])
+			break
+		}
+	}
+
+	// skip the rest of the context and the help text up to the next empty line
+	for i := 0; i < MAX_ERROR_CONTEXT_LINES && p.pos < len(p.lines) && diagnostic.Line > 0; i++ {
+		if p.lines[p.pos] == "" || isDiagnostic(p.lines[p.pos]) {
+			break
+		}
+
+		p.pos++
+	}
+
+	p.diagnostics = append(p.diagnostics, diagnostic)
+}
+
+// parseFileLineError handles messages in the "file:line: message" format of rubber and -file-line-error
+// rubber uses it for warnings as well, so references and boxes are recognized by their message
+func (p *diagnosticParser) parseFileLineError(file string, line int, message string) {
+
+	var diagnostic Diagnostic
+
+	switch {
+	case patternUndefinedRef.MatchString(message):
+		diagnostic = Diagnostic{Severity: SEVERITY_WARNING, Kind: DIAGNOSTIC_UNDEFINED_REFERENCE, Message: message}
+	case strings.HasPrefix(message, "Overfull"):
+		diagnostic = Diagnostic{Severity: SEVERITY_WARNING, Kind: DIAGNOSTIC_OVERFULL_BOX, Message: message}
+	case strings.HasPrefix(message, "Underfull"):
+		diagnostic = Diagnostic{Severity: SEVERITY_WARNING, Kind: DIAGNOSTIC_UNDERFULL_BOX, Message: message}
+	default:
+		diagnostic = classifyError(p.continuation(message))
+	}
+
+	diagnostic.File = file
+	diagnostic.Line = line
+
+	// skip the context lines of the error, they are printed the same way as without -file-line-error
+	if diagnostic.Severity == SEVERITY_ERROR {
+		for i := 0; i < MAX_ERROR_CONTEXT_LINES && p.pos < len(p.lines); i++ {
+			if patternErrorLine.MatchString(p.lines[p.pos]) {
+				p.pos++
+				break
+			}
+
+			if p.lines[p.pos] == "" || isDiagnostic(p.lines[p.pos]) {
+				break
+			}
+
+			p.pos++
+		}
+	}
+
+	p.diagnostics = append(p.diagnostics, diagnostic)
+}
+
+// parseBox handles over- and underfull box warnings
+// They are followed by the content of the box (up to an empty line), which must not be used for tracking files
+func (p *diagnosticParser) parseBox(line string) {
+
+	match := patternBox.FindStringSubmatch(line)
+
+	diagnostic := Diagnostic{
+		Severity: SEVERITY_WARNING,
+		Kind:     DIAGNOSTIC_OVERFULL_BOX,
+		Message:  line,
+		File:     p.currentFile(),
+	}
+
+	if match[1] == "Underfull" {
+		diagnostic.Kind = DIAGNOSTIC_UNDERFULL_BOX
+	}
+
+	if match[2] != "" {
+		diagnostic.Line, _ = strconv.Atoi(match[2])
+	}
+
+	for p.pos < len(p.lines) && p.lines[p.pos] != "" {
+		p.pos++
+	}
+
+	p.diagnostics = append(p.diagnostics, diagnostic)
+}
+
+// parseWarning handles LaTeX, package and class warnings
+func (p *diagnosticParser) parseWarning(pkg string, message string) {
+
+	diagnostic := Diagnostic{
+		Severity: SEVERITY_WARNING,
+		Kind:     DIAGNOSTIC_WARNING,
+		Message:  message,
+		File:     p.currentFile(),
+		Package:  pkg,
+	}
+
+	if patternUndefinedRef.MatchString(message) {
+		diagnostic.Kind = DIAGNOSTIC_UNDEFINED_REFERENCE
+	}
+
+	if match := patternInputLine.FindStringSubmatch(message); match != nil {
+		diagnostic.Line, _ = strconv.Atoi(match[1])
+	}
+
+	p.diagnostics = append(p.diagnostics, diagnostic)
+}
+
+// diagnose parses the diagnostics of a compilation
+// The LaTeX log is preferred as it is the most complete, the output of the compile stage is used if there is no log
+// File names are made relative to maindir if they are inside it
+func diagnose(texlog string, stages []StageLog, maindir string, builddir string) []Diagnostic {
+
+	var output string
+
+	if content, err := os.ReadFile(texlog); err == nil {
+		output = string(content)
+	} else {
+		for _, stage := range stages {
+			if stage.Stage == STAGE_COMPILE {
+				output += stage.Stdout + stage.Stderr
+			}
+		}
+	}
+
+	diagnostics := ParseDiagnostics(output)
+
+	for i := range diagnostics {
+		diagnostics[i].File = relativeFile(diagnostics[i].File, maindir, builddir)
+	}
+
+	return diagnostics
+}
+
+// relativeFile returns the name of file relative to the first of dirs it is inside, cleaned up otherwise
+func relativeFile(file string, dirs ...string) string {
+
+	if file == "" {
+		return ""
+	}
+
+	file = filepath.Clean(file)
+
+	if !filepath.IsAbs(file) {
+		return filepath.ToSlash(file)
+	}
+
+	for _, dir := range dirs {
+		if rel, err := filepath.Rel(dir, file); err == nil && filepath.IsLocal(rel) {
+			return filepath.ToSlash(rel)
+		}
+	}
+
+	return file
+}
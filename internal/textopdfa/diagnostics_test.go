@@ -0,0 +1,369 @@
+package textopdfa
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// wrapLog wraps the lines of a log at TEX_LOG_LINE_LENGTH, as TeX does
+func wrapLog(log string) string {
+	var lines []string
+
+	for _, line := range strings.Split(log, "\n") {
+		for len(line) > TEX_LOG_LINE_LENGTH {
+			lines = append(lines, line[:TEX_LOG_LINE_LENGTH])
+			line = line[TEX_LOG_LINE_LENGTH:]
+		}
+
+		lines = append(lines, line)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func TestParseDiagnostics(t *testing.T) {
+
+	tests := []struct {
+		name string
+		log  string
+		want []Diagnostic
+	}{
+		{
+			name: "clean log",
+			log: `This is pdfTeX, Version 3.141592653-2.6-1.40.25 (TeX Live 2023/Debian) (preloaded format=pdflatex 2024.1.10)  10 JAN 2024 12:00
+entering extended mode
+(./main.tex
+LaTeX2e <2023-11-01> patch level 1
+(/usr/share/texlive/texmf-dist/tex/latex/base/article.cls
+Document Class: article 2023/05/17 v1.4n Standard LaTeX document class
+(/usr/share/texlive/texmf-dist/tex/latex/base/size10.clo))
+(./main.aux) [1{/var/lib/texmf/fonts/map/pdftex/updmap/pdftex.map}] (./main.aux) )
+Output written on main.pdf (1 page, 12345 bytes).
+`,
+			want: nil,
+		},
+		{
+			name: "undefined reference and rerun summary",
+			log: `(./main.tex
+LaTeX2e <2023-11-01> patch level 1
+
+LaTeX Warning: Reference ` + "`fig:missing'" + ` on page 1 undefined on input line 12.
+
+[1{/var/lib/texmf/fonts/map/pdftex/updmap/pdftex.map}] (./main.aux)
+
+LaTeX Warning: There were undefined references.
+
+ )
+`,
+			want: []Diagnostic{
+				{Severity: SEVERITY_WARNING, Kind: DIAGNOSTIC_UNDEFINED_REFERENCE, Message: "Reference `fig:missing' on page 1 undefined on input line 12.", File: "./main.tex", Line: 12},
+				{Severity: SEVERITY_WARNING, Kind: DIAGNOSTIC_UNDEFINED_REFERENCE, Message: "There were undefined references.", File: "./main.tex"},
+			},
+		},
+		{
+			name: "undefined citation of a package",
+			log: `(./main.tex
+(/usr/share/texlive/texmf-dist/tex/latex/natbib/natbib.sty)
+
+Package natbib Warning: Citation ` + "`knuth84'" + ` on page 1 undefined on input line 7.
+
+
+LaTeX Warning: There were undefined citations.
+
+)
+`,
+			want: []Diagnostic{
+				{Severity: SEVERITY_WARNING, Kind: DIAGNOSTIC_UNDEFINED_REFERENCE, Message: "Citation `knuth84' on page 1 undefined on input line 7.", File: "./main.tex", Line: 7, Package: "natbib"},
+				{Severity: SEVERITY_WARNING, Kind: DIAGNOSTIC_UNDEFINED_REFERENCE, Message: "There were undefined citations.", File: "./main.tex"},
+			},
+		},
+		{
+			name: "package warning with continuation lines",
+			log: `(./main.tex
+
+Package hyperref Warning: Token not allowed in a PDF string (Unicode):
+(hyperref)                removing ` + "`math shift'" + ` on input line 5.
+
+)
+`,
+			want: []Diagnostic{
+				{Severity: SEVERITY_WARNING, Kind: DIAGNOSTIC_WARNING, Message: "Token not allowed in a PDF string (Unicode): removing `math shift' on input line 5.", File: "./main.tex", Line: 5, Package: "hyperref"},
+			},
+		},
+		{
+			name: "overfull and underfull boxes",
+			log: `(./main.tex
+Overfull \hbox (15.27pt too wide) in paragraph at lines 10--12
+[]\T1/cmr/m/n/10 Some text (with an unbalanced parenthesis and (./fake.tex in it|
+ []
+
+
+Underfull \hbox (badness 10000) in paragraph at lines 20--20
+
+ []
+
+
+Overfull \hbox (3.0pt too wide) detected at line 33
+[][]
+ []
+
+
+Underfull \vbox (badness 10000) has occurred while \output is active []
+
+ [1]
+
+LaTeX Warning: Label(s) may have changed. Rerun to get cross-references right.
+
+)
+`,
+			want: []Diagnostic{
+				{Severity: SEVERITY_WARNING, Kind: DIAGNOSTIC_OVERFULL_BOX, Message: `Overfull \hbox (15.27pt too wide) in paragraph at lines 10--12`, File: "./main.tex", Line: 10},
+				{Severity: SEVERITY_WARNING, Kind: DIAGNOSTIC_UNDERFULL_BOX, Message: `Underfull \hbox (badness 10000) in paragraph at lines 20--20`, File: "./main.tex", Line: 20},
+				{Severity: SEVERITY_WARNING, Kind: DIAGNOSTIC_OVERFULL_BOX, Message: `Overfull \hbox (3.0pt too wide) detected at line 33`, File: "./main.tex", Line: 33},
+				{Severity: SEVERITY_WARNING, Kind: DIAGNOSTIC_UNDERFULL_BOX, Message: `Underfull \vbox (badness 10000) has occurred while \output is active []`, File: "./main.tex"},
+				{Severity: SEVERITY_WARNING, Kind: DIAGNOSTIC_WARNING, Message: "Label(s) may have changed. Rerun to get cross-references right.", File: "./main.tex"},
+			},
+		},
+		{
+			name: "missing package",
+			log: `(./main.tex
+LaTeX2e <2023-11-01> patch level 1
+(/usr/share/texlive/texmf-dist/tex/latex/base/article.cls
+Document Class: article 2023/05/17 v1.4n Standard LaTeX document class
+(/usr/share/texlive/texmf-dist/tex/latex/base/size10.clo))
+
+! LaTeX Error: File ` + "`foobar.sty'" + ` not found.
+
+Type X to quit or <RETURN> to proceed,
+or enter new name. (Default extension: sty)
+
+Enter file name:
+! Emergency stop.
+<read *>
+
+l.3 \usepackage
+               {graphicx}^^M
+*** (cannot \read from terminal in nonstop modes)
+`,
+			want: []Diagnostic{
+				{Severity: SEVERITY_ERROR, Kind: DIAGNOSTIC_MISSING_PACKAGE, Message: "LaTeX Error: File `foobar.sty' not found.", File: "./main.tex", Package: "foobar"},
+				{Severity: SEVERITY_ERROR, Kind: DIAGNOSTIC_FATAL, Message: "Emergency stop.", File: "./main.tex", Line: 3},
+			},
+		},
+		{
+			name: "missing class",
+			log: `(./main.tex
+LaTeX2e <2023-11-01> patch level 1
+
+! LaTeX Error: File ` + "`fancyclass.cls'" + ` not found.
+
+Type X to quit or <RETURN> to proceed,
+or enter new name. (Default extension: cls)
+
+Enter file name:
+! Emergency stop.
+<read *>
+
+l.1 \documentclass{fancyclass}
+                              ^^M
+*** (cannot \read from terminal in nonstop modes)
+`,
+			want: []Diagnostic{
+				{Severity: SEVERITY_ERROR, Kind: DIAGNOSTIC_MISSING_PACKAGE, Message: "LaTeX Error: File `fancyclass.cls' not found.", File: "./main.tex", Package: "fancyclass"},
+				{Severity: SEVERITY_ERROR, Kind: DIAGNOSTIC_FATAL, Message: "Emergency stop.", File: "./main.tex", Line: 1},
+			},
+		},
+		{
+			name: "error with line number and help text",
+			log: `(./main.tex
+! Undefined control sequence.
+l.5 \foo
+
+The control sequence at the end of the top line
+of your error message was never \def'ed. If you have
+misspelled it (e.g., ` + "`\\hobx')" + `, type ` + "`I'" + ` and the correct
+spelling (e.g., ` + "`I\\hbox')" + `. Otherwise just continue,
+and I'll forget about whatever was undefined.
+
+[1{/var/lib/texmf/fonts/map/pdftex/updmap/pdftex.map}] (./main.aux) )
+`,
+			want: []Diagnostic{
+				{Severity: SEVERITY_ERROR, Kind: DIAGNOSTIC_ERROR, Message: "Undefined control sequence.", File: "./main.tex", Line: 5},
+			},
+		},
+		{
+			name: "package error with continuation lines",
+			log: `(./main.tex
+! Package inputenc Error: Unicode character ä (U+E4)
+(inputenc)                not set up for use with LaTeX.
+
+See the inputenc package documentation for explanation.
+Type  H <return>  for immediate help.
+ ...
+
+l.8 Grüße
+
+You may provide a definition with
+\DeclareUnicodeCharacter
+
+)
+`,
+			want: []Diagnostic{
+				{Severity: SEVERITY_ERROR, Kind: DIAGNOSTIC_ERROR, Message: "Package inputenc Error: Unicode character ä (U+E4) not set up for use with LaTeX.", File: "./main.tex", Line: 8, Package: "inputenc"},
+			},
+		},
+		{
+			name: "fatal errors",
+			log: `(./main.tex
+! TeX capacity exceeded, sorry [input stack size=10000].
+\foo ->\foo
+
+l.4 \foo
+
+If you really absolutely need more capacity,
+you can ask a wizard to enlarge me.
+
+
+! ==> Fatal error occurred, no output PDF file produced!
+`,
+			want: []Diagnostic{
+				{Severity: SEVERITY_ERROR, Kind: DIAGNOSTIC_FATAL, Message: "TeX capacity exceeded, sorry [input stack size=10000].", File: "./main.tex", Line: 4},
+				{Severity: SEVERITY_ERROR, Kind: DIAGNOSTIC_FATAL, Message: "==> Fatal error occurred, no output PDF file produced!", File: "./main.tex"},
+			},
+		},
+		{
+			name: "missing end of document",
+			log: `(./main.tex [1{/var/lib/texmf/fonts/map/pdftex/updmap/pdftex.map}] )
+! Emergency stop.
+<*> main.tex
+
+*** (job aborted, no legal \end found)
+
+`,
+			want: []Diagnostic{
+				{Severity: SEVERITY_ERROR, Kind: DIAGNOSTIC_FATAL, Message: "Emergency stop."},
+				{Severity: SEVERITY_ERROR, Kind: DIAGNOSTIC_FATAL, Message: `*** (job aborted, no legal \end found)`},
+			},
+		},
+		{
+			name: "nested files",
+			log: `This is pdfTeX, Version 3.141592653-2.6-1.40.25 (TeX Live 2023/Debian) (preloaded format=pdflatex)
+(./main.tex
+LaTeX2e <2023-11-01> patch level 1
+(/usr/share/texlive/texmf-dist/tex/latex/base/article.cls
+Document Class: article 2023/05/17 v1.4n Standard LaTeX document class
+(/usr/share/texlive/texmf-dist/tex/latex/base/size10.clo))
+(./chapters/intro.tex
+
+LaTeX Warning: Citation ` + "`x'" + ` on page 1 undefined on input line 3.
+
+) (./chapters/outro.tex
+! Undefined control sequence.
+l.2 \bar
+
+The control sequence at the end of the top line
+of your error message was never \def'ed.
+
+) (/usr/share/texlive/texmf-dist/tex/latex/base/ts1cmr.fd)
+
+LaTeX Warning: Label(s) may have changed. Rerun to get cross-references right.
+
+ )
+`,
+			want: []Diagnostic{
+				{Severity: SEVERITY_WARNING, Kind: DIAGNOSTIC_UNDEFINED_REFERENCE, Message: "Citation `x' on page 1 undefined on input line 3.", File: "./chapters/intro.tex", Line: 3},
+				{Severity: SEVERITY_ERROR, Kind: DIAGNOSTIC_ERROR, Message: "Undefined control sequence.", File: "./chapters/outro.tex", Line: 2},
+				{Severity: SEVERITY_WARNING, Kind: DIAGNOSTIC_WARNING, Message: "Label(s) may have changed. Rerun to get cross-references right.", File: "./main.tex"},
+			},
+		},
+		{
+			name: "wrapped lines",
+			log: wrapLog(`(./main.tex (/usr/share/texlive/texmf-dist/tex/latex/some-very-long-package-name/some-very-long-package-name.sty
+Package some-very-long-package-name Warning: This warning is much longer than the seventy-nine characters TeX writes per line on input line 9.
+)
+LaTeX Warning: Float too large for page by 12.0pt on input line 14.
+)
+`),
+			want: []Diagnostic{
+				{Severity: SEVERITY_WARNING, Kind: DIAGNOSTIC_WARNING, Message: "This warning is much longer than the seventy-nine characters TeX writes per line on input line 9.", File: "/usr/share/texlive/texmf-dist/tex/latex/some-very-long-package-name/some-very-long-package-name.sty", Line: 9, Package: "some-very-long-package-name"},
+				{Severity: SEVERITY_WARNING, Kind: DIAGNOSTIC_WARNING, Message: "Float too large for page by 12.0pt on input line 14.", File: "./main.tex", Line: 14},
+			},
+		},
+		{
+			name: "file:line:error format of rubber",
+			log: `compiling main.tex...
+./main.tex:5: Undefined control sequence.
+l.5 \foo
+./chapters/intro.tex:3: Reference ` + "`sec:x'" + ` on page 1 undefined
+./main.tex:10: Overfull \hbox (15.27pt too wide) in paragraph
+./main.tex:20: Underfull \hbox (badness 10000) in paragraph
+./main.tex:1: LaTeX Error: File ` + "`foobar.sty'" + ` not found.
+`,
+			want: []Diagnostic{
+				{Severity: SEVERITY_ERROR, Kind: DIAGNOSTIC_ERROR, Message: "Undefined control sequence.", File: "./main.tex", Line: 5},
+				{Severity: SEVERITY_WARNING, Kind: DIAGNOSTIC_UNDEFINED_REFERENCE, Message: "Reference `sec:x' on page 1 undefined", File: "./chapters/intro.tex", Line: 3},
+				{Severity: SEVERITY_WARNING, Kind: DIAGNOSTIC_OVERFULL_BOX, Message: `Overfull \hbox (15.27pt too wide) in paragraph`, File: "./main.tex", Line: 10},
+				{Severity: SEVERITY_WARNING, Kind: DIAGNOSTIC_UNDERFULL_BOX, Message: `Underfull \hbox (badness 10000) in paragraph`, File: "./main.tex", Line: 20},
+				{Severity: SEVERITY_ERROR, Kind: DIAGNOSTIC_MISSING_PACKAGE, Message: "LaTeX Error: File `foobar.sty' not found.", File: "./main.tex", Line: 1, Package: "foobar"},
+			},
+		},
+		{
+			name: "windows line endings",
+			log:  "(./main.tex\r\n! Undefined control sequence.\r\nl.7 \\foo\r\n        \r\n\r\n)\r\n",
+			want: []Diagnostic{
+				{Severity: SEVERITY_ERROR, Kind: DIAGNOSTIC_ERROR, Message: "Undefined control sequence.", File: "./main.tex", Line: 7},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseDiagnostics(tt.log)
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseDiagnostics() returned %d diagnostics, want %d", len(got), len(tt.want))
+
+				for i := 0; i < max(len(got), len(tt.want)); i++ {
+					var g, w Diagnostic
+
+					if i < len(got) {
+						g = got[i]
+					}
+
+					if i < len(tt.want) {
+						w = tt.want[i]
+					}
+
+					if g != w {
+						t.Errorf("diagnostic %d:\n got  %+v\n want %+v", i, g, w)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestRelativeFile(t *testing.T) {
+
+	tests := []struct {
+		name string
+		file string
+		dirs []string
+		want string
+	}{
+		{name: "empty", file: "", dirs: []string{"/jobs/1"}, want: ""},
+		{name: "relative", file: "./chapters/../main.tex", dirs: []string{"/jobs/1"}, want: "main.tex"},
+		{name: "inside main dir", file: "/jobs/1/src/main.tex", dirs: []string{"/jobs/1/src", "/jobs/1"}, want: "main.tex"},
+		{name: "inside build dir", file: "/jobs/1/styles/corporate.sty", dirs: []string{"/jobs/1/src", "/jobs/1"}, want: "styles/corporate.sty"},
+		{name: "outside", file: "/usr/share/texlive/texmf-dist/tex/latex/base/article.cls", dirs: []string{"/jobs/1"}, want: "/usr/share/texlive/texmf-dist/tex/latex/base/article.cls"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := relativeFile(tt.file, tt.dirs...); got != tt.want {
+				t.Errorf("relativeFile(%q, %q) = %q, want %q", tt.file, tt.dirs, got, tt.want)
+			}
+		})
+	}
+}
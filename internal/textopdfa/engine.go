@@ -0,0 +1,151 @@
+package textopdfa
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const (
+	ENGINE_RUBBER   = "rubber"
+	ENGINE_LATEXMK  = "latexmk"
+	ENGINE_PDFLATEX = "pdflatex"
+	ENGINE_XELATEX  = "xelatex"
+	ENGINE_LUALATEX = "lualatex"
+	DEFAULT_ENGINE  = ENGINE_RUBBER
+
+	// MAX_PASSES is the maximum number of passes of the direct engines (pdflatex, xelatex, lualatex)
+	MAX_PASSES = 5
+)
+
+// rerunPattern matches the messages of LaTeX and common packages asking for another pass
+var rerunPattern = regexp.MustCompile(`(?i)(rerun to get|rerun latex|label\(s\) may have changed|please rerun)`)
+
+// Engine compiles a TeX file to a PDF file
+type Engine interface {
+	// Name returns the name of the engine (one of the ENGINE_* constants)
+	Name() string
+
+	// Commands returns the commands the engine needs
+	Commands() []string
+
+	// Compile compiles texfile into builddir, the resulting PDF is expected at builddir/<basename>.pdf
+	// The output of all commands is recorded in rec, failures are returned as StageError
+	Compile(ctx context.Context, rec *Recorder, texfile string, builddir string) error
+}
+
+// engines are all available engines by name
+var engines = map[string]Engine{
+	ENGINE_RUBBER:   rubberEngine{},
+	ENGINE_LATEXMK:  latexmkEngine{},
+	ENGINE_PDFLATEX: latexEngine{program: ENGINE_PDFLATEX},
+	ENGINE_XELATEX:  latexEngine{program: ENGINE_XELATEX},
+	ENGINE_LUALATEX: latexEngine{program: ENGINE_LUALATEX},
+}
+
+// EngineNames returns the names of all available engines, sorted
+func EngineNames() []string {
+	names := make([]string, 0, len(engines))
+
+	for name := range engines {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	return names
+}
+
+// GetEngine returns the engine with the given name
+func GetEngine(name string) (Engine, error) {
+
+	if engine, ok := engines[name]; ok {
+		return engine, nil
+	}
+
+	return nil, fmt.Errorf("unknown engine '%s', expected one of %v", name, EngineNames())
+}
+
+// === rubber ===
+
+// rubberEngine compiles with rubber, which runs pdflatex (and bibtex etc.) as often as needed
+type rubberEngine struct{}
+
+func (rubberEngine) Name() string {
+	return ENGINE_RUBBER
+}
+
+func (rubberEngine) Commands() []string {
+	return []string{"pdflatex", "rubber"}
+}
+
+func (rubberEngine) Compile(ctx context.Context, rec *Recorder, texfile string, builddir string) error {
+	return runStage(ctx, rec, ErrCompileFailed, STAGE_COMPILE, "", "rubber", "--pdf", "--into="+builddir, texfile)
+}
+
+// === latexmk ===
+
+// latexmkEngine compiles with latexmk (using pdflatex), which runs pdflatex (and bibtex/biber etc.) as often as needed
+type latexmkEngine struct{}
+
+func (latexmkEngine) Name() string {
+	return ENGINE_LATEXMK
+}
+
+func (latexmkEngine) Commands() []string {
+	return []string{"pdflatex", "latexmk"}
+}
+
+func (latexmkEngine) Compile(ctx context.Context, rec *Recorder, texfile string, builddir string) error {
+	return runStage(ctx, rec, ErrCompileFailed, STAGE_COMPILE, filepath.Dir(texfile), "latexmk", "-pdf", "-interaction=nonstopmode", "-halt-on-error", "-output-directory="+builddir, texfile)
+}
+
+// === pdflatex, xelatex, lualatex ===
+
+// latexEngine runs a LaTeX program directly, repeating the run until no further pass is requested (at most MAX_PASSES)
+type latexEngine struct {
+	program string
+}
+
+func (e latexEngine) Name() string {
+	return e.program
+}
+
+func (e latexEngine) Commands() []string {
+	return []string{e.program}
+}
+
+func (e latexEngine) Compile(ctx context.Context, rec *Recorder, texfile string, builddir string) error {
+	basename := strings.TrimSuffix(filepath.Base(texfile), ".tex")
+	logfile := filepath.Join(builddir, basename+".log")
+
+	for pass := 1; pass <= MAX_PASSES; pass++ {
+		Log(ctx).Debug().Str("engine", e.program).Int("pass", pass).Msg("Running LaTeX pass")
+
+		err := runStage(ctx, rec, ErrCompileFailed, STAGE_COMPILE, filepath.Dir(texfile), e.program, "-interaction=nonstopmode", "-halt-on-error", "-output-directory="+builddir, texfile)
+
+		if err != nil {
+			return err
+		}
+
+		texlog, err := os.ReadFile(logfile)
+
+		if err != nil {
+			Log(ctx).Warn().Err(err).Str("logfile", logfile).Msg("Could not read LaTeX log, assuming no further pass is needed")
+			return nil
+		}
+
+		if !rerunPattern.Match(texlog) {
+			Log(ctx).Debug().Str("engine", e.program).Int("passes", pass).Msg("No further pass requested")
+			return nil
+		}
+	}
+
+	Log(ctx).Warn().Str("engine", e.program).Int("passes", MAX_PASSES).Msg("Maximum number of passes reached, there may be unresolved references")
+
+	return nil
+}
@@ -0,0 +1,98 @@
+package textopdfa
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Stages of the compile pipeline, used to report where an error occurred
+const (
+	STAGE_PREPARE  = "prepare"
+	STAGE_COMPILE  = "compile"
+	STAGE_PDFA1    = "pdfa1"    // intermediate conversion to PDF/A-1
+	STAGE_PDFA     = "pdfa"     // conversion to the requested PDF/A part
+	STAGE_VALIDATE = "validate" // optional validation of the PDF/A file
+	STAGE_OUTPUT   = "output"
+)
+
+var (
+	// ErrToolMissing is returned if an essential command (e.g. rubber or gs) is not available
+	ErrToolMissing = errors.New("required tool missing")
+
+	// ErrInvalidInput is returned if the input of the pipeline is not usable (e.g. the tex-file does not exist)
+	ErrInvalidInput = errors.New("invalid input")
+
+	// ErrCompileFailed is returned if the TeX file could not be compiled to PDF
+	ErrCompileFailed = errors.New("compiling TeX to PDF failed")
+
+	// ErrConversionFailed is returned if the PDF could not be converted to PDF/A
+	ErrConversionFailed = errors.New("converting PDF to PDF/A failed")
+
+	// ErrValidationFailed is returned if the validator could not check the PDF/A file
+	// A file that is not compliant is no error, it is reported in Result.Validation
+	ErrValidationFailed = errors.New("validating PDF/A failed")
+
+	// ErrTimeout is returned if a stage or the whole pipeline exceeded its timeout
+	ErrTimeout = errors.New("timed out")
+
+	// ErrCancelled is returned if the context of the pipeline was cancelled
+	ErrCancelled = errors.New("cancelled")
+
+	// ErrInternal is returned if the pipeline failed for reasons unrelated to the input (e.g. file system errors)
+	ErrInternal = errors.New("internal error")
+)
+
+// ToolMissingError is returned if one or more essential commands are not available
+// It matches ErrToolMissing with errors.Is
+type ToolMissingError struct {
+	Tools []string // names of the missing commands
+}
+
+func (e *ToolMissingError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrToolMissing, strings.Join(e.Tools, ", "))
+}
+
+func (e *ToolMissingError) Unwrap() error {
+	return ErrToolMissing
+}
+
+// StageError describes a failed stage of the pipeline
+// It matches its Kind (one of the Err* values) and the underlying error with errors.Is
+type StageError struct {
+	Kind     error  // kind of the error, one of ErrInvalidInput, ErrCompileFailed, ErrConversionFailed, ErrValidationFailed, ErrTimeout, ErrCancelled or ErrInternal
+	Stage    string // stage of the pipeline, one of the STAGE_* constants
+	Command  string // command line of the failed command, empty if the stage failed without running a command
+	ExitCode int    // exit code of the failed command, -1 if unknown
+	Stdout   string // captured stdout of the failed command
+	Stderr   string // captured stderr of the failed command
+	Err      error  // underlying error
+}
+
+func (e *StageError) Error() string {
+	msg := fmt.Sprintf("%s (stage %s)", e.Kind, e.Stage)
+
+	if e.ExitCode >= 0 {
+		return msg + fmt.Sprintf(": command exited with code %d", e.ExitCode)
+	}
+
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+
+	return msg
+}
+
+func (e *StageError) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+// newStageError creates a StageError for a stage that failed without running a command
+func newStageError(kind error, stage string, err error) *StageError {
+	return &StageError{
+		Kind:     kind,
+		Stage:    stage,
+		ExitCode: -1,
+		Err:      err,
+	}
+}
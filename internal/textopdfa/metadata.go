@@ -0,0 +1,192 @@
+package textopdfa
+
+import (
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// reservedXMPPrefixes are namespace prefixes used by gs, PDF/A and Factur-X, custom properties must not use them
+var reservedXMPPrefixes = []string{"x", "rdf", "dc", "pdf", "xmp", "xmpMM", "pdfaid", "pdfaExtension", "pdfaSchema", "pdfaProperty", "fx"}
+
+var (
+	patternXMLName     = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+	patternLanguageTag = regexp.MustCompile(`^[A-Za-z]{1,8}(-[A-Za-z0-9]{1,8})*$`)
+)
+
+// Metadata is the document metadata written to both the Info dictionary and the XMP metadata of the PDF/A file
+type Metadata struct {
+	Title    string `json:"title,omitempty"`
+	Author   string `json:"author,omitempty"`
+	Subject  string `json:"subject,omitempty"`
+	Keywords string `json:"keywords,omitempty"`
+	Creator  string `json:"creator,omitempty"`  // the application that created the document, e.g. the name of the invoicing system
+	Language string `json:"language,omitempty"` // BCP 47 language tag of the document, e.g. "de-DE"
+
+	// Custom are additional XMP properties, an extension schema describing them is added as required by PDF/A
+	Custom []XMPProperty `json:"custom,omitempty"`
+}
+
+// XMPProperty is a custom XMP property with a text value
+type XMPProperty struct {
+	Namespace string `json:"namespace"` // namespace URI, e.g. "https://example.com/ns/invoice/1.0/"
+	Prefix    string `json:"prefix"`    // namespace prefix, e.g. "inv"
+	Name      string `json:"name"`      // property name, e.g. "CustomerNumber"
+	Value     string `json:"value"`
+}
+
+// xmpPropertyDescription describes a property of a PDF/A extension schema
+type xmpPropertyDescription struct {
+	name        string
+	description string
+}
+
+// validate checks the metadata
+func (m *Metadata) validate() error {
+
+	if m == nil {
+		return nil
+	}
+
+	if m.Language != "" && !patternLanguageTag.MatchString(m.Language) {
+		return fmt.Errorf("invalid language '%s', expected a language tag like 'de-DE'", m.Language)
+	}
+
+	namespaces := map[string]string{} // prefix -> namespace
+	names := map[string]bool{}        // prefix:name
+
+	for _, property := range m.Custom {
+
+		if !patternXMLName.MatchString(property.Prefix) || !patternXMLName.MatchString(property.Name) {
+			return fmt.Errorf("invalid XMP property '%s:%s', prefix and name must be XML names", property.Prefix, property.Name)
+		}
+
+		if slices.Contains(reservedXMPPrefixes, property.Prefix) {
+			return fmt.Errorf("XMP prefix '%s' is reserved", property.Prefix)
+		}
+
+		if property.Namespace == "" || strings.ContainsAny(property.Namespace, "<>\"' ") {
+			return fmt.Errorf("invalid namespace '%s' of XMP property '%s:%s'", property.Namespace, property.Prefix, property.Name)
+		}
+
+		if namespace, ok := namespaces[property.Prefix]; ok && namespace != property.Namespace {
+			return fmt.Errorf("XMP prefix '%s' is used for different namespaces", property.Prefix)
+		}
+
+		if names[property.Prefix+":"+property.Name] {
+			return fmt.Errorf("duplicate XMP property '%s:%s'", property.Prefix, property.Name)
+		}
+
+		namespaces[property.Prefix] = property.Namespace
+		names[property.Prefix+":"+property.Name] = true
+	}
+
+	return nil
+}
+
+// xmlEscape escapes s for use as XML text
+func xmlEscape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// addXMPSchema declares a PDF/A extension schema with text properties, as required for properties outside the predefined schemas
+// All schemas are written in a single pdfaExtension:schemas property when the prologue is written
+func (p *pdfmarks) addXMPSchema(schema string, namespace string, prefix string, properties []xmpPropertyDescription) {
+	var b strings.Builder
+
+	b.WriteString(`<rdf:li rdf:parseType="Resource">`)
+	b.WriteString(`<pdfaSchema:schema>` + xmlEscape(schema) + `</pdfaSchema:schema>`)
+	b.WriteString(`<pdfaSchema:namespaceURI>` + xmlEscape(namespace) + `</pdfaSchema:namespaceURI>`)
+	b.WriteString(`<pdfaSchema:prefix>` + prefix + `</pdfaSchema:prefix>`)
+	b.WriteString(`<pdfaSchema:property><rdf:Seq>`)
+
+	for _, property := range properties {
+		b.WriteString(`<rdf:li rdf:parseType="Resource">` +
+			`<pdfaProperty:name>` + property.name + `</pdfaProperty:name>` +
+			`<pdfaProperty:valueType>Text</pdfaProperty:valueType>` +
+			`<pdfaProperty:category>external</pdfaProperty:category>` +
+			`<pdfaProperty:description>` + xmlEscape(property.description) + `</pdfaProperty:description>` +
+			`</rdf:li>`)
+	}
+
+	b.WriteString(`</rdf:Seq></pdfaSchema:property></rdf:li>`)
+
+	p.schemas = append(p.schemas, b.String())
+}
+
+// xmpExtensionSchemas returns an rdf:Description with all declared extension schemas
+func (p *pdfmarks) xmpExtensionSchemas() string {
+	return `<rdf:Description rdf:about="" ` +
+		`xmlns:pdfaExtension="http://www.aiim.org/pdfa/ns/extension/" ` +
+		`xmlns:pdfaSchema="http://www.aiim.org/pdfa/ns/schema#" ` +
+		`xmlns:pdfaProperty="http://www.aiim.org/pdfa/ns/property#">` +
+		`<pdfaExtension:schemas><rdf:Bag>` + strings.Join(p.schemas, "") + `</rdf:Bag></pdfaExtension:schemas>` +
+		`</rdf:Description>`
+}
+
+// addMetadata adds pdfmarks writing the metadata to the Info dictionary, the catalog and the XMP metadata
+// gs derives the XMP properties dc:title, dc:creator, dc:description, pdf:Keywords and xmp:CreatorTool from the Info dictionary,
+// so both always agree
+func (p *pdfmarks) addMetadata(m *Metadata) {
+
+	if m == nil {
+		return
+	}
+
+	var docinfo []string
+
+	for _, entry := range []struct{ key, value string }{
+		{"Title", m.Title},
+		{"Author", m.Author},
+		{"Subject", m.Subject},
+		{"Keywords", m.Keywords},
+		{"Creator", m.Creator},
+	} {
+		if entry.value != "" {
+			docinfo = append(docinfo, "/"+entry.key+" "+psTextString(entry.value))
+		}
+	}
+
+	if len(docinfo) > 0 {
+		p.add("[ %s /DOCINFO pdfmark", strings.Join(docinfo, " "))
+	}
+
+	if m.Language != "" {
+		p.add("[ {Catalog} << /Lang %s >> /PUT pdfmark", psString(m.Language))
+		p.addXMP(`<rdf:Description rdf:about="" xmlns:dc="http://purl.org/dc/elements/1.1/">` +
+			`<dc:language><rdf:Bag><rdf:li>` + xmlEscape(m.Language) + `</rdf:li></rdf:Bag></dc:language>` +
+			`</rdf:Description>`)
+	}
+
+	// one extension schema and one description per namespace, in order of first use
+	var prefixes []string
+	byPrefix := map[string][]XMPProperty{}
+
+	for _, property := range m.Custom {
+		if _, ok := byPrefix[property.Prefix]; !ok {
+			prefixes = append(prefixes, property.Prefix)
+		}
+
+		byPrefix[property.Prefix] = append(byPrefix[property.Prefix], property)
+	}
+
+	for _, prefix := range prefixes {
+		properties := byPrefix[prefix]
+		namespace := properties[0].Namespace
+
+		var descriptions []xmpPropertyDescription
+		var values strings.Builder
+
+		for _, property := range properties {
+			descriptions = append(descriptions, xmpPropertyDescription{property.Name, property.Name})
+			fmt.Fprintf(&values, "<%s:%s>%s</%s:%s>", prefix, property.Name, xmlEscape(property.Value), prefix, property.Name)
+		}
+
+		p.addXMPSchema("Custom properties", namespace, prefix, descriptions)
+		p.addXMP(`<rdf:Description rdf:about="" xmlns:` + prefix + `="` + xmlEscape(namespace) + `">` + values.String() + `</rdf:Description>`)
+	}
+}
@@ -0,0 +1,234 @@
+package textopdfa
+
+import (
+	"fmt"
+	"slices"
+)
+
+const (
+	DEFAULT_PDFA_PART             = 3
+	DEFAULT_COLOR_STRATEGY        = "UseDeviceIndependentColor"
+	DEFAULT_COMPATIBILITY_POLICY  = 2
+	DEFAULT_TIMEOUT_SECONDS       = 600
+	DEFAULT_STAGE_TIMEOUT_SECONDS = 300
+)
+
+// PDFA_CONFORMANCE is the PDF/A conformance level of the results: gs always identifies its output as level b
+// (level u would additionally require Unicode mappings for all text, which gs cannot guarantee)
+const PDFA_CONFORMANCE = "b"
+
+var (
+	// colorStrategies are the values of gs' ColorConversionStrategy that are usable for PDF/A
+	colorStrategies = []string{"LeaveColorUnchanged", "Gray", "RGB", "CMYK", "UseDeviceIndependentColor"}
+)
+
+// CompileOptions controls how a TeX file is compiled and converted to PDF/A
+// Zero values are replaced by their defaults (see DefaultCompileOptions), so a partially filled value is valid
+type CompileOptions struct {
+	// Engine is the name of the engine compiling the TeX file (see EngineNames)
+	Engine string `json:"engine,omitempty"`
+
+	// PDFAPart is the PDF/A part of the result (1, 2 or 3)
+	PDFAPart int `json:"pdfa_part,omitempty"`
+
+	// ColorConversionStrategy is passed to gs as -sColorConversionStrategy (e.g. "UseDeviceIndependentColor" or "RGB")
+	ColorConversionStrategy string `json:"color_conversion_strategy,omitempty"`
+
+	// CompatibilityPolicy is passed to gs as -dPDFACompatibilityPolicy (0: include and warn, 1: ignore and warn, 2: abort)
+	CompatibilityPolicy *int `json:"compatibility_policy,omitempty"`
+
+	// SkipPDFA1 skips the intermediate PDF/A-1 conversion and converts the PDF directly to the requested part
+	SkipPDFA1 bool `json:"skip_pdfa1,omitempty"`
+
+	// TimeoutSeconds limits the duration of the whole pipeline
+	TimeoutSeconds int `json:"timeout_seconds,omitempty"`
+
+	// StageTimeoutSeconds limits the duration of each command (e.g. a single LaTeX pass or gs run)
+	StageTimeoutSeconds int `json:"stage_timeout_seconds,omitempty"`
+
+	// Validator is the name of the validator checking the PDF/A file (see ValidatorNames), empty skips the validation
+	Validator string `json:"validator,omitempty"`
+
+	// Attachments are embedded into the PDF/A file, they require PDF/A part 3
+	Attachments []Attachment `json:"attachments,omitempty"`
+
+	// FacturXProfile marks the document as Factur-X / ZUGFeRD invoice of the given profile (e.g. "EN16931", see FacturXProfileNames)
+	// The XML invoice must be attached as "factur-x.xml"
+	FacturXProfile string `json:"facturx_profile,omitempty"`
+
+	// Metadata is written to the Info dictionary and the XMP metadata of the PDF/A file, nil keeps what LaTeX and gs write
+	Metadata *Metadata `json:"metadata,omitempty"`
+
+	// OutputIntent selects the ICC profile of the output intent (e.g. a CMYK profile of a print partner), nil keeps gs' default
+	// The color conversion strategy must match the color space of the profile, the default strategy is replaced accordingly
+	OutputIntent *OutputIntent `json:"output_intent,omitempty"`
+
+	// ICCProfileDir is the directory of the ICC profile registry, OutputIntent.Profile names a profile in it
+	// It is set by the server (not by clients), empty means DEFAULT_ICC_PROFILE_DIR
+	ICCProfileDir string `json:"-"`
+}
+
+// DefaultCompileOptions returns the default options: rubber and PDF/A-3b with device independent colors via PDF/A-1
+func DefaultCompileOptions() *CompileOptions {
+	policy := DEFAULT_COMPATIBILITY_POLICY
+
+	return &CompileOptions{
+		Engine:                  DEFAULT_ENGINE,
+		PDFAPart:                DEFAULT_PDFA_PART,
+		ColorConversionStrategy: DEFAULT_COLOR_STRATEGY,
+		CompatibilityPolicy:     &policy,
+		SkipPDFA1:               false,
+		TimeoutSeconds:          DEFAULT_TIMEOUT_SECONDS,
+		StageTimeoutSeconds:     DEFAULT_STAGE_TIMEOUT_SECONDS,
+		ICCProfileDir:           DEFAULT_ICC_PROFILE_DIR,
+	}
+}
+
+// WithDefaults returns a copy of the options with all unset values replaced by their defaults
+// It is safe to call on nil
+func (opts *CompileOptions) WithDefaults() *CompileOptions {
+	result := DefaultCompileOptions()
+
+	if opts == nil {
+		return result
+	}
+
+	if opts.Engine != "" {
+		result.Engine = opts.Engine
+	}
+
+	if opts.PDFAPart != 0 {
+		result.PDFAPart = opts.PDFAPart
+	}
+
+	if opts.ColorConversionStrategy != "" {
+		result.ColorConversionStrategy = opts.ColorConversionStrategy
+	}
+
+	if opts.CompatibilityPolicy != nil {
+		policy := *opts.CompatibilityPolicy
+		result.CompatibilityPolicy = &policy
+	}
+
+	result.SkipPDFA1 = opts.SkipPDFA1
+
+	if opts.TimeoutSeconds != 0 {
+		result.TimeoutSeconds = opts.TimeoutSeconds
+	}
+
+	if opts.StageTimeoutSeconds != 0 {
+		result.StageTimeoutSeconds = opts.StageTimeoutSeconds
+	}
+
+	result.Validator = opts.Validator
+	result.FacturXProfile = opts.FacturXProfile
+
+	if opts.Metadata != nil {
+		metadata := *opts.Metadata
+		result.Metadata = &metadata
+	}
+
+	if opts.OutputIntent != nil {
+		intent := *opts.OutputIntent
+		result.OutputIntent = &intent
+	}
+
+	if opts.ICCProfileDir != "" {
+		result.ICCProfileDir = opts.ICCProfileDir
+	}
+
+	for _, attachment := range opts.Attachments {
+		result.Attachments = append(result.Attachments, attachment.withDefaults(opts.FacturXProfile != ""))
+	}
+
+	return result
+}
+
+// Validate checks the options (after applying the defaults) and returns an error describing the first invalid value
+func (opts *CompileOptions) Validate() error {
+	o := opts.WithDefaults()
+
+	if _, err := GetEngine(o.Engine); err != nil {
+		return err
+	}
+
+	if o.PDFAPart < 1 || o.PDFAPart > 3 {
+		return fmt.Errorf("invalid PDF/A part %d, expected 1, 2 or 3", o.PDFAPart)
+	}
+
+	if !slices.Contains(colorStrategies, o.ColorConversionStrategy) {
+		return fmt.Errorf("invalid color conversion strategy '%s', expected one of %v", o.ColorConversionStrategy, colorStrategies)
+	}
+
+	if *o.CompatibilityPolicy < 0 || *o.CompatibilityPolicy > 2 {
+		return fmt.Errorf("invalid compatibility policy %d, expected 0, 1 or 2", *o.CompatibilityPolicy)
+	}
+
+	if o.TimeoutSeconds < 0 || o.StageTimeoutSeconds < 0 {
+		return fmt.Errorf("invalid timeout, expected a positive number of seconds")
+	}
+
+	if o.Validator != "" && !slices.Contains(ValidatorNames(), o.Validator) {
+		return fmt.Errorf("unknown validator '%s', expected one of %v", o.Validator, ValidatorNames())
+	}
+
+	if len(o.Attachments) > 0 && o.PDFAPart != 3 {
+		return fmt.Errorf("attachments require PDF/A part 3")
+	}
+
+	names := map[string]bool{}
+
+	for _, attachment := range o.Attachments {
+		if err := attachment.validate(); err != nil {
+			return err
+		}
+
+		if names[attachment.Name] {
+			return fmt.Errorf("duplicate attachment name '%s'", attachment.Name)
+		}
+
+		names[attachment.Name] = true
+	}
+
+	if err := o.Metadata.validate(); err != nil {
+		return err
+	}
+
+	if err := o.OutputIntent.validate(); err != nil {
+		return err
+	}
+
+	// uploaded profiles are checked when compiling, as the files may not be written yet
+	if o.OutputIntent != nil && o.OutputIntent.Profile != "" {
+		if _, err := findICCProfile(o.ICCProfileDir, o.OutputIntent.Profile); err != nil {
+			return err
+		}
+	}
+
+	if o.FacturXProfile != "" {
+		if _, ok := facturxLevel(o.FacturXProfile); !ok {
+			return fmt.Errorf("unknown Factur-X profile '%s', expected one of %v", o.FacturXProfile, FacturXProfileNames())
+		}
+
+		if !names[FACTURX_FILENAME] {
+			return fmt.Errorf("Factur-X requires the XML invoice attached as '%s'", FACTURX_FILENAME)
+		}
+	}
+
+	return nil
+}
+
+// gsArgs returns the gs arguments to convert infiles to the given PDF/A part
+// infiles are processed in order, a PostScript prologue (see pdfmarks) must precede the PDF file
+func (opts *CompileOptions) gsArgs(part int, outfile string, infiles ...string) []string {
+
+	args := []string{
+		"-sDEVICE=pdfwrite",
+		fmt.Sprintf("-dPDFA=%d", part),
+		"-sColorConversionStrategy=" + opts.ColorConversionStrategy,
+		fmt.Sprintf("-dPDFACompatibilityPolicy=%d", *opts.CompatibilityPolicy),
+		"-o", outfile,
+	}
+
+	return append(args, infiles...)
+}
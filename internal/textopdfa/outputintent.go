@@ -0,0 +1,269 @@
+package textopdfa
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const (
+	// DEFAULT_ICC_PROFILE_DIR is the directory of the server-side ICC profile registry (where Debian's icc-profiles packages install to)
+	DEFAULT_ICC_PROFILE_DIR = "/usr/share/color/icc"
+
+	// ICC_HEADER_SIZE is the size of the header of an ICC profile
+	ICC_HEADER_SIZE = 128
+)
+
+// iccExtensions are the file extensions of ICC profiles in the registry
+var iccExtensions = []string{".icc", ".icm"}
+
+// iccColorSpaces maps the color space signature of an ICC profile (without trailing spaces) to the number of components and the matching gs color conversion strategy
+var iccColorSpaces = map[string]struct {
+	components int
+	strategy   string
+}{
+	"GRAY": {1, "Gray"},
+	"RGB":  {3, "RGB"},
+	"CMYK": {4, "CMYK"},
+}
+
+// OutputIntent selects the ICC profile of the PDF/A output intent, replacing gs' default
+// Exactly one of Profile and File must be set
+type OutputIntent struct {
+	// Profile is the name of a profile of the server-side registry (file name without extension, see ICCProfiles)
+	Profile string `json:"profile,omitempty"`
+
+	// File is the path of an uploaded ICC profile, relative to the directory of the main TeX file
+	File string `json:"file,omitempty"`
+
+	// Identifier is the OutputConditionIdentifier (e.g. "FOGRA39"), defaults to the name of the profile
+	Identifier string `json:"identifier,omitempty"`
+
+	// Condition is an optional human readable description of the output condition (e.g. "Offset printing, paper type 1 or 2")
+	Condition string `json:"condition,omitempty"`
+
+	// RegistryName is an optional URL of the registry the identifier is defined in (e.g. "http://www.color.org")
+	RegistryName string `json:"registry_name,omitempty"`
+}
+
+// ICCProfile describes an ICC profile usable as output intent
+type ICCProfile struct {
+	Name       string `json:"name"`        // file name without extension
+	Path       string `json:"-"`           // absolute path of the file
+	ColorSpace string `json:"color_space"` // "GRAY", "RGB" or "CMYK"
+	Components int    `json:"components"`  // number of color components (1, 3 or 4)
+}
+
+// strategy returns the gs color conversion strategy converting to the color space of the profile
+func (p *ICCProfile) strategy() string {
+	return iccColorSpaces[p.ColorSpace].strategy
+}
+
+// readICCProfile reads the header of an ICC profile and returns its description
+func readICCProfile(path string) (*ICCProfile, error) {
+	file, err := os.Open(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	header := make([]byte, ICC_HEADER_SIZE)
+
+	if _, err := io.ReadFull(file, header); err != nil || string(header[36:40]) != "acsp" {
+		return nil, fmt.Errorf("'%s' is no ICC profile", filepath.Base(path))
+	}
+
+	signature := strings.TrimRight(string(header[16:20]), " ")
+	colorspace, ok := iccColorSpaces[signature]
+
+	if !ok {
+		return nil, fmt.Errorf("unsupported color space '%s' of ICC profile '%s', expected GRAY, RGB or CMYK", signature, filepath.Base(path))
+	}
+
+	abspath, err := filepath.Abs(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &ICCProfile{
+		Name:       strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)),
+		Path:       abspath,
+		ColorSpace: signature,
+		Components: colorspace.components,
+	}, nil
+}
+
+// ICCProfiles returns the usable ICC profiles of the registry dir (including subdirectories), sorted by name
+// Files that are no ICC profiles or have an unsupported color space are skipped, for duplicate names the first one found wins
+// A missing dir is an empty registry
+func ICCProfiles(dir string) ([]ICCProfile, error) {
+	profiles := []ICCProfile{}
+	names := map[string]bool{}
+
+	if _, err := os.Stat(dir); errors.Is(err, fs.ErrNotExist) {
+		return profiles, nil
+	}
+
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() || !slices.Contains(iccExtensions, strings.ToLower(filepath.Ext(path))) {
+			return nil
+		}
+
+		profile, err := readICCProfile(path)
+
+		if err != nil || names[profile.Name] {
+			return nil
+		}
+
+		names[profile.Name] = true
+		profiles = append(profiles, *profile)
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("could not read ICC profile dir: %w", err)
+	}
+
+	slices.SortFunc(profiles, func(a, b ICCProfile) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return profiles, nil
+}
+
+// findICCProfile returns the profile of the registry dir with the given name
+func findICCProfile(dir string, name string) (*ICCProfile, error) {
+	profiles, err := ICCProfiles(dir)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, profile := range profiles {
+		if profile.Name == name {
+			return &profile, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown ICC profile '%s'", name)
+}
+
+// RegistryProfilePath returns the path of the registry profile selected as output intent by the options, empty if none is selected
+// Profiles uploaded with a job (OutputIntent.File) are not considered, they are among the files of the job
+func RegistryProfilePath(opts *CompileOptions) (string, error) {
+
+	opts = opts.WithDefaults()
+
+	if opts.OutputIntent == nil || opts.OutputIntent.Profile == "" {
+		return "", nil
+	}
+
+	profile, err := findICCProfile(opts.ICCProfileDir, opts.OutputIntent.Profile)
+
+	if err != nil {
+		return "", err
+	}
+
+	return profile.Path, nil
+}
+
+// validate checks the output intent, without accessing the profile
+func (o *OutputIntent) validate() error {
+
+	if o == nil {
+		return nil
+	}
+
+	if (o.Profile == "") == (o.File == "") {
+		return errors.New("output intent requires either a profile name or an ICC profile file")
+	}
+
+	if o.Profile != "" && strings.ContainsAny(o.Profile, "/\\") {
+		return fmt.Errorf("invalid ICC profile name '%s'", o.Profile)
+	}
+
+	if o.File != "" && !filepath.IsLocal(filepath.FromSlash(o.File)) {
+		return fmt.Errorf("invalid ICC profile file '%s', expected a path relative to the main TeX file", o.File)
+	}
+
+	return nil
+}
+
+// resolve returns the ICC profile of the output intent
+// dir is the registry dir, maindir the directory uploaded profiles are relative to
+func (o *OutputIntent) resolve(dir string, maindir string) (*ICCProfile, error) {
+
+	if o.Profile != "" {
+		return findICCProfile(dir, o.Profile)
+	}
+
+	return readICCProfile(filepath.Join(maindir, filepath.FromSlash(o.File)))
+}
+
+// identifier returns the OutputConditionIdentifier of the output intent
+func (o *OutputIntent) identifier(profile *ICCProfile) string {
+
+	if o.Identifier != "" {
+		return o.Identifier
+	}
+
+	return profile.Name
+}
+
+// addOutputIntent adds pdfmarks embedding the ICC profile and setting it as PDF/A output intent, like gs' PDFA_def.ps
+func (p *pdfmarks) addOutputIntent(o *OutputIntent, profile *ICCProfile) {
+
+	icc := p.object("icc_PDFA")
+	intent := p.object("OutputIntent_PDFA")
+
+	p.files = append(p.files, profile.Path)
+
+	p.add("[ /_objdef %s /type /stream /OBJ pdfmark", icc)
+	p.add("[ %s << /N %d >> /PUT pdfmark", icc, profile.Components)
+	p.add("[ %s %s (r) file /PUT pdfmark", icc, psString(profile.Path))
+
+	dict := fmt.Sprintf("/Type /OutputIntent /S /GTS_PDFA1 /DestOutputProfile %s /OutputConditionIdentifier %s /Info %s",
+		icc, psString(o.identifier(profile)), psString(profile.Name))
+
+	if o.Condition != "" {
+		dict += " /OutputCondition " + psString(o.Condition)
+	}
+
+	if o.RegistryName != "" {
+		dict += " /RegistryName " + psString(o.RegistryName)
+	}
+
+	p.add("[ /_objdef %s /type /dict /OBJ pdfmark", intent)
+	p.add("[ %s << %s >> /PUT pdfmark", intent, dict)
+	p.add("[ {Catalog} << /OutputIntents [ %s ] >> /PUT pdfmark", intent)
+}
+
+// useOutputIntent adapts the color conversion strategy to the color space of the profile, as PDF/A requires
+// The default strategy (device independent colors) is replaced, any other strategy must match the profile
+func (opts *CompileOptions) useOutputIntent(profile *ICCProfile) error {
+
+	if opts.ColorConversionStrategy == DEFAULT_COLOR_STRATEGY {
+		opts.ColorConversionStrategy = profile.strategy()
+	}
+
+	if opts.ColorConversionStrategy != profile.strategy() {
+		return fmt.Errorf("color conversion strategy '%s' does not match the %s output intent '%s', expected '%s'",
+			opts.ColorConversionStrategy, profile.ColorSpace, profile.Name, profile.strategy())
+	}
+
+	return nil
+}
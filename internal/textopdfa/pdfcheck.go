@@ -0,0 +1,239 @@
+package textopdfa
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// MAX_INSPECT_SIZE is the maximum size of decompressed stream data the internal validator inspects
+const MAX_INSPECT_SIZE = 64 << 20 // 64 MiB
+
+var (
+	patternStream         = regexp.MustCompile(`stream\r?\n`)
+	patternFontDescriptor = regexp.MustCompile(`/Type\s*/FontDescriptor`)
+	patternFontFile       = regexp.MustCompile(`/FontFile[23]?\b`)
+	patternPDFAIDPart     = regexp.MustCompile(`pdfaid:part(?:="|>)\s*(\d)`)
+	patternPDFAIDConf     = regexp.MustCompile(`pdfaid:conformance(?:="|>)\s*([A-Za-z])`)
+	patternJavaScript     = regexp.MustCompile(`/(?:JavaScript|JS)\b`)
+)
+
+// structuralCheck is a single check of the internal validator
+type structuralCheck struct {
+	clauses     [2]string // clause in ISO 19005-1 and in ISO 19005-2/3
+	description string
+	failed      func(doc *pdfDocument, part int, conformance string) bool // conformance is the requested level, e.g. "b"
+}
+
+// structuralChecks are the checks of the internal validator
+// They cover the requirements gs' output fails most often, they are no replacement for a full validator like veraPDF
+var structuralChecks = []structuralCheck{
+	{
+		clauses:     [2]string{"6.1.2", "6.1.2"},
+		description: "The file header shall be followed by a comment line with at least four bytes above 127",
+		failed: func(doc *pdfDocument, part int, conformance string) bool {
+			return !doc.hasBinaryHeader()
+		},
+	},
+	{
+		clauses:     [2]string{"6.1.3", "6.1.3"},
+		description: "The file trailer shall contain the ID keyword",
+		failed: func(doc *pdfDocument, part int, conformance string) bool {
+			return !bytes.Contains(doc.raw, []byte("/ID"))
+		},
+	},
+	{
+		clauses:     [2]string{"6.1.3", "6.1.3"},
+		description: "The Encrypt key shall not be present in the trailer dictionary",
+		failed: func(doc *pdfDocument, part int, conformance string) bool {
+			return doc.contains("/Encrypt")
+		},
+	},
+	{
+		clauses:     [2]string{"6.1.3", "6.1.3"},
+		description: "No data shall follow the last end-of-file marker",
+		failed: func(doc *pdfDocument, part int, conformance string) bool {
+			return !bytes.HasSuffix(bytes.TrimRight(doc.raw, "\r\n"), []byte("%%EOF"))
+		},
+	},
+	{
+		clauses:     [2]string{"6.1.10", "6.1.7.2"},
+		description: "The LZWDecode filter shall not be used",
+		failed: func(doc *pdfDocument, part int, conformance string) bool {
+			return doc.contains("/LZWDecode")
+		},
+	},
+	{
+		clauses:     [2]string{"6.2.2", "6.2.3"},
+		description: "The document shall contain a PDF/A output intent (GTS_PDFA1)",
+		failed: func(doc *pdfDocument, part int, conformance string) bool {
+			return !doc.contains("/OutputIntents") || !doc.contains("/GTS_PDFA1")
+		},
+	},
+	{
+		clauses:     [2]string{"6.3.4", "6.2.11.4.1"},
+		description: "All fonts shall be embedded",
+		failed: func(doc *pdfDocument, part int, conformance string) bool {
+			return len(patternFontDescriptor.FindAllIndex(doc.content, -1)) > len(patternFontFile.FindAllIndex(doc.content, -1))
+		},
+	},
+	{
+		clauses:     [2]string{"6.6.1", "6.6.1"},
+		description: "The document shall not contain JavaScript",
+		failed: func(doc *pdfDocument, part int, conformance string) bool {
+			return patternJavaScript.Match(doc.content)
+		},
+	},
+	{
+		clauses:     [2]string{"6.7.2", "6.6.2.1"},
+		description: "The document catalog shall contain a Metadata stream with XMP metadata",
+		failed: func(doc *pdfDocument, part int, conformance string) bool {
+			return !doc.contains("/Metadata") || !doc.contains("<x:xmpmeta")
+		},
+	},
+	{
+		clauses:     [2]string{"6.7.11", "6.6.4"},
+		description: "The XMP metadata shall identify the PDF/A part and conformance level the file is checked against",
+		failed: func(doc *pdfDocument, part int, conformance string) bool {
+			partMatch := patternPDFAIDPart.FindSubmatch(doc.content)
+			confMatch := patternPDFAIDConf.FindSubmatch(doc.content)
+
+			if partMatch == nil || confMatch == nil || string(partMatch[1]) != fmt.Sprint(part) {
+				return true
+			}
+
+			levels := "AB"
+
+			if part > 1 {
+				levels = "ABU"
+			}
+
+			// a file declaring level b is not compliant with level u (or a), even if it passes the other checks
+			declared := strings.ToUpper(string(confMatch[1]))
+
+			return !strings.Contains(levels, declared) || declared != strings.ToUpper(conformance)
+		},
+	},
+	{
+		clauses:     [2]string{"6.1.11", ""},
+		description: "The document shall not contain embedded files",
+		failed: func(doc *pdfDocument, part int, conformance string) bool {
+			return doc.contains("/EmbeddedFiles")
+		},
+	},
+}
+
+// pdfDocument is a PDF file prepared for the structural checks
+type pdfDocument struct {
+	raw     []byte // the file as is
+	content []byte // the file followed by the content of all Flate compressed streams (e.g. object streams)
+}
+
+// readPDFDocument reads a PDF file and decompresses its streams, at most MAX_INSPECT_SIZE bytes in total
+func readPDFDocument(path string) (*pdfDocument, error) {
+	raw, err := os.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	content := bytes.NewBuffer(append([]byte(nil), raw...))
+	budget := int64(MAX_INSPECT_SIZE)
+
+	for _, loc := range patternStream.FindAllIndex(raw, -1) {
+		start := loc[1]
+		end := bytes.Index(raw[start:], []byte("endstream"))
+
+		if end < 0 || budget <= 0 {
+			break
+		}
+
+		reader, err := zlib.NewReader(bytes.NewReader(raw[start : start+end]))
+
+		if err != nil {
+			// not compressed or another filter
+			continue
+		}
+
+		content.WriteByte('\n')
+		n, _ := io.Copy(content, io.LimitReader(reader, budget))
+		budget -= n
+		reader.Close()
+	}
+
+	return &pdfDocument{raw: raw, content: content.Bytes()}, nil
+}
+
+// contains reports whether the file or one of its decompressed streams contains s
+func (doc *pdfDocument) contains(s string) bool {
+	return bytes.Contains(doc.content, []byte(s))
+}
+
+// hasBinaryHeader reports whether the file starts with a PDF header followed by a comment of at least four binary bytes
+func (doc *pdfDocument) hasBinaryHeader() bool {
+	lines := bytes.SplitN(doc.raw, []byte("\n"), 3)
+
+	if len(lines) < 3 || !bytes.HasPrefix(lines[0], []byte("%PDF-")) || !bytes.HasPrefix(lines[1], []byte("%")) {
+		return false
+	}
+
+	binary := 0
+
+	for _, b := range lines[1][1:] {
+		if b > 127 {
+			binary++
+		}
+	}
+
+	return binary >= 4
+}
+
+// internalValidator performs structural checks without external tools, see structuralChecks
+type internalValidator struct{}
+
+func (internalValidator) Name() string {
+	return VALIDATOR_INTERNAL
+}
+
+func (internalValidator) Validate(ctx context.Context, rec *Recorder, pdffile string, part int, conformance string) (*ValidationReport, error) {
+	doc, err := readPDFDocument(pdffile)
+
+	if err != nil {
+		return nil, newStageError(ErrValidationFailed, STAGE_VALIDATE, fmt.Errorf("could not read PDF/A file: %w", err))
+	}
+
+	report := &ValidationReport{
+		Validator:   VALIDATOR_INTERNAL,
+		Profile:     strings.ToUpper(fmt.Sprintf("PDF/A-%d%s", part, conformance)),
+		Compliant:   true,
+		FailedRules: []ValidationRule{},
+	}
+
+	clause := 1
+
+	if part == 1 {
+		clause = 0
+	}
+
+	for _, check := range structuralChecks {
+		if check.clauses[clause] == "" || !check.failed(doc, part, conformance) {
+			continue
+		}
+
+		report.Compliant = false
+		report.FailedRules = append(report.FailedRules, ValidationRule{
+			Specification: specification(part),
+			Clause:        check.clauses[clause],
+			Description:   check.description,
+		})
+	}
+
+	Log(ctx).Debug().Bool("compliant", report.Compliant).Int("failed_rules", len(report.FailedRules)).Msg("Checked PDF/A structure")
+
+	return report, nil
+}
@@ -0,0 +1,113 @@
+package textopdfa
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf16"
+)
+
+// pdfmarks builds a PostScript prologue of pdfmark operators, gs applies it to the PDF it writes
+// It is passed to gs before the input file, files read by it must be allowed with --permit-file-read
+type pdfmarks struct {
+	ps      strings.Builder
+	files   []string // files read by the prologue
+	schemas []string // PDF/A extension schemas of the XMP metadata, see addXMPSchema
+	objs    int      // number of named objects, used to generate unique names
+}
+
+// psString returns s as PostScript string literal, non-ASCII bytes are written as octal escapes (UTF-8 is kept byte by byte)
+func psString(s string) string {
+	var b strings.Builder
+
+	b.WriteByte('(')
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+
+		switch {
+		case c == '(' || c == ')' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < 0x20 || c > 0x7e:
+			fmt.Fprintf(&b, "\\%03o", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	b.WriteByte(')')
+
+	return b.String()
+}
+
+// psTextString returns s as PostScript string literal of a PDF text string
+// Strings with non-ASCII characters are written as UTF-16BE with byte order mark, as required for PDF text strings
+func psTextString(s string) string {
+
+	for _, r := range s {
+		if r > 0x7e {
+			var b strings.Builder
+
+			b.WriteString("<FEFF")
+
+			for _, u := range utf16.Encode([]rune(s)) {
+				fmt.Fprintf(&b, "%04X", u)
+			}
+
+			b.WriteString(">")
+
+			return b.String()
+		}
+	}
+
+	return psString(s)
+}
+
+// psName returns s as PostScript name object (e.g. "text/xml" as /text#2Fxml via cvn)
+func psName(s string) string {
+	return psString(s) + " cvn"
+}
+
+// object returns a new unique object name with the given prefix, e.g. "{attachment1}"
+func (p *pdfmarks) object(prefix string) string {
+	p.objs++
+	return fmt.Sprintf("{%s%d}", prefix, p.objs)
+}
+
+// add appends a line to the prologue
+func (p *pdfmarks) add(format string, args ...any) {
+	fmt.Fprintf(&p.ps, format, args...)
+	p.ps.WriteByte('\n')
+}
+
+// addXMP adds XML (e.g. an rdf:Description) to the XMP metadata of the document
+func (p *pdfmarks) addXMP(xml string) {
+	p.add("[ /XML %s /Ext_Metadata pdfmark", psString(xml))
+}
+
+// empty reports whether the prologue contains no pdfmarks
+func (p *pdfmarks) empty() bool {
+	return p.ps.Len() == 0 && len(p.schemas) == 0
+}
+
+// write writes the prologue to path and returns the gs arguments permitting to read its files
+func (p *pdfmarks) write(path string) ([]string, error) {
+
+	if len(p.schemas) > 0 {
+		p.addXMP(p.xmpExtensionSchemas())
+		p.schemas = nil
+	}
+
+	if err := os.WriteFile(path, []byte(p.ps.String()), 0644); err != nil {
+		return nil, err
+	}
+
+	args := make([]string, 0, len(p.files))
+
+	for _, file := range p.files {
+		args = append(args, "--permit-file-read="+file)
+	}
+
+	return args, nil
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package textopdfa
+
+import (
+	"os/exec"
+)
+
+// setProcessGroup is a no-op on platforms without process groups, only the command itself is killed on cancellation
+func setProcessGroup(cmd *exec.Cmd) {}
@@ -0,0 +1,103 @@
+package textopdfa
+
+import (
+	"bytes"
+	"context"
+	"sync"
+)
+
+// MAX_PROGRESS_LINE is the maximum length of a reported line of output, longer lines are split
+const MAX_PROGRESS_LINE = 4096
+
+// progressKey is the context key for the progress callback of the pipeline
+var progressKey = &struct{ name string }{"progress"}
+
+// ProgressEvent reports the progress of the pipeline while it runs, see WithProgress
+// An event either announces a command (Line is empty) or carries a line of its output
+type ProgressEvent struct {
+	Stage   string `json:"stage"`             // stage of the pipeline, one of the STAGE_* constants
+	Pass    int    `json:"pass"`              // number of the command within the stage, starting at 1 (e.g. the LaTeX pass)
+	Command string `json:"command,omitempty"` // command line, empty for stages without command (e.g. the internal validator)
+	Line    string `json:"line,omitempty"`    // a line of output of the command, without line break
+	Stderr  bool   `json:"stderr,omitempty"`  // the line was written to stderr
+}
+
+// WithProgress returns a context reporting the progress of CompileTexToPDFA to fn
+// fn is called for the start of each command and for each line of its output, it may be called concurrently and must not block
+func WithProgress(ctx context.Context, fn func(ProgressEvent)) context.Context {
+	return context.WithValue(ctx, progressKey, fn)
+}
+
+// reportProgress reports an event to the progress callback of the context, if any
+func reportProgress(ctx context.Context, event ProgressEvent) {
+
+	if fn, ok := ctx.Value(progressKey).(func(ProgressEvent)); ok {
+		fn(event)
+	}
+}
+
+// progressWriter reports the output written to it line by line, see Flush
+type progressWriter struct {
+	mu    sync.Mutex
+	ctx   context.Context
+	event ProgressEvent // template of the reported events
+	line  []byte        // incomplete line
+}
+
+// newProgressWriter returns a writer reporting lines of the command announced by event, nil if the context has no progress callback
+func newProgressWriter(ctx context.Context, event ProgressEvent, stderr bool) *progressWriter {
+
+	if _, ok := ctx.Value(progressKey).(func(ProgressEvent)); !ok {
+		return nil
+	}
+
+	event.Command = ""
+	event.Stderr = stderr
+
+	return &progressWriter{ctx: ctx, event: event}
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.line = append(w.line, p...)
+
+	for {
+		i := bytes.IndexByte(w.line, '\n')
+
+		if i < 0 && len(w.line) < MAX_PROGRESS_LINE {
+			break
+		}
+
+		if i < 0 || i > MAX_PROGRESS_LINE {
+			i = MAX_PROGRESS_LINE
+		}
+
+		w.report(w.line[:i])
+		w.line = bytes.TrimPrefix(w.line[i:], []byte("\n"))
+	}
+
+	return len(p), nil
+}
+
+// Flush reports the last line, if it has no line break
+func (w *progressWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.line) > 0 {
+		w.report(w.line)
+		w.line = nil
+	}
+}
+
+// report reports a line, the caller must hold w.mu
+func (w *progressWriter) report(line []byte) {
+	event := w.event
+	event.Line = string(bytes.TrimSuffix(line, []byte("\r")))
+
+	if event.Line != "" {
+		reportProgress(w.ctx, event)
+	}
+}
@@ -0,0 +1,97 @@
+package textopdfa
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StageLog is the captured output of a single command run by the pipeline
+type StageLog struct {
+	Stage      string `json:"stage"`       // stage of the pipeline, one of the STAGE_* constants
+	Command    string `json:"command"`     // command line
+	ExitCode   int    `json:"exit_code"`   // exit code, -1 if the command could not be started or was killed
+	DurationMS int64  `json:"duration_ms"` // run time in milliseconds
+	Stdout     string `json:"stdout"`
+	Stderr     string `json:"stderr"`
+}
+
+// Recorder collects the output of all commands run by the pipeline, it is safe for concurrent use
+type Recorder struct {
+	mu     sync.Mutex
+	stages []StageLog
+}
+
+// add records the output of a finished command
+func (rec *Recorder) add(stage string, command string, exitcode int, duration time.Duration, stdout string, stderr string) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	rec.stages = append(rec.stages, StageLog{
+		Stage:      stage,
+		Command:    command,
+		ExitCode:   exitcode,
+		DurationMS: duration.Milliseconds(),
+		Stdout:     stdout,
+		Stderr:     stderr,
+	})
+}
+
+// Stages returns a copy of the recorded commands in the order they were run
+func (rec *Recorder) Stages() []StageLog {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	return append([]StageLog(nil), rec.stages...)
+}
+
+// count returns the number of recorded commands of a stage
+func (rec *Recorder) count(stage string) int {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	n := 0
+
+	for _, log := range rec.stages {
+		if log.Stage == stage {
+			n++
+		}
+	}
+
+	return n
+}
+
+// last returns the most recently recorded command
+func (rec *Recorder) last() StageLog {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	if len(rec.stages) == 0 {
+		return StageLog{}
+	}
+
+	return rec.stages[len(rec.stages)-1]
+}
+
+// Log returns the output of all recorded commands as a single text, each command introduced by its command line
+func (rec *Recorder) Log() string {
+	return CombineStageLogs(rec.Stages())
+}
+
+// CombineStageLogs returns the output of the given commands as a single text, each command introduced by its command line
+func CombineStageLogs(stages []StageLog) string {
+	var log strings.Builder
+
+	for _, stage := range stages {
+		fmt.Fprintf(&log, "$ %s\n", stage.Command)
+		log.WriteString(stage.Stdout)
+		log.WriteString(stage.Stderr)
+
+		if log.Len() > 0 && !strings.HasSuffix(log.String(), "\n") {
+			log.WriteString("\n")
+		}
+	}
+
+	return log.String()
+}
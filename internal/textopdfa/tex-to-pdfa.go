@@ -3,16 +3,28 @@ package textopdfa
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/rs/zerolog"
 	"github.com/tilseiffert/docker-tex-to-pdf/internal/contextkeys"
 )
 
+const (
+	// COMMAND_WAIT_DELAY is the time to wait for the output of a killed command before giving up on it
+	COMMAND_WAIT_DELAY = 5 * time.Second
+)
+
+// stageTimeoutKey is the context key for the timeout of a single stage
+var stageTimeoutKey = &struct{ name string }{"stage-timeout"}
+
 // Log returns the logger from the context (for more convenient logging)
 func Log(ctx context.Context) *zerolog.Logger {
 
@@ -36,169 +48,387 @@ func assureCommand(ctx context.Context, name string) error {
 	return nil
 }
 
+// Result is the outcome of a compilation
+type Result struct {
+	Path        string            // absolute path to the resulting PDF/A file, empty if the compilation failed
+	Log         string            // collected output (stdout and stderr) of all commands that were run
+	Stages      []StageLog        // output of each command that was run
+	TexLogPath  string            // absolute path to the LaTeX .log file (next to the tex-file), empty if LaTeX wrote none
+	Diagnostics []Diagnostic      // errors and warnings reported by the TeX engine
+	Validation  *ValidationReport // result of the PDF/A validation, nil if it was not requested
+}
+
+// runStage runs a command as part of a stage of the pipeline and records its output
+// dir is the working directory of the command, empty means the current working directory
+// The command (including its children) is killed if ctx is done or the stage timeout (see stageContext) is exceeded
+// If the command fails, a StageError of the given kind is returned (ErrTimeout or ErrCancelled if it was killed)
+func runStage(ctx context.Context, rec *Recorder, kind error, stage string, dir string, name string, args ...string) error {
+	var cmd_stdout, cmd_stderr bytes.Buffer
+
+	ctx, cancel := stageContext(ctx)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	cmd.Stdout = &cmd_stdout
+	cmd.Stderr = &cmd_stderr
+	cmd.WaitDelay = COMMAND_WAIT_DELAY
+	setProcessGroup(cmd)
+
+	// report the command and its output while it runs, if requested (see WithProgress)
+	progress := ProgressEvent{Stage: stage, Pass: rec.count(stage) + 1, Command: cmd.String()}
+	reportProgress(ctx, progress)
+
+	if stdout := newProgressWriter(ctx, progress, false); stdout != nil {
+		stderr := newProgressWriter(ctx, progress, true)
+		cmd.Stdout = io.MultiWriter(&cmd_stdout, stdout)
+		cmd.Stderr = io.MultiWriter(&cmd_stderr, stderr)
+
+		defer stdout.Flush()
+		defer stderr.Flush()
+	}
+
+	Log(ctx).Debug().Str("stage", stage).Str("cmd", cmd.String()).Msg("Running command")
+	starttime := time.Now()
+	err := cmd.Run()
+
+	exitcode := -1
+
+	if cmd.ProcessState != nil {
+		exitcode = cmd.ProcessState.ExitCode()
+	}
+
+	rec.add(stage, cmd.String(), exitcode, time.Since(starttime), cmd_stdout.String(), cmd_stderr.String())
+
+	if err != nil && ctx.Err() != nil {
+		kind = ErrCancelled
+
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			kind = ErrTimeout
+		}
+
+		err = fmt.Errorf("%s killed: %w", filepath.Base(name), ctx.Err())
+	}
+
+	if err != nil {
+		Log(ctx).Error().Err(err).Str("stage", stage).Str("stdout", cmd_stdout.String()).Str("stderr", cmd_stderr.String()).Msg("Could not run command, aborting...")
+
+		if kind == ErrTimeout || kind == ErrCancelled {
+			exitcode = -1
+		}
+
+		return &StageError{
+			Kind:     kind,
+			Stage:    stage,
+			Command:  cmd.String(),
+			ExitCode: exitcode,
+			Stdout:   cmd_stdout.String(),
+			Stderr:   cmd_stderr.String(),
+			Err:      err,
+		}
+	}
+
+	Log(ctx).Trace().Str("stdout", cmd_stdout.String()).Str("stderr", cmd_stderr.String()).Msgf("Command %s finished", cmd.Path)
+
+	return nil
+}
+
+// keepTexLog copies the LaTeX log from the build dir to dst and returns dst, it returns an empty string if there is no log
+func keepTexLog(ctx context.Context, src string, dst string) string {
+	content, err := os.ReadFile(src)
+
+	if err != nil {
+		Log(ctx).Debug().Err(err).Str("path", src).Msg("No LaTeX log found")
+		return ""
+	}
+
+	if err := os.WriteFile(dst, content, 0644); err != nil {
+		Log(ctx).Warn().Err(err).Str("path", dst).Msg("Could not keep LaTeX log")
+		return ""
+	}
+
+	return dst
+}
+
+// stageContext returns the context for a single stage, limited by the stage timeout stored in ctx (if any)
+func stageContext(ctx context.Context) (context.Context, context.CancelFunc) {
+
+	if timeout, ok := ctx.Value(stageTimeoutKey).(time.Duration); ok && timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+
+	return context.WithCancel(ctx)
+}
+
+// assureOutput checks if a stage produced its expected output file and returns a StageError of the given kind if not
+func assureOutput(ctx context.Context, kind error, stage string, path string) error {
+
+	if _, err := os.Stat(path); err != nil {
+		Log(ctx).Error().Err(err).Str("stage", stage).Msgf("Output file does not exist, expected '%s', aborting...", path)
+		return newStageError(kind, stage, fmt.Errorf("expected output file '%s': %w", filepath.Base(path), err))
+	}
+
+	Log(ctx).Trace().Str("stage", stage).Str("path", path).Msg("Found output file")
+
+	return nil
+}
+
 // CompileTexToPDFA compiles a TeX file to a PDF/A file
-// It returns the path to the PDF/A file
+// It returns the path to the PDF/A file and the collected output of all commands
+// On failure, the error is a *ToolMissingError or a *StageError and the returned result (if not nil) holds the output collected so far
 // ctx is the context
-// texfile_name is the name to the TeX file (it is expected in the current working directory)
+// texfile_name is the name to the TeX file (relative to the current working directory or absolute)
 // builddir_template is the template for the build directory (e.g. "tex-to-pdfa_build_*")
-func CompileTexToPDFA(ctx context.Context, texfile_name string, builddir_template string) (string, error) {
-	var cmd_stdout, cmd_stderr bytes.Buffer
+// opts are the compile options, nil means DefaultCompileOptions
+func CompileTexToPDFA(ctx context.Context, texfile_name string, builddir_template string, opts *CompileOptions) (result *Result, err error) {
+	rec := &Recorder{}
+	result = &Result{}
+
+	// fill in the collected output on every return
+	defer func() {
+		if result != nil {
+			result.Stages = rec.Stages()
+			result.Log = CombineStageLogs(result.Stages)
+		}
+	}()
+
+	// === Check options ===
+
+	if err := opts.Validate(); err != nil {
+		Log(ctx).Error().Err(err).Msg("Invalid compile options, aborting...")
+		return nil, newStageError(ErrInvalidInput, STAGE_PREPARE, err)
+	}
+
+	opts = opts.WithDefaults()
+
+	// the whole pipeline is limited by the total timeout, each stage additionally by the stage timeout
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(opts.TimeoutSeconds)*time.Second)
+	defer cancel()
+
+	ctx = context.WithValue(ctx, stageTimeoutKey, time.Duration(opts.StageTimeoutSeconds)*time.Second)
+
+	engine, err := GetEngine(opts.Engine)
+
+	if err != nil {
+		return nil, newStageError(ErrInvalidInput, STAGE_PREPARE, err)
+	}
 
 	// === Check for essential commands ===
 
-	// slice of strings
-	commands := []string{"pdflatex", "rubber", "gs"}
+	// the engine's commands, gs for the PDF/A conversion and the validator
+	commands, err := Commands(opts)
+
+	if err != nil {
+		return nil, newStageError(ErrInvalidInput, STAGE_PREPARE, err)
+	}
+	var missing []string
 
-	ok := true
 	for _, command := range commands {
 		err := assureCommand(ctx, command)
 
 		if err != nil {
-			ok = false
+			missing = append(missing, command)
 			Log(ctx).Err(err).Str("command", command).Msg("Command not found")
 		}
 	}
 
-	if !ok {
+	if len(missing) > 0 {
 		Log(ctx).Error().Msg("Could not assure essential commands, see errors above, aborting...")
-		return "", fmt.Errorf("could not assure essential commands")
+		return nil, &ToolMissingError{Tools: missing}
 	}
 
 	Log(ctx).Trace().Msg("All essential commands found")
 
 	// === Prepare build ===
 
-	// create temp dir
-	builddir, err := os.MkdirTemp("", builddir_template)
-	defer os.RemoveAll(builddir)
-
-	if err != nil {
-		Log(ctx).Fatal().Err(err).Msg("Could not create temp dir, aborting...")
-	}
-
-	Log(ctx).Debug().Str("builddir", builddir).Msg("Created temp dir")
-
-	// === Build PDF from TeX ===
-
 	// get absolute path of main.tex
 	texfile, err := filepath.Abs(texfile_name)
 
 	if err != nil {
-		Log(ctx).Fatal().Err(err).Msg("Could not get absolute path of tex-file, aborting...")
+		Log(ctx).Error().Err(err).Msg("Could not get absolute path of tex-file, aborting...")
+		return nil, newStageError(ErrInvalidInput, STAGE_PREPARE, err)
 	}
 
 	// check if file main.tex exists
-	if _, err := os.Stat(texfile); os.IsNotExist(err) {
-		Log(ctx).Fatal().Err(err).Msgf("tex-file does not exist, expected '%s', aborting...", texfile)
+	if _, err := os.Stat(texfile); err != nil {
+		Log(ctx).Error().Err(err).Msgf("tex-file does not exist, expected '%s', aborting...", texfile)
+		return nil, newStageError(ErrInvalidInput, STAGE_PREPARE, fmt.Errorf("tex-file '%s': %w", filepath.Base(texfile), err))
 	}
 
 	basename := strings.TrimSuffix(filepath.Base(texfile), ".tex")
 	maindir := filepath.Dir(texfile)
 	Log(ctx).Debug().Str("texfile", texfile).Msgf("Found tex-file %s", basename)
 
-	// cmd := exec.Command("pdflatex", "-output-directory="+builddir, "-interaction=nonstopmode", TEXFILE)
-	cmd := exec.Command("rubber", "--pdf", "--into="+builddir, texfile)
-	cmd.Stdout = &cmd_stdout
-	cmd.Stderr = &cmd_stderr
-
-	Log(ctx).Info().Msg("Compiling TeX file")
-	Log(ctx).Debug().Str("cmd", cmd.String()).Msg("Running command")
-	err = cmd.Run()
+	// create temp dir
+	builddir, err := os.MkdirTemp("", builddir_template)
 
 	if err != nil {
-		Log(ctx).Fatal().Err(err).Str("stdout", cmd_stdout.String()).Str("stderr", cmd_stderr.String()).Msg("Could not run command, aborting...")
+		Log(ctx).Error().Err(err).Msg("Could not create temp dir, aborting...")
+		return nil, newStageError(ErrInternal, STAGE_PREPARE, err)
 	}
 
-	Log(ctx).Trace().Str("stdout", cmd_stdout.String()).Str("stderr", cmd_stderr.String()).Msgf("Command %s finished", cmd.Path)
+	defer os.RemoveAll(builddir)
 
-	// check pdf file
-	pdffile := builddir + "/" + basename + ".pdf"
+	Log(ctx).Debug().Str("builddir", builddir).Msg("Created temp dir")
 
-	if _, err := os.Stat(pdffile); os.IsNotExist(err) {
-		Log(ctx).Fatal().Err(err).Msgf("pdffile does not exist, expected '%s', aborting...", pdffile)
-	}
+	// === Prepare output intent ===
 
-	Log(ctx).Trace().Str("path", pdffile).Msg("Found pdffile")
+	var pdfadef []string    // PDFA_def.ps prologue setting the output intent, passed to all gs stages, if any
+	var intentArgs []string // arguments allowing gs to read the ICC profile and converting colors with it
 
-	// === Convert PDF to PDF/A-1 ===
-	Log(ctx).Info().Msg("Converting PDF to PDF/A")
+	if opts.OutputIntent != nil {
+		profile, err := opts.OutputIntent.resolve(opts.ICCProfileDir, maindir)
 
-	pdffile_pdfa1 := builddir + "/" + basename + "_pdfa1.pdf"
+		if err == nil {
+			err = opts.useOutputIntent(profile)
+		}
 
-	cmd_stdout.Reset()
-	cmd_stderr.Reset()
+		if err != nil {
+			Log(ctx).Error().Err(err).Msg("Could not prepare output intent, aborting...")
+			return nil, newStageError(ErrInvalidInput, STAGE_PREPARE, err)
+		}
 
-	cmd = exec.Command("gs", "-sDEVICE=pdfwrite", "-dPDFA=1", "-sColorConversionStrategy=UseDeviceIndependentColor", "-dPDFACompatibilityPolicy=2", "-o", pdffile_pdfa1, pdffile)
-	cmd.Stdout = &cmd_stdout
-	cmd.Stderr = &cmd_stderr
+		marks := &pdfmarks{}
+		marks.addOutputIntent(opts.OutputIntent, profile)
 
-	Log(ctx).Debug().Str("cmd", cmd.String()).Msg("Running command")
-	err = cmd.Run()
+		pdfadef = []string{builddir + "/" + basename + "_PDFA_def.ps"}
+		intentArgs, err = marks.write(pdfadef[0])
 
-	if err != nil {
-		Log(ctx).Fatal().Err(err).Str("stdout", cmd_stdout.String()).Str("stderr", cmd_stderr.String()).Msg("Could not run command, aborting...")
+		if err != nil {
+			Log(ctx).Error().Err(err).Msg("Could not write PDFA_def.ps, aborting...")
+			return nil, newStageError(ErrInternal, STAGE_PREPARE, err)
+		}
+
+		intentArgs = append(intentArgs, "-sOutputICCProfile="+profile.Path)
+
+		Log(ctx).Debug().Str("profile", profile.Name).Str("color_space", profile.ColorSpace).Msg("Using output intent")
 	}
 
-	Log(ctx).Trace().Str("stdout", cmd_stdout.String()).Str("stderr", cmd_stderr.String()).Msgf("Command %s finished", cmd.Path)
+	// === Prepare pdfmarks (metadata, attachments, Factur-X) ===
 
-	if _, err := os.Stat(pdffile_pdfa1); os.IsNotExist(err) {
-		Log(ctx).Fatal().Err(err).Msgf("pdffile does not exist, expected '%s', aborting...", pdffile_pdfa1)
+	marks := &pdfmarks{}
+	marks.addMetadata(opts.Metadata)
+
+	if err := marks.addAttachments(opts.Attachments, maindir); err != nil {
+		Log(ctx).Error().Err(err).Msg("Could not prepare attachments, aborting...")
+		return nil, newStageError(ErrInvalidInput, STAGE_PREPARE, err)
 	}
 
-	Log(ctx).Trace().Str("path", pdffile_pdfa1).Msg("Found pdffile_pdfa1")
+	if level, ok := facturxLevel(opts.FacturXProfile); ok {
+		marks.addFacturX(level)
+	}
 
-	// === Convert PDF to PDF/A-3 ===
+	var prologue []string // PostScript prologue passed to the final gs stage, if any
+	var permits []string  // arguments allowing gs to read the files of the prologue
 
-	pdffile_pdfa3 := builddir + "/" + basename + "_pdfa3.pdf"
+	if !marks.empty() {
+		prologue = []string{builddir + "/" + basename + "_pdfmarks.ps"}
+		permits, err = marks.write(prologue[0])
 
-	cmd_stdout.Reset()
-	cmd_stderr.Reset()
+		if err != nil {
+			Log(ctx).Error().Err(err).Msg("Could not write pdfmarks, aborting...")
+			return nil, newStageError(ErrInternal, STAGE_PREPARE, err)
+		}
+	}
 
-	cmd = exec.Command("gs", "-sDEVICE=pdfwrite", "-dPDFA=3", "-sColorConversionStrategy=UseDeviceIndependentColor", "-dPDFACompatibilityPolicy=2", "-o", pdffile_pdfa3, pdffile_pdfa1)
-	cmd.Stdout = &cmd_stdout
-	cmd.Stderr = &cmd_stderr
+	// === Build PDF from TeX ===
+
+	Log(ctx).Info().Str("engine", engine.Name()).Msg("Compiling TeX file")
 
-	Log(ctx).Debug().Str("cmd", cmd.String()).Msg("Running command")
-	err = cmd.Run()
+	err = engine.Compile(ctx, rec, texfile, builddir)
+
+	// keep the LaTeX log next to the tex-file, it is needed most if the compilation failed
+	result.TexLogPath = keepTexLog(ctx, builddir+"/"+basename+".log", maindir+"/"+basename+".log")
+	result.Diagnostics = diagnose(result.TexLogPath, rec.Stages(), maindir, builddir)
 
 	if err != nil {
-		Log(ctx).Fatal().Err(err).Str("stdout", cmd_stdout.String()).Str("stderr", cmd_stderr.String()).Msg("Could not run command, aborting...")
+		return result, err
 	}
 
-	Log(ctx).Trace().Str("stdout", cmd_stdout.String()).Str("stderr", cmd_stderr.String()).Msgf("Command %s finished", cmd.Path)
+	// check pdf file
+	pdffile := builddir + "/" + basename + ".pdf"
 
-	if _, err := os.Stat(pdffile_pdfa3); os.IsNotExist(err) {
-		Log(ctx).Fatal().Err(err).Msgf("pdffile does not exist, expected '%s', aborting...", pdffile_pdfa3)
+	if err := assureOutput(ctx, ErrCompileFailed, STAGE_COMPILE, pdffile); err != nil {
+		return result, err
 	}
 
-	Log(ctx).Trace().Str("path", pdffile_pdfa3).Msg("Found pdffile_pdfa2")
+	// === Convert PDF to PDF/A-1 ===
+	Log(ctx).Info().Int("part", opts.PDFAPart).Msg("Converting PDF to PDF/A")
 
-	// === Move PDF to output dir ===
+	if opts.PDFAPart != 1 && !opts.SkipPDFA1 {
+		pdffile_pdfa1 := builddir + "/" + basename + "_pdfa1.pdf"
 
-	resultpath := maindir + "/" + basename + ".pdf"
+		args := slices.Concat(intentArgs, opts.gsArgs(1, pdffile_pdfa1, slices.Concat(pdfadef, []string{pdffile})...))
 
-	cmd_stdout.Reset()
-	cmd_stderr.Reset()
+		err = runStage(ctx, rec, ErrConversionFailed, STAGE_PDFA1, "", "gs", args...)
 
-	cmd = exec.Command("cp", "-v", pdffile_pdfa3, resultpath)
-	cmd.Stdout = &cmd_stdout
-	cmd.Stderr = &cmd_stderr
+		if err != nil {
+			return result, err
+		}
+
+		if err := assureOutput(ctx, ErrConversionFailed, STAGE_PDFA1, pdffile_pdfa1); err != nil {
+			return result, err
+		}
+
+		pdffile = pdffile_pdfa1
+	}
+
+	// === Convert PDF to requested PDF/A part ===
+
+	pdffile_pdfa := fmt.Sprintf("%s/%s_pdfa%d.pdf", builddir, basename, opts.PDFAPart)
 
-	err = cmd.Run()
+	args := slices.Concat(intentArgs, permits, opts.gsArgs(opts.PDFAPart, pdffile_pdfa, slices.Concat(pdfadef, prologue, []string{pdffile})...))
+
+	err = runStage(ctx, rec, ErrConversionFailed, STAGE_PDFA, "", "gs", args...)
 
 	if err != nil {
-		Log(ctx).Fatal().Err(err).Str("stdout", cmd_stdout.String()).Str("stderr", cmd_stderr.String()).Msg("Could not run command, aborting...")
+		return result, err
 	}
 
-	Log(ctx).Trace().Str("stdout", cmd_stdout.String()).Str("stderr", cmd_stderr.String()).Msgf("Command %s finished", cmd.Path)
+	if err := assureOutput(ctx, ErrConversionFailed, STAGE_PDFA, pdffile_pdfa); err != nil {
+		return result, err
+	}
+
+	// === Validate PDF/A ===
+
+	if opts.Validator != "" {
+		validator, err := GetValidator(opts.Validator)
+
+		if err != nil {
+			return result, newStageError(ErrInvalidInput, STAGE_VALIDATE, err)
+		}
+
+		Log(ctx).Info().Str("validator", validator.Name()).Msg("Validating PDF/A")
+		reportProgress(ctx, ProgressEvent{Stage: STAGE_VALIDATE, Pass: rec.count(STAGE_VALIDATE) + 1})
+
+		result.Validation, err = validator.Validate(ctx, rec, pdffile_pdfa, opts.PDFAPart, PDFA_CONFORMANCE)
+
+		if err != nil {
+			return result, err
+		}
+
+		if !result.Validation.Compliant {
+			Log(ctx).Warn().Int("failed_rules", len(result.Validation.FailedRules)).Msgf("PDF/A file is not compliant with %s", result.Validation.Profile)
+		}
+	}
+
+	// === Move PDF to output dir ===
+
+	resultpath := maindir + "/" + basename + ".pdf"
+
+	err = runStage(ctx, rec, ErrInternal, STAGE_OUTPUT, "", "cp", "-v", pdffile_pdfa, resultpath)
+
+	if err != nil {
+		return result, err
+	}
 
-	if _, err := os.Stat(resultpath); os.IsNotExist(err) {
-		Log(ctx).Fatal().Err(err).Msgf("pdffile does not exist, expected '%s', aborting...", resultpath)
+	if err := assureOutput(ctx, ErrInternal, STAGE_OUTPUT, resultpath); err != nil {
+		return result, err
 	}
 
-	Log(ctx).Trace().Str("path", resultpath).Msg("Found resultpath")
+	Log(ctx).Info().Str("path", resultpath).Msgf("PDF/A-%d%s file created", opts.PDFAPart, PDFA_CONFORMANCE)
 
-	Log(ctx).Info().Str("path", resultpath).Msg("PDF/A-3 file created")
+	result.Path = resultpath
 
-	return resultpath, nil
+	return result, nil
 }
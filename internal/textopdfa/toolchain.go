@@ -0,0 +1,61 @@
+package textopdfa
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// VERSION_TIMEOUT limits the duration of a `--version` call of a command
+const VERSION_TIMEOUT = 10 * time.Second
+
+// Commands returns the commands run by CompileTexToPDFA with the given options: the engine's commands, gs and the validator if any
+func Commands(opts *CompileOptions) ([]string, error) {
+
+	opts = opts.WithDefaults()
+
+	engine, err := GetEngine(opts.Engine)
+
+	if err != nil {
+		return nil, err
+	}
+
+	commands := append(engine.Commands(), "gs")
+
+	if opts.Validator == "" {
+		return commands, nil
+	}
+
+	// VALIDATOR_AUTO runs veraPDF if it is installed
+	validator, err := ResolveValidator(opts.Validator)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if validator == VALIDATOR_VERAPDF {
+		commands = append(commands, VERAPDF_COMMAND)
+	}
+
+	return commands, nil
+}
+
+// CommandVersion returns the first line of the output of `name --version` (e.g. "10.02.1" for gs)
+func CommandVersion(ctx context.Context, name string) (string, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, VERSION_TIMEOUT)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, name, "--version").Output()
+
+	if err != nil {
+		return "", fmt.Errorf("could not get version of '%s': %w", name, err)
+	}
+
+	line, _, _ := bytes.Cut(output, []byte("\n"))
+
+	return strings.TrimSpace(string(line)), nil
+}
@@ -0,0 +1,104 @@
+package textopdfa
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"slices"
+)
+
+const (
+	VALIDATOR_AUTO     = "auto" // veraPDF if installed, the internal checker otherwise
+	VALIDATOR_VERAPDF  = "verapdf"
+	VALIDATOR_INTERNAL = "internal"
+)
+
+// ValidationRule is a rule of the PDF/A specification the file violates
+type ValidationRule struct {
+	Specification string `json:"specification"`           // e.g. "ISO 19005-3:2012"
+	Clause        string `json:"clause"`                  // clause of the specification, e.g. "6.6.2.1"
+	TestNumber    int    `json:"test_number,omitempty"`   // number of the test within the clause (veraPDF only)
+	Description   string `json:"description"`             // what the rule requires
+	FailedChecks  int    `json:"failed_checks,omitempty"` // number of objects violating the rule (veraPDF only)
+}
+
+// ValidationReport is the result of validating a PDF/A file
+type ValidationReport struct {
+	Validator   string           `json:"validator"`    // name of the validator, one of the VALIDATOR_* constants
+	Profile     string           `json:"profile"`      // the profile the file was checked against, e.g. "PDF/A-3B"
+	Compliant   bool             `json:"compliant"`    // whether the file passed all rules
+	FailedRules []ValidationRule `json:"failed_rules"` // rules the file violates, empty if it is compliant
+}
+
+// Validator checks whether a file conforms to a PDF/A profile
+type Validator interface {
+	// Name returns the name of the validator (one of the VALIDATOR_* constants)
+	Name() string
+
+	// Validate checks pdffile against PDF/A-<part><conformance>
+	// The output of all commands is recorded in rec, failures to check the file are returned as StageError
+	Validate(ctx context.Context, rec *Recorder, pdffile string, part int, conformance string) (*ValidationReport, error)
+}
+
+// validators are all available validators by name
+var validators = map[string]Validator{
+	VALIDATOR_VERAPDF:  verapdfValidator{},
+	VALIDATOR_INTERNAL: internalValidator{},
+}
+
+// ValidatorNames returns the names of all validators including VALIDATOR_AUTO, sorted
+func ValidatorNames() []string {
+	names := []string{VALIDATOR_AUTO}
+
+	for name := range validators {
+		names = append(names, name)
+	}
+
+	slices.Sort(names)
+
+	return names
+}
+
+// ResolveValidator returns the name of the validator that runs for the given name, VALIDATOR_AUTO is resolved to
+// VALIDATOR_VERAPDF if veraPDF is installed and to VALIDATOR_INTERNAL otherwise
+func ResolveValidator(name string) (string, error) {
+
+	if name == VALIDATOR_AUTO {
+		if _, err := exec.LookPath(VERAPDF_COMMAND); err == nil {
+			return VALIDATOR_VERAPDF, nil
+		}
+
+		return VALIDATOR_INTERNAL, nil
+	}
+
+	if _, ok := validators[name]; ok {
+		return name, nil
+	}
+
+	return "", fmt.Errorf("unknown validator '%s', expected one of %v", name, ValidatorNames())
+}
+
+// GetValidator returns the validator with the given name, VALIDATOR_AUTO picks veraPDF if it is installed (see ResolveValidator)
+func GetValidator(name string) (Validator, error) {
+
+	name, err := ResolveValidator(name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return validators[name], nil
+}
+
+// specification returns the name of the PDF/A specification of the given part
+func specification(part int) string {
+
+	switch part {
+	case 1:
+		return "ISO 19005-1:2005"
+	case 2:
+		return "ISO 19005-2:2011"
+	}
+
+	return "ISO 19005-3:2012"
+}
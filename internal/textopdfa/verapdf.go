@@ -0,0 +1,98 @@
+package textopdfa
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// VERAPDF_COMMAND is the command line interface of veraPDF (https://verapdf.org)
+const VERAPDF_COMMAND = "verapdf"
+
+// verapdfReport is the part of veraPDF's XML report (--format xml) the validation result is taken from
+type verapdfReport struct {
+	Jobs []struct {
+		ValidationReport *struct {
+			ProfileName string `xml:"profileName,attr"`
+			IsCompliant bool   `xml:"isCompliant,attr"`
+			Rules       []struct {
+				Specification string `xml:"specification,attr"`
+				Clause        string `xml:"clause,attr"`
+				TestNumber    int    `xml:"testNumber,attr"`
+				Status        string `xml:"status,attr"`
+				FailedChecks  int    `xml:"failedChecks,attr"`
+				Description   string `xml:"description"`
+			} `xml:"details>rule"`
+		} `xml:"validationReport"`
+		TaskException *struct {
+			Message string `xml:"exceptionMessage"`
+		} `xml:"taskException"`
+	} `xml:"jobs>job"`
+}
+
+// verapdfValidator validates with the veraPDF command line interface
+type verapdfValidator struct{}
+
+func (verapdfValidator) Name() string {
+	return VALIDATOR_VERAPDF
+}
+
+func (verapdfValidator) Validate(ctx context.Context, rec *Recorder, pdffile string, part int, conformance string) (*ValidationReport, error) {
+	flavour := fmt.Sprintf("%d%s", part, conformance)
+
+	err := runStage(ctx, rec, ErrValidationFailed, STAGE_VALIDATE, "", VERAPDF_COMMAND, "--format", "xml", "--flavour", flavour, pdffile)
+
+	// veraPDF exits with code 1 if the file is not compliant, the report is written nevertheless
+	var stageErr *StageError
+
+	if err != nil && !(errors.As(err, &stageErr) && stageErr.Kind == ErrValidationFailed && stageErr.ExitCode == 1) {
+		return nil, err
+	}
+
+	var report verapdfReport
+
+	if err := xml.Unmarshal([]byte(rec.last().Stdout), &report); err != nil {
+		return nil, newStageError(ErrValidationFailed, STAGE_VALIDATE, fmt.Errorf("could not parse veraPDF report: %w", err))
+	}
+
+	if len(report.Jobs) == 0 {
+		return nil, newStageError(ErrValidationFailed, STAGE_VALIDATE, fmt.Errorf("veraPDF report contains no result"))
+	}
+
+	job := report.Jobs[0]
+
+	if job.ValidationReport == nil {
+		message := "veraPDF report contains no validation result"
+
+		if job.TaskException != nil {
+			message = "veraPDF failed: " + strings.TrimSpace(job.TaskException.Message)
+		}
+
+		return nil, newStageError(ErrValidationFailed, STAGE_VALIDATE, errors.New(message))
+	}
+
+	result := &ValidationReport{
+		Validator:   VALIDATOR_VERAPDF,
+		Profile:     strings.ToUpper("PDF/A-" + flavour),
+		Compliant:   job.ValidationReport.IsCompliant,
+		FailedRules: []ValidationRule{},
+	}
+
+	for _, rule := range job.ValidationReport.Rules {
+		if rule.Status != "failed" {
+			continue
+		}
+
+		result.FailedRules = append(result.FailedRules, ValidationRule{
+			Specification: rule.Specification,
+			Clause:        rule.Clause,
+			TestNumber:    rule.TestNumber,
+			Description:   strings.TrimSpace(rule.Description),
+			FailedChecks:  rule.FailedChecks,
+		})
+	}
+
+	return result, nil
+}
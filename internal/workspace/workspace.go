@@ -0,0 +1,206 @@
+package workspace
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// MAX_TOTAL_SIZE is the maximum size of all files written into a workspace, it protects against archive bombs
+	MAX_TOTAL_SIZE = 256 << 20 // 256 MiB
+
+	// MAX_FILES is the maximum number of files written into a workspace
+	MAX_FILES = 10000
+)
+
+var (
+	// ErrInvalidPath is returned for file names that are absolute or escape the workspace (e.g. "../main.tex")
+	ErrInvalidPath = errors.New("invalid path")
+
+	// ErrTooLarge is returned if the files exceed MAX_TOTAL_SIZE or MAX_FILES
+	ErrTooLarge = errors.New("workspace too large")
+
+	// ErrUnsupportedArchive is returned for archives that are neither zip nor tar.gz
+	ErrUnsupportedArchive = errors.New("unsupported archive format, expected zip or tar.gz")
+)
+
+// File is a file of a workspace
+type File struct {
+	Name    string `json:"name"`    // slash separated path relative to the workspace (e.g. "images/logo.png")
+	Content []byte `json:"content"` // content of the file, base64 encoded in JSON
+}
+
+// Workspace is a directory files are written into, it tracks the written files to enforce the limits
+type Workspace struct {
+	Dir   string // the directory of the workspace
+	size  int64
+	files int
+}
+
+// New returns a workspace writing into dir, dir must exist
+func New(dir string) *Workspace {
+	return &Workspace{Dir: dir}
+}
+
+// Path returns the absolute path of name inside the workspace
+// name is a slash separated relative path, names that are absolute or escape the workspace are rejected with ErrInvalidPath
+func (ws *Workspace) Path(name string) (string, error) {
+	local := filepath.FromSlash(name)
+
+	if name == "" || !filepath.IsLocal(local) {
+		return "", fmt.Errorf("%w: '%s'", ErrInvalidPath, name)
+	}
+
+	return filepath.Join(ws.Dir, local), nil
+}
+
+// Exists reports whether the file name exists inside the workspace
+func (ws *Workspace) Exists(name string) bool {
+	path, err := ws.Path(name)
+
+	if err != nil {
+		return false
+	}
+
+	info, err := os.Stat(path)
+
+	return err == nil && info.Mode().IsRegular()
+}
+
+// Write writes the content of r to the file name inside the workspace, creating parent directories as needed
+func (ws *Workspace) Write(name string, r io.Reader) error {
+	path, err := ws.Path(name)
+
+	if err != nil {
+		return err
+	}
+
+	ws.files++
+
+	if ws.files > MAX_FILES {
+		return fmt.Errorf("%w: more than %d files", ErrTooLarge, MAX_FILES)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("could not create directory for '%s': %w", name, err)
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+
+	if err != nil {
+		return fmt.Errorf("could not create file '%s': %w", name, err)
+	}
+
+	defer file.Close()
+
+	// copy at most one byte more than allowed to detect exceeding the limit
+	remaining := MAX_TOTAL_SIZE - ws.size
+	n, err := io.Copy(file, io.LimitReader(r, remaining+1))
+	ws.size += n
+
+	if err != nil {
+		return fmt.Errorf("could not write file '%s': %w", name, err)
+	}
+
+	if n > remaining {
+		return fmt.Errorf("%w: more than %d bytes", ErrTooLarge, MAX_TOTAL_SIZE)
+	}
+
+	return nil
+}
+
+// WriteFiles writes all files into the workspace
+func (ws *Workspace) WriteFiles(files []File) error {
+
+	for _, file := range files {
+		if err := ws.Write(file.Name, bytes.NewReader(file.Content)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ExtractArchive extracts a zip or tar.gz archive into the workspace, the format is detected from the content
+// Directories are created as needed, other entries than regular files (e.g. symlinks) are skipped
+func (ws *Workspace) ExtractArchive(archive []byte) error {
+
+	switch {
+	case bytes.HasPrefix(archive, []byte("PK\x03\x04")), bytes.HasPrefix(archive, []byte("PK\x05\x06")):
+		return ws.extractZip(archive)
+	case bytes.HasPrefix(archive, []byte{0x1f, 0x8b}):
+		return ws.extractTarGz(archive)
+	}
+
+	return ErrUnsupportedArchive
+}
+
+func (ws *Workspace) extractZip(archive []byte) error {
+	reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+
+	if err != nil {
+		return fmt.Errorf("could not read zip archive: %w", err)
+	}
+
+	for _, entry := range reader.File {
+		if !entry.Mode().IsRegular() {
+			continue
+		}
+
+		content, err := entry.Open()
+
+		if err != nil {
+			return fmt.Errorf("could not read '%s' from zip archive: %w", entry.Name, err)
+		}
+
+		err = ws.Write(strings.TrimPrefix(entry.Name, "./"), content)
+		content.Close()
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (ws *Workspace) extractTarGz(archive []byte) error {
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+
+	if err != nil {
+		return fmt.Errorf("could not read tar.gz archive: %w", err)
+	}
+
+	defer gz.Close()
+
+	reader := tar.NewReader(gz)
+
+	for {
+		header, err := reader.Next()
+
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return fmt.Errorf("could not read tar.gz archive: %w", err)
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		// tar archives created with "tar -C dir ." prefix all names with "./"
+		if err := ws.Write(strings.TrimPrefix(header.Name, "./"), reader); err != nil {
+			return err
+		}
+	}
+}
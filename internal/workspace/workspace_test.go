@@ -0,0 +1,366 @@
+package workspace
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// entry is a file, directory or link of a test archive
+type entry struct {
+	name     string
+	content  string
+	typeflag byte   // tar.TypeReg, tar.TypeDir, tar.TypeSymlink or tar.TypeLink
+	target   string // target of a link
+}
+
+// reg returns a regular file entry
+func reg(name, content string) entry {
+	return entry{name: name, content: content, typeflag: tar.TypeReg}
+}
+
+// makeTarGz returns a tar.gz archive with the given entries
+func makeTarGz(t *testing.T, entries ...entry) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+
+	for _, e := range entries {
+		header := &tar.Header{Name: e.name, Typeflag: e.typeflag, Linkname: e.target, Mode: 0644, Size: int64(len(e.content))}
+
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := tw.Write([]byte(e.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+// makeZip returns a zip archive with the given entries, symlinks store their target as content like zip -y does
+func makeZip(t *testing.T, entries ...entry) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	for _, e := range entries {
+		header := &zip.FileHeader{Name: e.name, Method: zip.Deflate}
+		content := e.content
+
+		switch e.typeflag {
+		case tar.TypeSymlink:
+			header.SetMode(os.ModeSymlink | 0777)
+			content = e.target
+		case tar.TypeDir:
+			header.SetMode(os.ModeDir | 0755)
+		default:
+			header.SetMode(0644)
+		}
+
+		w, err := zw.CreateHeader(header)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+// listFiles returns the slash separated paths of all entries below dir with "/" appended to directories and
+// " -> target" to symlinks, sorted
+func listFiles(t *testing.T, dir string) []string {
+	t.Helper()
+
+	var files []string
+
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+
+		if err != nil || path == dir {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+
+		if err != nil {
+			return err
+		}
+
+		rel = filepath.ToSlash(rel)
+
+		switch {
+		case d.Type()&os.ModeSymlink != 0:
+			target, err := os.Readlink(path)
+
+			if err != nil {
+				return err
+			}
+
+			rel += " -> " + target
+		case d.IsDir():
+			rel += "/"
+		}
+
+		files = append(files, rel)
+
+		return nil
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return files
+}
+
+func TestPath(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		file    string
+		want    string // relative to the workspace
+		wantErr error
+	}{
+		{name: "file", file: "main.tex", want: "main.tex"},
+		{name: "subdirectory", file: "images/logo.png", want: "images/logo.png"},
+		{name: "dot segments inside", file: "chapters/../main.tex", want: "main.tex"},
+		{name: "empty", file: "", wantErr: ErrInvalidPath},
+		{name: "parent", file: "../x", wantErr: ErrInvalidPath},
+		{name: "parent after subdirectory", file: "images/../../x", wantErr: ErrInvalidPath},
+		{name: "dot dot only", file: "..", wantErr: ErrInvalidPath},
+		{name: "absolute", file: "/etc/passwd", wantErr: ErrInvalidPath},
+	}
+
+	ws := New(t.TempDir())
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ws.Path(tt.file)
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Path(%q) returned error %v, want %v", tt.file, err, tt.wantErr)
+			}
+
+			if tt.wantErr != nil {
+				return
+			}
+
+			if want := filepath.Join(ws.Dir, filepath.FromSlash(tt.want)); got != want {
+				t.Errorf("Path(%q) = %q, want %q", tt.file, got, want)
+			}
+		})
+	}
+}
+
+func TestExtractArchive(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		archive   func(t *testing.T, outside string) []byte // outside is a directory next to the workspace
+		wantErr   error
+		wantFiles []string // entries of the workspace after the extraction, see listFiles
+	}{
+		{
+			name: "zip",
+			archive: func(t *testing.T, outside string) []byte {
+				return makeZip(t, reg("main.tex", "hello"), entry{name: "images/", typeflag: tar.TypeDir}, reg("./images/logo.png", "png"))
+			},
+			wantFiles: []string{"images/", "images/logo.png", "main.tex"},
+		},
+		{
+			name: "tar.gz",
+			archive: func(t *testing.T, outside string) []byte {
+				return makeTarGz(t, entry{name: "./", typeflag: tar.TypeDir}, reg("./main.tex", "hello"), reg("./chapters/intro.tex", "intro"))
+			},
+			wantFiles: []string{"chapters/", "chapters/intro.tex", "main.tex"},
+		},
+		{
+			name: "zip with parent path",
+			archive: func(t *testing.T, outside string) []byte {
+				return makeZip(t, reg("../"+filepath.Base(outside)+"/evil.tex", "evil"))
+			},
+			wantErr: ErrInvalidPath,
+		},
+		{
+			name: "zip with absolute path",
+			archive: func(t *testing.T, outside string) []byte {
+				return makeZip(t, reg(filepath.ToSlash(filepath.Join(outside, "evil.tex")), "evil"))
+			},
+			wantErr: ErrInvalidPath,
+		},
+		{
+			name: "tar.gz with parent path",
+			archive: func(t *testing.T, outside string) []byte {
+				return makeTarGz(t, reg("main.tex", "hello"), reg("./../"+filepath.Base(outside)+"/evil.tex", "evil"))
+			},
+			wantErr:   ErrInvalidPath,
+			wantFiles: []string{"main.tex"},
+		},
+		{
+			name: "tar.gz with absolute path",
+			archive: func(t *testing.T, outside string) []byte {
+				return makeTarGz(t, reg(filepath.ToSlash(filepath.Join(outside, "evil.tex")), "evil"))
+			},
+			wantErr: ErrInvalidPath,
+		},
+		{
+			name: "zip with symlink",
+			archive: func(t *testing.T, outside string) []byte {
+				return makeZip(t, entry{name: "link", typeflag: tar.TypeSymlink, target: outside}, reg("link/evil.tex", "evil"))
+			},
+			// the symlink is skipped, so the file is written into a directory of the workspace
+			wantFiles: []string{"link/", "link/evil.tex"},
+		},
+		{
+			name: "tar.gz with symlink",
+			archive: func(t *testing.T, outside string) []byte {
+				return makeTarGz(t, entry{name: "link", typeflag: tar.TypeSymlink, target: outside}, reg("link/evil.tex", "evil"))
+			},
+			wantFiles: []string{"link/", "link/evil.tex"},
+		},
+		{
+			name: "tar.gz with hard link",
+			archive: func(t *testing.T, outside string) []byte {
+				return makeTarGz(t, entry{name: "passwd", typeflag: tar.TypeLink, target: "/etc/passwd"})
+			},
+		},
+		{
+			name: "unsupported format",
+			archive: func(t *testing.T, outside string) []byte {
+				return []byte("\\documentclass{article}")
+			},
+			wantErr: ErrUnsupportedArchive,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			dir := filepath.Join(root, "workspace")
+			outside := filepath.Join(root, "outside")
+
+			for _, d := range []string{dir, outside} {
+				if err := os.Mkdir(d, 0755); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := New(dir).ExtractArchive(tt.archive(t, outside))
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ExtractArchive() returned error %v, want %v", err, tt.wantErr)
+			}
+
+			if got := listFiles(t, dir); strings.Join(got, ",") != strings.Join(tt.wantFiles, ",") {
+				t.Errorf("ExtractArchive() wrote %q, want %q", got, tt.wantFiles)
+			}
+
+			if got := listFiles(t, outside); len(got) > 0 {
+				t.Errorf("ExtractArchive() wrote %q outside of the workspace", got)
+			}
+		})
+	}
+}
+
+func TestLimits(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		size    int64 // bytes already written into the workspace
+		files   int   // files already written into the workspace
+		write   func(ws *Workspace) error
+		wantErr error
+	}{
+		{
+			name: "size below limit",
+			size: MAX_TOTAL_SIZE - 5,
+			write: func(ws *Workspace) error {
+				return ws.Write("main.tex", strings.NewReader("hello"))
+			},
+		},
+		{
+			name: "size above limit",
+			size: MAX_TOTAL_SIZE - 4,
+			write: func(ws *Workspace) error {
+				return ws.Write("main.tex", strings.NewReader("hello"))
+			},
+			wantErr: ErrTooLarge,
+		},
+		{
+			name:  "file count at limit",
+			files: MAX_FILES - 1,
+			write: func(ws *Workspace) error {
+				return ws.WriteFiles([]File{{Name: "main.tex", Content: []byte("hello")}})
+			},
+		},
+		{
+			name:  "file count above limit",
+			files: MAX_FILES - 1,
+			write: func(ws *Workspace) error {
+				return ws.WriteFiles([]File{{Name: "main.tex", Content: []byte("hello")}, {Name: "logo.png", Content: []byte("png")}})
+			},
+			wantErr: ErrTooLarge,
+		},
+		{
+			name: "size of archive above limit",
+			size: MAX_TOTAL_SIZE - 1024,
+			write: func(ws *Workspace) error {
+				// compresses well, like an archive bomb
+				return ws.ExtractArchive(makeZip(t, reg("zeros.bin", strings.Repeat("\x00", 4096))))
+			},
+			wantErr: ErrTooLarge,
+		},
+		{
+			name:  "file count of archive above limit",
+			files: MAX_FILES - 1,
+			write: func(ws *Workspace) error {
+				return ws.ExtractArchive(makeTarGz(t, reg("a.tex", "a"), reg("b.tex", "b")))
+			},
+			wantErr: ErrTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ws := New(t.TempDir())
+			ws.size = tt.size
+			ws.files = tt.files
+
+			if err := tt.write(ws); !errors.Is(err, tt.wantErr) {
+				t.Errorf("writing returned error %v, want %v", err, tt.wantErr)
+			}
+
+			if ws.size > MAX_TOTAL_SIZE+1 {
+				t.Errorf("wrote %d bytes, want at most one byte above the limit", ws.size-tt.size)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+const (
+	HEADER_API_KEY = "X-API-Key" // alternative to "Authorization: Bearer <key>"
+
+	ROLE_CLIENT = "client" // sees its own jobs only
+	ROLE_ADMIN  = "admin"  // sees all jobs and the administrative endpoints
+)
+
+// ErrInvalidKey is returned by an Authenticator for unknown or revoked keys
+var ErrInvalidKey = errors.New("invalid API key")
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	ID   string // unique id of the caller, e.g. the id of its API key
+	Name string
+	Role string // ROLE_CLIENT or ROLE_ADMIN
+}
+
+// Admin returns true if the caller may see everything
+func (p *Principal) Admin() bool {
+	return p.Role == ROLE_ADMIN
+}
+
+// Authenticator checks the API key of a request and returns its caller
+type Authenticator interface {
+	Authenticate(ctx context.Context, key string) (*Principal, error)
+}
+
+// WithPrincipal returns a context carrying the caller of a request (see PrincipalFromContext)
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, "principal", principal)
+}
+
+// PrincipalFromContext returns the caller of a request, false if the request was not authenticated (authentication is disabled)
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value("principal").(*Principal)
+	return principal, ok && principal != nil
+}
+
+// APIKeyFromHeader returns the API key of a request: the bearer token of the Authorization header or the X-API-Key header
+func APIKeyFromHeader(header http.Header) string {
+
+	if token, ok := strings.CutPrefix(header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+
+	return strings.TrimSpace(header.Get(HEADER_API_KEY))
+}
+
+// authMiddleware rejects requests without a valid API key, except for the public paths, and adds the caller to the context of the others
+func (srv *RestServer) authMiddleware(next http.Handler, auth Authenticator, public []string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		if slices.Contains(public, r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		logger := srv.Logger
+
+		if reqLogger, ok := loggerFromContext(r.Context()); ok {
+			logger = reqLogger
+		}
+
+		key := APIKeyFromHeader(r.Header)
+
+		if key == "" {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			_ = WriteError(w, http.StatusUnauthorized, "API key required [K3WQ8ZRN]", logger)
+			return
+		}
+
+		principal, err := auth.Authenticate(r.Context(), key)
+
+		if errors.Is(err, ErrInvalidKey) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			_ = WriteError(w, http.StatusUnauthorized, "invalid API key [1BHV5MXT]", logger)
+			return
+		}
+
+		if err != nil {
+			logger.Error("failed to check API key", "error-id", "Y7DC2LQP", "error", err)
+			_ = WriteError(w, http.StatusInternalServerError, "failed to check API key [Y7DC2LQP]", logger)
+			return
+		}
+
+		ctx := WithPrincipal(r.Context(), principal)
+
+		if reqLogger, ok := loggerFromContext(ctx); ok {
+			ctx = context.WithValue(ctx, "logger", reqLogger.With("principal", principal.ID))
+		}
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// loggerFromContext returns the logger of a request (see logRequestMiddleware)
+func loggerFromContext(ctx context.Context) (*slog.Logger, bool) {
+	logger, ok := ctx.Value("logger").(*slog.Logger)
+	return logger, ok
+}
@@ -0,0 +1,45 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// grpcMultiplexer routes gRPC calls (HTTP/2 requests of content type application/grpc) to grpcHandler and all other requests to handler
+// HTTP/2 without TLS (h2c) is enabled on httpserver, gRPC clients use it on plain connections
+// The calls are tracked, so Shutdown can wait for them: a *grpc.Server serving via ServeHTTP cannot be stopped gracefully
+func (srv *RestServer) grpcMultiplexer(httpserver *http.Server, handler http.Handler, grpcHandler http.Handler) (http.Handler, error) {
+
+	h2s := &http2.Server{}
+
+	// tells the clients on shutdown to open no new streams (GOAWAY)
+	if err := http2.ConfigureServer(httpserver, h2s); err != nil {
+		return nil, err
+	}
+
+	return h2c.NewHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		if r.ProtoMajor != 2 || !strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		srv.mu.Lock()
+
+		if srv.closed {
+			srv.mu.Unlock()
+			http.Error(w, "server is shutting down", http.StatusServiceUnavailable)
+			return
+		}
+
+		srv.grpcCalls.Add(1)
+		srv.mu.Unlock()
+
+		defer srv.grpcCalls.Done()
+
+		grpcHandler.ServeHTTP(w, r)
+	}), h2s), nil
+}
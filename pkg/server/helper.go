@@ -7,7 +7,9 @@ import (
 	"time"
 )
 
-func (srv *RestServer) logRequestMiddleware(next http.Handler) http.Handler {
+// logRequestMiddleware assigns an id to each request and adds it and the request's logger to the context ("requestID", "logger")
+// It is installed for all requests, as the handlers depend on the logger, logRequests only controls whether the requests are logged
+func (srv *RestServer) logRequestMiddleware(next http.Handler, logRequests bool) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
 		startTime := time.Now()
@@ -21,11 +23,13 @@ func (srv *RestServer) logRequestMiddleware(next http.Handler) http.Handler {
 
 		logger := srv.Logger.With("requestID", requestID)
 
-		logger.Info(fmt.Sprintf("REQUEST: %-4s %s", r.Method, r.URL.Path),
-			"method", r.Method,
-			"url", r.URL.Path,
-			"host", r.Host,
-		)
+		if logRequests {
+			logger.Info(fmt.Sprintf("REQUEST: %-4s %s", r.Method, r.URL.Path),
+				"method", r.Method,
+				"url", r.URL.Path,
+				"host", r.Host,
+			)
+		}
 
 		ctx := context.WithValue(r.Context(), "logger", logger)
 		ctx = context.WithValue(ctx, "requestID", requestID)
@@ -40,6 +44,10 @@ func (srv *RestServer) logRequestMiddleware(next http.Handler) http.Handler {
 		// Rufe den nächsten Handler in der Kette auf
 		next.ServeHTTP(w, r)
 
+		if !logRequests {
+			return
+		}
+
 		stopTime := time.Now()
 
 		logger.Debug(fmt.Sprintf("request completed in %s", stopTime.Sub(startTime).String()),
@@ -36,7 +36,12 @@ func writeActualResponse(w http.ResponseWriter, resp CommonResponse, logger *slo
 // With optional logger to log the response message, may be nil. If not nil, the logger will be used to log the response message at debug level. All returned errors will also be logged.
 func WriteResponse(w http.ResponseWriter, data interface{}, logger *slog.Logger) error {
 
-	code := http.StatusOK
+	return WriteResponseStatus(w, http.StatusOK, data, logger)
+}
+
+// WriteResponseStatus writes a response to the client with the given data and a successful status code (e.g. 202 Accepted).
+// With optional logger to log the response message, may be nil. If not nil, the logger will be used to log the response message at debug level. All returned errors will also be logged.
+func WriteResponseStatus(w http.ResponseWriter, code int, data interface{}, logger *slog.Logger) error {
 
 	resp := CommonResponse{
 		Status:  code,
@@ -1,9 +1,12 @@
 package server
 
 import (
+	"context"
+	"errors"
 	"log/slog"
 	"math/rand"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/oklog/ulid"
@@ -11,13 +14,28 @@ import (
 
 type RestServer struct {
 	Logger  *slog.Logger
-	Entropy *rand.Rand // Entropy source for generating ULIDs.
+	Entropy *rand.Rand // Entropy source for generating ULIDs, guarded by entropyMu.
+
+	entropyMu  sync.Mutex // Entropy is not safe for concurrent use, request ids are generated by concurrent requests
+	mu         sync.Mutex
+	httpserver *http.Server   // the running server, nil before Start
+	closed     bool           // Shutdown was called, Start does not start the server anymore
+	grpcCalls  sync.WaitGroup // multiplexed gRPC calls in flight (see RestServerOptions.GRPCHandler)
 }
 
 type RestServerOptions struct {
 	Address                  string
-	OptLogReqeust            bool
+	OptLogReqeust            bool // log every request, the handlers get a logger in the context either way
 	CallbackEndpointRegister func(muxer *http.ServeMux)
+
+	// GRPCHandler optionally serves gRPC calls on the same address (e.g. a *grpc.Server), calls are not logged
+	// Shutdown waits for the calls, but it does not stop the handler: stop a *grpc.Server afterwards (Stop, not GracefulStop)
+	GRPCHandler http.Handler
+
+	// Authenticator optionally requires an API key for all requests except to PublicPaths (e.g. "/api/v1/ping"), nil disables authentication
+	// The caller of a request is available via PrincipalFromContext. gRPC calls are not checked, see GRPCHandler
+	Authenticator Authenticator
+	PublicPaths   []string
 }
 
 // NewRestServer creates a new RestServer instance. It requires a logger instance. All other options are set to their defaults.
@@ -42,15 +60,15 @@ func RestServerOptionsDefaults(callbackEndpointRegister func(muxer *http.ServeMu
 // NewULID generates a new ULID using the server's entropy source.
 func (srv *RestServer) NewULID() (ulid.ULID, error) {
 
+	srv.entropyMu.Lock()
+	defer srv.entropyMu.Unlock()
+
 	return ulid.New(ulid.Timestamp(time.Now()), srv.Entropy)
 }
 
-// Start starts the RestServer on the given address/options. Set options to nil to use the defaults.
-func (srv *RestServer) Start(opts *RestServerOptions) error {
-
-	if opts == nil {
-		opts = RestServerOptionsDefaults(nil)
-	}
+// Handler returns the handler of the endpoints registered by opts.CallbackEndpointRegister, including the logging and authentication
+// of the requests, but without gRPC (see RestServerOptions.GRPCHandler). Start serves it, it can also be used with net/http/httptest.
+func (srv *RestServer) Handler(opts *RestServerOptions) http.Handler {
 
 	muxer := http.NewServeMux()
 
@@ -58,15 +76,38 @@ func (srv *RestServer) Start(opts *RestServerOptions) error {
 		opts.CallbackEndpointRegister(muxer)
 	}
 
+	var handler http.Handler = muxer
+
+	if opts.Authenticator != nil {
+		handler = srv.authMiddleware(handler, opts.Authenticator, opts.PublicPaths)
+	}
+
+	// the handlers take their logger from the context, so the middleware is installed even if requests are not logged
+	return srv.logRequestMiddleware(handler, opts.OptLogReqeust)
+}
+
+// Start starts the RestServer on the given address/options. Set options to nil to use the defaults.
+// It blocks until the server fails or is stopped by Shutdown, in the latter case it returns nil.
+func (srv *RestServer) Start(opts *RestServerOptions) error {
+
+	if opts == nil {
+		opts = RestServerOptionsDefaults(nil)
+	}
+
 	// === create http server ===
 	httpserver := &http.Server{
-		Addr: opts.Address,
+		Addr:    opts.Address,
+		Handler: srv.Handler(opts),
 	}
 
-	if opts.OptLogReqeust {
-		httpserver.Handler = srv.logRequestMiddleware(muxer)
-	} else {
-		httpserver.Handler = muxer
+	if opts.GRPCHandler != nil {
+		handler, err := srv.grpcMultiplexer(httpserver, httpserver.Handler, opts.GRPCHandler)
+
+		if err != nil {
+			return err
+		}
+
+		httpserver.Handler = handler
 	}
 
 	address := opts.Address
@@ -76,6 +117,61 @@ func (srv *RestServer) Start(opts *RestServerOptions) error {
 		address = "localhost" + address
 	}
 
+	srv.mu.Lock()
+
+	if srv.closed {
+		srv.mu.Unlock()
+		return nil
+	}
+
+	srv.httpserver = httpserver
+	srv.mu.Unlock()
+
 	srv.Logger.Info("starting server on http://" + address)
-	return httpserver.ListenAndServe()
+	err := httpserver.ListenAndServe()
+
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+
+	return err
+}
+
+// Shutdown stops accepting new connections and waits for the in-flight requests (including gRPC calls) to complete until ctx is done.
+// It returns ctx.Err() if requests were still running at the deadline, their connections are closed then.
+func (srv *RestServer) Shutdown(ctx context.Context) error {
+
+	srv.mu.Lock()
+	httpserver := srv.httpserver
+	srv.closed = true
+	srv.mu.Unlock()
+
+	if httpserver == nil {
+		return nil
+	}
+
+	srv.Logger.Info("shutting down server")
+
+	err := httpserver.Shutdown(ctx)
+
+	if err != nil {
+		// force-close the connections of requests that did not complete in time
+		_ = httpserver.Close()
+		return err
+	}
+
+	// gRPC calls run on hijacked connections, httpserver.Shutdown does not wait for them
+	done := make(chan struct{})
+
+	go func() {
+		srv.grpcCalls.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }